@@ -0,0 +1,140 @@
+// Package address parses and validates email addresses before mail is
+// sent: RFC 5322 syntax, internationalized domain names, disposable
+// domains and, optionally, the mail servers of the domain in DNS.
+//
+// Failures are returned as *Error, whose Code tells why the address
+// was rejected.
+package address
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"strings"
+
+	"github.com/oarkflow/errors"
+	"golang.org/x/net/idna"
+)
+
+// Code is why an address is invalid.
+type Code string
+
+const (
+	Syntax       Code = "syntax"         // Not an RFC 5322 address.
+	Domain       Code = "domain"         // Invalid domain name.
+	Disposable   Code = "disposable"     // Domain of a disposable mail service.
+	NoMailServer Code = "no_mail_server" // Domain without MX nor address records, or with a null MX.
+	Lookup       Code = "lookup"         // DNS lookup failed, e.g. timed out; the address may be valid.
+)
+
+// Error is returned for an invalid address.
+type Error struct {
+	Field   string // Header field of the address, e.g. "to", if known.
+	Address string
+	Code    Code
+	Err     error
+}
+
+func (e *Error) Error() string {
+	field := "email"
+	if e.Field != "" {
+		field = e.Field
+	}
+	return fmt.Sprintf("Invalid %s address %q: %v", field, e.Address, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Temporary reports whether the address could not be checked, rather
+// than being invalid.
+func (e *Error) Temporary() bool {
+	return e.Code == Lookup
+}
+
+// Address is a parsed email address.
+type Address struct {
+	Name   string // Display name, decoded.
+	Local  string // Local part, unquoted.
+	Domain string // Lower cased, ASCII: internationalized domains are punycode encoded.
+}
+
+// Parse parses an RFC 5322 address, with an optional display name,
+// e.g. "Jane Doe <jane@example.com>". Internationalized domains are
+// converted to their ASCII form. Domains may be address literals, e.g.
+// "[192.0.2.1]", or have a single label, e.g. "localhost", which a
+// Validator rejects.
+func Parse(s string) (*Address, error) {
+	s = strings.TrimSpace(s)
+	parsed, err := mail.ParseAddress(s)
+	if err != nil {
+		return nil, &Error{Address: s, Code: Syntax, Err: err}
+	}
+	at := strings.LastIndexByte(parsed.Address, '@')
+	if at < 0 {
+		return nil, &Error{Address: s, Code: Syntax, Err: errors.New("missing @")}
+	}
+	a := &Address{Name: parsed.Name, Local: parsed.Address[:at]}
+	if len(a.Local) > 64 {
+		return nil, &Error{Address: s, Code: Syntax, Err: errors.New("local part longer than 64 bytes")}
+	}
+	if a.Domain, err = domain(parsed.Address[at+1:]); err != nil {
+		return nil, &Error{Address: s, Code: Domain, Err: err}
+	}
+	if len(a.Addr()) > 254 {
+		return nil, &Error{Address: s, Code: Syntax, Err: errors.New("address longer than 254 bytes")}
+	}
+	return a, nil
+}
+
+// domain returns the ASCII form of a domain name or address literal.
+func domain(name string) (string, error) {
+	if strings.HasPrefix(name, "[") && strings.HasSuffix(name, "]") {
+		ip := strings.TrimPrefix(name[1:len(name)-1], "IPv6:")
+		if net.ParseIP(ip) == nil {
+			return "", fmt.Errorf("invalid address literal %s", name)
+		}
+		return name, nil
+	}
+	ascii, err := idna.Lookup.ToASCII(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid domain %s: %w", name, err)
+	}
+	ascii = strings.ToLower(ascii)
+	if len(ascii) > 253 {
+		return "", fmt.Errorf("domain %s longer than 253 bytes", name)
+	}
+	for _, label := range strings.Split(ascii, ".") {
+		if label == "" || len(label) > 63 {
+			return "", fmt.Errorf("invalid domain %s", name)
+		}
+	}
+	return ascii, nil
+}
+
+// Addr returns the address without display name, e.g.
+// "jane@example.com".
+func (a *Address) Addr() string {
+	return a.Local + "@" + a.Domain
+}
+
+// String returns the address with its display name, quoted or encoded
+// as needed, e.g. `"Doe, Jane" <jane@example.com>`.
+func (a *Address) String() string {
+	return (&mail.Address{Name: a.Name, Address: a.Addr()}).String()
+}
+
+// Unicode returns the address without display name, its domain in
+// Unicode form, e.g. "jane@bücher.example".
+func (a *Address) Unicode() string {
+	if d, err := idna.ToUnicode(a.Domain); err == nil {
+		return a.Local + "@" + d
+	}
+	return a.Addr()
+}
+
+// Literal reports whether the domain of a is an address literal.
+func (a *Address) Literal() bool {
+	return strings.HasPrefix(a.Domain, "[")
+}
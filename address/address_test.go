@@ -0,0 +1,138 @@
+package address
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/oarkflow/errors"
+)
+
+func TestParse(t *testing.T) {
+	for s, want := range map[string]string{
+		"jane@example.com":                      "<jane@example.com>",
+		"Jane Doe <Jane@Example.COM>":           `"Jane Doe" <Jane@example.com>`,
+		`"Doe, Jane" <jane@example.com>`:        `"Doe, Jane" <jane@example.com>`,
+		"=?UTF-8?q?Ren=C3=A9?= <r@example.com>": "=?utf-8?q?Ren=C3=A9?= <r@example.com>",
+		"jane@bücher.example":                   "<jane@xn--bcher-kva.example>",
+		"jane@[192.0.2.1]":                      "<jane@[192.0.2.1]>",
+		"jane@localhost":                        "<jane@localhost>",
+	} {
+		a, err := Parse(s)
+		if err != nil {
+			t.Errorf("%s: %v", s, err)
+			continue
+		}
+		if a.String() != want {
+			t.Errorf("%s: want %s, have %s", s, want, a.String())
+		}
+	}
+	if a, _ := Parse("jane@bücher.example"); a.Unicode() != "jane@bücher.example" {
+		t.Errorf("want unicode domain, have %s", a.Unicode())
+	}
+
+	for s, code := range map[string]Code{
+		"jane":                                   Syntax,
+		"jane@":                                  Syntax,
+		"jane doe@example.com":                   Syntax,
+		strings.Repeat("j", 65) + "@example.com": Syntax,
+		"jane@exa_mple.com":                      Domain,
+		"jane@-example.com":                      Domain,
+		"jane@[300.0.0.1]":                       Syntax,
+		"jane@" + strings.Repeat("a", 64) + ".com": Domain,
+	} {
+		_, err := Parse(s)
+		var e *Error
+		if !errors.As(err, &e) || e.Code != code {
+			t.Errorf("%s: want %s error, have %v", s, code, err)
+		}
+	}
+}
+
+type fakeResolver struct {
+	mx    map[string][]*net.MX
+	hosts map[string][]string
+	fail  map[string]bool // Domains whose lookups time out.
+	n     int
+}
+
+func (r *fakeResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	r.n++
+	if r.fail[name] {
+		return nil, &net.DNSError{Err: "i/o timeout", Name: name, IsTimeout: true}
+	}
+	if mx, ok := r.mx[name]; ok {
+		return mx, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if hosts, ok := r.hosts[host]; ok {
+		return hosts, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestValidator(t *testing.T) {
+	resolver := &fakeResolver{
+		mx: map[string][]*net.MX{
+			"example.com":      {{Host: "mx.example.com.", Pref: 10}},
+			"nomail.example":   {{Host: ".", Pref: 0}},
+			"xn--bcher-kva.ch": {{Host: "mx.xn--bcher-kva.ch.", Pref: 10}},
+		},
+		hosts: map[string][]string{"implicit.example": {"192.0.2.1"}},
+		fail:  map[string]bool{"slow.example": true},
+	}
+	disposable, err := LoadDomains(strings.NewReader("# Disposable\nthrowaway.example\n\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := &Validator{Disposable: disposable, CheckMX: true, Resolver: resolver}
+	ctx := context.Background()
+
+	for _, s := range []string{"jane@example.com", "Jane <jane@EXAMPLE.com>", "jane@bücher.ch", "jane@implicit.example", "jane@[192.0.2.1]"} {
+		if _, err = v.Validate(ctx, s); err != nil {
+			t.Errorf("%s: %v", s, err)
+		}
+	}
+	if resolver.n != 3 {
+		t.Errorf("want mail servers of example.com cached, have %d MX lookups", resolver.n)
+	}
+	for s, code := range map[string]Code{
+		"jane":                     Syntax,
+		"jane@localhost":           Domain,
+		"jane@throwaway.example":   Disposable,
+		"jane@x.throwaway.example": Disposable,
+		"jane@nomail.example":      NoMailServer,
+		"jane@missing.example":     NoMailServer,
+		"jane@slow.example":        Lookup,
+	} {
+		_, err = v.Validate(ctx, s)
+		var e *Error
+		if !errors.As(err, &e) || e.Code != code {
+			t.Errorf("%s: want %s error, have %v", s, code, err)
+		} else if e.Temporary() != (code == Lookup) {
+			t.Errorf("%s: want temporary %v", s, code == Lookup)
+		}
+	}
+	// Lookup failures are not cached.
+	n := resolver.n
+	v.Validate(ctx, "jane@slow.example")
+	v.Validate(ctx, "jane@missing.example")
+	if resolver.n != n+1 {
+		t.Errorf("want one more MX lookup, have %d", resolver.n-n)
+	}
+
+	var none *Validator
+	if _, err = none.Validate(ctx, "jane@localhost"); err != nil {
+		t.Fatalf("want single label domain accepted without validator, have %v", err)
+	}
+	if _, err = none.Validate(ctx, "jane@mailinator.com"); err != nil {
+		t.Fatalf("want syntax only checked without validator, have %v", err)
+	}
+	if !DisposableDomains.Contains("mailinator.com") {
+		t.Fatal("want mailinator.com disposable")
+	}
+}
@@ -0,0 +1,200 @@
+package address
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oarkflow/errors"
+)
+
+const (
+	// DefaultTimeout bounds the DNS lookups of an address.
+	DefaultTimeout = 5 * time.Second
+	// DefaultCacheDuration is how long the mail servers of a domain
+	// are remembered.
+	DefaultCacheDuration = 10 * time.Minute
+)
+
+// Resolver looks up the mail servers of domains, *net.Resolver in
+// production and a fake in tests.
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Domains is a set of domains, also matching their subdomains. It
+// must not be modified while used by a Validator.
+type Domains map[string]struct{}
+
+// DisposableDomains are well known disposable mail services.
+var DisposableDomains = NewDomains(
+	"10minutemail.com",
+	"dispostable.com",
+	"getnada.com",
+	"guerrillamail.com",
+	"guerrillamail.net",
+	"mailinator.com",
+	"maildrop.cc",
+	"mintemail.com",
+	"sharklasers.com",
+	"temp-mail.org",
+	"tempmail.com",
+	"throwawaymail.com",
+	"trashmail.com",
+	"yopmail.com",
+)
+
+// NewDomains returns the set of the given domains.
+func NewDomains(domains ...string) Domains {
+	d := make(Domains, len(domains))
+	d.Add(domains...)
+	return d
+}
+
+// LoadDomains reads a list of domains, one per line. Blank lines and
+// lines starting with # are ignored.
+func LoadDomains(r io.Reader) (Domains, error) {
+	d := make(Domains)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && line[0] != '#' {
+			d.Add(line)
+		}
+	}
+	return d, scanner.Err()
+}
+
+// Add adds domains to d, in ASCII form.
+func (d Domains) Add(domains ...string) {
+	for _, name := range domains {
+		if ascii, err := domain(strings.TrimSuffix(name, ".")); err == nil {
+			d[ascii] = struct{}{}
+		}
+	}
+}
+
+// Contains reports whether the ASCII domain name, or one of its
+// parent domains, is in d.
+func (d Domains) Contains(name string) bool {
+	name = strings.ToLower(name)
+	for {
+		if _, ok := d[name]; ok {
+			return true
+		}
+		_, parent, ok := strings.Cut(name, ".")
+		if !ok {
+			return false
+		}
+		name = parent
+	}
+}
+
+// Validator validates addresses beyond their syntax, safe for
+// concurrent use. A nil *Validator only parses them.
+type Validator struct {
+	Disposable Domains // Domains rejected, e.g. DisposableDomains.
+	// CheckMX rejects the domains without mail server: neither MX
+	// nor address records, or a null MX (RFC 7505).
+	CheckMX       bool
+	Resolver      Resolver      // net.DefaultResolver if nil.
+	Timeout       time.Duration // DefaultTimeout if 0.
+	CacheDuration time.Duration // DefaultCacheDuration if 0, lookup failures not being cached.
+	mu            sync.Mutex
+	cache         map[string]lookup
+}
+
+type lookup struct {
+	err     error // Of the mail servers of the domain, nil if found.
+	expires time.Time
+}
+
+// Validate parses s, see Parse, and checks its domain, which must be
+// fully qualified.
+func (v *Validator) Validate(ctx context.Context, s string) (*Address, error) {
+	a, err := Parse(s)
+	if err != nil || v == nil || a.Literal() {
+		return a, err
+	}
+	if !strings.Contains(a.Domain, ".") {
+		return nil, &Error{Address: s, Code: Domain, Err: fmt.Errorf("domain %s is not fully qualified", a.Domain)}
+	}
+	if v.Disposable.Contains(a.Domain) {
+		return nil, &Error{Address: s, Code: Disposable, Err: fmt.Errorf("disposable domain %s", a.Domain)}
+	}
+	if v.CheckMX {
+		if err = v.mailServer(ctx, a.Domain); err != nil {
+			code := NoMailServer
+			var dnsErr *net.DNSError
+			if errors.As(err, &dnsErr) && !dnsErr.IsNotFound {
+				code = Lookup
+			}
+			return nil, &Error{Address: s, Code: code, Err: err}
+		}
+	}
+	return a, nil
+}
+
+// mailServer returns an error unless domain has a mail server,
+// remembering the outcome unless the lookup failed.
+func (v *Validator) mailServer(ctx context.Context, domain string) error {
+	now := time.Now()
+	v.mu.Lock()
+	cached, ok := v.cache[domain]
+	v.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.err
+	}
+	timeout := v.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := v.lookup(ctx, domain)
+	var dnsErr *net.DNSError
+	if err != nil && (!errors.As(err, &dnsErr) || !dnsErr.IsNotFound) {
+		return err
+	}
+	duration := v.CacheDuration
+	if duration <= 0 {
+		duration = DefaultCacheDuration
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.cache == nil {
+		v.cache = make(map[string]lookup)
+	}
+	v.cache[domain] = lookup{err: err, expires: now.Add(duration)}
+	return err
+}
+
+// lookup returns an error unless domain has MX records, or address
+// records standing for an implicit MX (RFC 5321 section 5.1).
+// Domains without mail server fail with a *net.DNSError not found.
+func (v *Validator) lookup(ctx context.Context, domain string) error {
+	var resolver Resolver = net.DefaultResolver
+	if v.Resolver != nil {
+		resolver = v.Resolver
+	}
+	mxs, err := resolver.LookupMX(ctx, domain)
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil && len(mxs) == 1 && strings.TrimSuffix(mxs[0].Host, ".") == "":
+		return &net.DNSError{Err: "null MX, domain accepts no mail", Name: domain, IsNotFound: true}
+	case err == nil && len(mxs) > 0:
+		return nil
+	case err != nil && (!errors.As(err, &dnsErr) || !dnsErr.IsNotFound):
+		return err
+	}
+	hosts, err := resolver.LookupHost(ctx, domain)
+	if err == nil && len(hosts) == 0 {
+		err = &net.DNSError{Err: "no mail server", Name: domain, IsNotFound: true}
+	}
+	return err
+}
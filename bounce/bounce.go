@@ -0,0 +1,275 @@
+// Package bounce parses the reports mail servers send back about
+// delivered messages: RFC 3464 delivery status notifications (DSN),
+// e.g. bounces, and RFC 5965 abuse reporting format (ARF) feedback
+// reports, e.g. spam complaints.
+//
+// Reports refer to the original message by its Message-ID, which
+// senders keep to correlate them with what they sent.
+package bounce
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+
+	"github.com/oarkflow/errors"
+)
+
+// ErrNotReport is returned by Parse for messages that are neither a
+// delivery status notification nor a feedback report.
+var ErrNotReport = errors.New("Message is not a delivery status notification or feedback report")
+
+// ErrMalformed is wrapped by the errors Parse returns for messages
+// that are not valid MIME, which are not reports either.
+var ErrMalformed = errors.New("Malformed message")
+
+// Kind is the kind of a report.
+type Kind string
+
+const (
+	DSN      Kind = "dsn"      // Delivery status notification.
+	Feedback Kind = "feedback" // Abuse feedback report.
+)
+
+// Action is the outcome of a message for a recipient of a DSN.
+type Action string
+
+const (
+	Failed    Action = "failed"
+	Delayed   Action = "delayed"
+	Delivered Action = "delivered"
+	Relayed   Action = "relayed"
+	Expanded  Action = "expanded"
+)
+
+// Recipient is the outcome of the original message for one of its
+// recipients.
+type Recipient struct {
+	Address    string `json:"address"`
+	Action     Action `json:"action,omitempty"`     // Of DSNs.
+	Status     string `json:"status,omitempty"`     // Enhanced status code, e.g. "5.1.1".
+	Diagnostic string `json:"diagnostic,omitempty"` // Reply of the remote server, e.g. "550 5.1.1 User unknown", or the feedback type.
+}
+
+// BadMailbox reports whether delivery to the recipient failed because
+// its mailbox does not exist, see BadMailbox.
+func (r Recipient) BadMailbox() bool {
+	return r.Action == Failed && BadMailbox(r.Status)
+}
+
+// BadMailbox reports whether the enhanced status code is a permanent
+// rejection of the recipient mailbox, i.e. 5.1.1 (bad mailbox), 5.1.2
+// (bad domain), 5.1.6 (mailbox moved) and 5.1.10 (null MX). Other
+// permanent failures may be caused by the sender.
+func BadMailbox(status string) bool {
+	switch status {
+	case "5.1.1", "5.1.2", "5.1.6", "5.1.10":
+		return true
+	}
+	return false
+}
+
+// Report is a delivery status notification or feedback report.
+type Report struct {
+	Kind         Kind        `json:"kind"`
+	MessageID    string      `json:"message_id,omitempty"` // Message-ID of the original message, without angle brackets.
+	ReportingMTA string      `json:"reporting_mta,omitempty"`
+	FeedbackType string      `json:"feedback_type,omitempty"` // Of feedback reports, e.g. "abuse".
+	Recipients   []Recipient `json:"recipients"`
+}
+
+// Failed returns the recipients the original message failed for.
+func (r *Report) Failed() []Recipient {
+	var failed []Recipient
+	for _, rcpt := range r.Recipients {
+		if rcpt.Action == Failed {
+			failed = append(failed, rcpt)
+		}
+	}
+	return failed
+}
+
+// Parse parses the raw MIME message read from r, a multipart/report
+// of report-type delivery-status or feedback-report.
+func Parse(r io.Reader) (*Report, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/report" || params["boundary"] == "" {
+		return nil, ErrNotReport
+	}
+	report := &Report{}
+	switch strings.ToLower(params["report-type"]) {
+	case "delivery-status":
+		report.Kind = DSN
+	case "feedback-report":
+		report.Kind = Feedback
+	default:
+		return nil, ErrNotReport
+	}
+	var original textproto.MIMEHeader
+	parsed := false
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		body := decode(part, part.Header.Get("Content-Transfer-Encoding"))
+		switch partType {
+		case "message/delivery-status", "message/global-delivery-status":
+			if err = report.parseDSN(body); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+			}
+			parsed = true
+		case "message/feedback-report":
+			if err = report.parseFeedback(body); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+			}
+			parsed = true
+		case "message/rfc822", "message/global", "text/rfc822-headers", "message/global-headers":
+			// Only the headers of the original message are needed.
+			original, _ = textproto.NewReader(bufio.NewReader(body)).ReadMIMEHeader()
+		}
+	}
+	if !parsed {
+		return nil, ErrNotReport
+	}
+	if original != nil {
+		if report.MessageID == "" {
+			report.MessageID = trimID(original.Get("Message-Id"))
+		}
+		// Feedback reports may omit Original-Rcpt-To.
+		if report.Kind == Feedback && len(report.Recipients) == 0 {
+			if to, err := mail.ParseAddressList(original.Get("To")); err == nil {
+				for _, addr := range to {
+					report.Recipients = append(report.Recipients, Recipient{Address: addr.Address, Diagnostic: report.FeedbackType})
+				}
+			}
+		}
+	}
+	return report, nil
+}
+
+// parseDSN parses the per-message fields of a delivery-status part
+// followed by the fields of each recipient, separated by blank lines.
+func (r *Report) parseDSN(body io.Reader) error {
+	groups, err := fieldGroups(body)
+	if err != nil {
+		return err
+	}
+	for i, fields := range groups {
+		if i == 0 {
+			r.ReportingMTA = typedValue(fields.Get("Reporting-Mta"))
+			continue
+		}
+		address := typedValue(fields.Get("Final-Recipient"))
+		if address == "" {
+			address = typedValue(fields.Get("Original-Recipient"))
+		}
+		if address == "" {
+			continue
+		}
+		status := strings.TrimSpace(fields.Get("Status"))
+		if j := strings.IndexAny(status, " ("); j > 0 {
+			status = status[:j]
+		}
+		r.Recipients = append(r.Recipients, Recipient{
+			Address:    address,
+			Action:     Action(strings.ToLower(strings.TrimSpace(fields.Get("Action")))),
+			Status:     status,
+			Diagnostic: typedValue(fields.Get("Diagnostic-Code")),
+		})
+	}
+	return nil
+}
+
+// parseFeedback parses the fields of a feedback-report part.
+func (r *Report) parseFeedback(body io.Reader) error {
+	groups, err := fieldGroups(body)
+	if err != nil {
+		return err
+	}
+	for _, fields := range groups {
+		if v := fields.Get("Feedback-Type"); v != "" {
+			r.FeedbackType = strings.ToLower(strings.TrimSpace(v))
+		}
+		if v := fields.Get("Reporting-Mta"); v != "" {
+			r.ReportingMTA = typedValue(v)
+		}
+		for _, to := range fields.Values("Original-Rcpt-To") {
+			if to = trimID(to); to != "" {
+				r.Recipients = append(r.Recipients, Recipient{Address: to})
+			}
+		}
+	}
+	for i := range r.Recipients {
+		r.Recipients[i].Diagnostic = r.FeedbackType
+	}
+	return nil
+}
+
+// fieldGroups reads the groups of header fields of body.
+func fieldGroups(body io.Reader) ([]textproto.MIMEHeader, error) {
+	br := bufio.NewReader(body)
+	var groups []textproto.MIMEHeader
+	for {
+		// Blank lines separate groups, and may be repeated.
+		for {
+			b, err := br.Peek(1)
+			if err != nil {
+				return groups, nil
+			}
+			if b[0] != '\r' && b[0] != '\n' {
+				break
+			}
+			br.ReadByte()
+		}
+		fields, err := textproto.NewReader(br).ReadMIMEHeader()
+		if len(fields) > 0 {
+			groups = append(groups, fields)
+		}
+		// The last group may not end with a blank line.
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return groups, nil
+		}
+		if err != nil {
+			return groups, err
+		}
+	}
+}
+
+// typedValue returns the value of a field of the form "type; value",
+// e.g. "rfc822; jane@example.com".
+func typedValue(v string) string {
+	if _, value, ok := strings.Cut(v, ";"); ok {
+		v = value
+	}
+	return strings.TrimSpace(v)
+}
+
+// trimID returns a message ID or address without angle brackets.
+func trimID(id string) string {
+	return strings.Trim(strings.TrimSpace(id), "<>")
+}
+
+// decode decodes a base64 part, others being decoded by
+// multipart.Reader.
+func decode(r io.Reader, encoding string) io.Reader {
+	if strings.EqualFold(strings.TrimSpace(encoding), "base64") {
+		return base64.NewDecoder(base64.StdEncoding, r)
+	}
+	return r
+}
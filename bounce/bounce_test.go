@@ -0,0 +1,139 @@
+package bounce
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/oarkflow/errors"
+)
+
+const dsn = `From: MAILER-DAEMON@mx.example.net
+To: noreply@example.com
+Subject: Undelivered Mail Returned to Sender
+MIME-Version: 1.0
+Content-Type: multipart/report; report-type=delivery-status; boundary="b1"
+
+--b1
+Content-Type: text/plain
+
+The mail could not be delivered.
+
+--b1
+Content-Type: message/delivery-status
+
+Reporting-MTA: dns; mx.example.net
+Arrival-Date: Mon, 5 Oct 2026 10:00:00 +0000
+
+Final-Recipient: rfc822; jane@example.net
+Original-Recipient: rfc822; Jane@example.net
+Action: failed
+Status: 5.1.1
+Diagnostic-Code: smtp; 550 5.1.1 <jane@example.net>:
+ Recipient address rejected: User unknown
+
+Final-Recipient: rfc822; john@example.net
+Action: delayed
+Status: 4.4.1 (connection timed out)
+
+--b1
+Content-Type: text/rfc822-headers
+
+From: noreply@example.com
+To: jane@example.net, john@example.net
+Message-ID: <1.2.3@example.com>
+Subject: Hi
+
+--b1--
+`
+
+const arf = `From: feedback@isp.example
+To: abuse@example.com
+MIME-Version: 1.0
+Content-Type: multipart/report; report-type=feedback-report; boundary="b2"
+
+--b2
+Content-Type: text/plain
+
+This is an email abuse report.
+
+--b2
+Content-Type: message/feedback-report
+Content-Transfer-Encoding: base64
+
+RmVlZGJhY2stVHlwZTogYWJ1c2UKVXNlci1BZ2VudDogU29tZUdlbmVyYXRvci8xLjAKVmVyc2lv
+bjogMQo=
+
+--b2
+Content-Type: message/rfc822
+
+From: noreply@example.com
+To: Joe <joe@isp.example>
+Message-ID: <4.5.6@example.com>
+Subject: Offer
+
+Buy now.
+--b2--
+`
+
+func TestParse(t *testing.T) {
+	r, err := Parse(strings.NewReader(strings.ReplaceAll(dsn, "\n", "\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Kind != DSN || r.MessageID != "1.2.3@example.com" || r.ReportingMTA != "mx.example.net" || len(r.Recipients) != 2 {
+		t.Fatalf("want DSN of 2 recipients, have %+v", r)
+	}
+	jane, john := r.Recipients[0], r.Recipients[1]
+	if jane.Address != "jane@example.net" || !jane.BadMailbox() || !strings.HasPrefix(jane.Diagnostic, "550 5.1.1") {
+		t.Fatalf("want bad mailbox, have %+v", jane)
+	}
+	if john.Action != Delayed || john.Status != "4.4.1" || john.BadMailbox() {
+		t.Fatalf("want delayed, have %+v", john)
+	}
+	if failed := r.Failed(); len(failed) != 1 || failed[0].Address != jane.Address {
+		t.Fatalf("want jane failed, have %+v", failed)
+	}
+
+	r, err = Parse(strings.NewReader(arf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Kind != Feedback || r.FeedbackType != "abuse" || r.MessageID != "4.5.6@example.com" ||
+		len(r.Recipients) != 1 || r.Recipients[0].Address != "joe@isp.example" {
+		t.Fatalf("want abuse report of joe, have %+v", r)
+	}
+
+	if _, err = Parse(strings.NewReader("Subject: Hi\r\nContent-Type: text/plain\r\n\r\nHello\r\n")); !errors.Is(err, ErrNotReport) {
+		t.Fatalf("want ErrNotReport, have %v", err)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{"1.eml": dsn, "2.eml": "Subject: Hi\n\nHello\n", "3.eml": arf, "4.eml": "Not a header\n\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var kinds []Kind
+	n, err := Drain(Dir(dir), func(r io.Reader) error {
+		report, err := Parse(r)
+		if err != nil {
+			return err
+		}
+		if report.Kind == Feedback {
+			return errors.New("store unavailable")
+		}
+		kinds = append(kinds, report.Kind)
+		return nil
+	})
+	if err != nil || n != 3 || len(kinds) != 1 {
+		t.Fatalf("want the DSN, non report and malformed message drained, have %d %v %v", n, kinds, err)
+	}
+	if ids, _ := Dir(dir).List(); len(ids) != 1 || ids[0] != "3.eml" {
+		t.Fatalf("want failed report kept, have %v", ids)
+	}
+}
@@ -0,0 +1,101 @@
+package bounce
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/oarkflow/errors"
+	"github.com/oarkflow/log"
+)
+
+// Mailbox is a mailbox receiving reports, e.g. the inbox of the
+// return path of sent mail read over IMAP or POP3.
+type Mailbox interface {
+	// List returns the IDs of the messages in the mailbox.
+	List() ([]string, error)
+	// Open returns the raw message with the given ID.
+	Open(id string) (io.ReadCloser, error)
+	// Delete removes the message with the given ID once processed.
+	Delete(id string) error
+}
+
+// Dir is a Mailbox of the files of a directory, one message each,
+// e.g. the "new" directory of a Maildir fed by the mail server.
+type Dir string
+
+func (d Dir) List() ([]string, error) {
+	entries, err := os.ReadDir(string(d))
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && e.Name()[0] != '.' {
+			ids = append(ids, e.Name())
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (d Dir) Open(id string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), filepath.Base(id)))
+}
+
+func (d Dir) Delete(id string) error {
+	return os.Remove(filepath.Join(string(d), filepath.Base(id)))
+}
+
+// Drain processes the messages of mailbox and returns how many were
+// processed. Messages are deleted once processed, as are those that
+// are not reports or are malformed, see ErrMalformed, which would
+// fail again. Those process fails for otherwise, e.g. because the
+// suppression list is unavailable, are kept for the next pass.
+func Drain(mailbox Mailbox, process func(io.Reader) error) (int, error) {
+	ids, err := mailbox.List()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, id := range ids {
+		r, err := mailbox.Open(id)
+		if err != nil {
+			log.Error().Err(err).Str("message", id).Msg("Unable to open report")
+			continue
+		}
+		err = process(r)
+		r.Close()
+		switch {
+		case errors.Is(err, ErrMalformed):
+			log.Warn().Err(err).Str("message", id).Msg("Deleting malformed report")
+		case err != nil && !errors.Is(err, ErrNotReport):
+			log.Error().Err(err).Str("message", id).Msg("Unable to process report")
+			continue
+		}
+		if err = mailbox.Delete(id); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Poll drains mailbox every interval until ctx is done.
+func Poll(ctx context.Context, mailbox Mailbox, interval time.Duration, process func(io.Reader) error) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := Drain(mailbox, process); err != nil {
+			log.Error().Err(err).Msg("Unable to drain report mailbox")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
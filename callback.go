@@ -0,0 +1,409 @@
+package protocol
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/oarkflow/errors"
+	"github.com/oarkflow/log"
+
+	"github.com/oarkflow/protocol/http"
+	"github.com/oarkflow/protocol/smpp"
+	"github.com/oarkflow/protocol/utils/xid"
+)
+
+// CallbackEvent is a stage of the lifecycle of a payload.
+type CallbackEvent string
+
+const (
+	EventQueued    CallbackEvent = "queued"
+	EventSent      CallbackEvent = "sent"
+	EventDelivered CallbackEvent = "delivered"
+	EventFailed    CallbackEvent = "failed"
+)
+
+const (
+	SignatureHeader = "X-Signature"           // "sha256=" and the hex HMAC of timestamp, "." and body.
+	TimestampHeader = "X-Signature-Timestamp" // Unix time of the signature.
+)
+
+// CallbackPart is a part of a long SMS.
+type CallbackPart struct {
+	ID          string    `json:"id"`
+	MessageID   string    `json:"message_id,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	SentAt      time.Time `json:"sent_at"`
+	DeliveredAt time.Time `json:"delivered_at"`
+	FailedAt    time.Time `json:"failed_at"`
+}
+
+// Callback is the JSON body posted to the CallbackURL of a payload.
+type Callback struct {
+	ID          string         `json:"id"` // Unique per event, for deduplication by the receiver.
+	Event       CallbackEvent  `json:"event"`
+	PayloadID   string         `json:"payload_id"`
+	JobID       string         `json:"job_id,omitempty"`
+	Type        Type           `json:"type"`
+	Service     string         `json:"service,omitempty"`
+	To          string         `json:"to,omitempty"`
+	Response    string         `json:"response,omitempty"` // Response of the server, if any.
+	Error       string         `json:"error,omitempty"`
+	Parts       []CallbackPart `json:"parts,omitempty"`
+	SentAt      time.Time      `json:"sent_at"`
+	DeliveredAt time.Time      `json:"delivered_at"`
+	FailedAt    time.Time      `json:"failed_at"`
+	Timestamp   time.Time      `json:"timestamp"`
+}
+
+// DeadCallback is a callback that could not be delivered.
+type DeadCallback struct {
+	URL      string    `json:"url"`
+	Callback Callback  `json:"callback"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	DiedAt   time.Time `json:"died_at"`
+}
+
+// DeadLetter records undeliverable callbacks.
+type DeadLetter interface {
+	Record(dead DeadCallback) error
+}
+
+// FileDeadLetter appends undeliverable callbacks to a file, one JSON
+// line each. The file is readable by its owner only, the callbacks
+// being signed.
+type FileDeadLetter struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileDeadLetter(path string) *FileDeadLetter {
+	return &FileDeadLetter{path: path}
+}
+
+func (d *FileDeadLetter) Record(dead DeadCallback) error {
+	b, err := json.Marshal(dead)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err = os.MkdirAll(filepath.Dir(d.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(d.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadDeadLetters returns the callbacks recorded by a FileDeadLetter,
+// e.g. to dispatch them again.
+func ReadDeadLetters(r io.Reader) ([]DeadCallback, error) {
+	var deads []DeadCallback
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var dead DeadCallback
+		if err := json.Unmarshal(sc.Bytes(), &dead); err != nil {
+			return deads, err
+		}
+		deads = append(deads, dead)
+	}
+	return deads, sc.Err()
+}
+
+// CallbackOptions configures a CallbackDispatcher.
+type CallbackOptions struct {
+	Secret      string                          // HMAC-SHA256 key, callbacks are not signed if empty.
+	MaxAttempts int                             // Default 5.
+	Backoff     func(attempt int) time.Duration // Wait before the next attempt, default 10s doubled each attempt up to 1h.
+	Client      *http.Client                    // Default a client retrying connection errors twice.
+	// DeadLetter records undeliverable callbacks, a FileDeadLetter
+	// at DeadLetterPath by default.
+	DeadLetter     DeadLetter
+	DeadLetterPath string // Default "callbacks.dead" in the queue journal directory.
+}
+
+// CallbackDispatcher posts callbacks in the background, retrying
+// failed deliveries, i.e. errors and non-2xx responses, with backoff.
+type CallbackDispatcher struct {
+	opts   CallbackOptions
+	mu     sync.Mutex
+	timers map[string]*retry
+	wg     sync.WaitGroup
+	closed bool
+}
+
+type retry struct {
+	timer *time.Timer
+	dead  DeadCallback
+}
+
+func NewCallbackDispatcher(opts CallbackOptions) *CallbackDispatcher {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = func(attempt int) time.Duration {
+			if attempt > 8 {
+				return time.Hour
+			}
+			return min(10*time.Second<<(attempt-1), time.Hour)
+		}
+	}
+	if opts.Client == nil {
+		opts.Client = http.NewClient(&http.Options{
+			RetryWaitMin:  500 * time.Millisecond,
+			RetryWaitMax:  5 * time.Second,
+			Timeout:       30 * time.Second,
+			RetryMax:      2,
+			RespReadLimit: 4096,
+		})
+	}
+	if opts.DeadLetter == nil {
+		path := opts.DeadLetterPath
+		if path == "" {
+			path = filepath.Join(filepath.Dir(DefaultJournalPath("callbacks")), "callbacks.dead")
+		}
+		opts.DeadLetter = NewFileDeadLetter(path)
+	}
+	return &CallbackDispatcher{opts: opts, timers: make(map[string]*retry)}
+}
+
+// Dispatch posts cb to url in the background. It does nothing if url
+// is empty.
+func (d *CallbackDispatcher) Dispatch(url string, cb Callback) {
+	if d == nil || url == "" {
+		return
+	}
+	if cb.ID == "" {
+		cb.ID = xid.New().String()
+	}
+	if cb.Timestamp.IsZero() {
+		cb.Timestamp = time.Now()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		d.deadLetter(DeadCallback{URL: url, Callback: cb, Error: "dispatcher closed"})
+		return
+	}
+	d.wg.Add(1)
+	go d.attempt(DeadCallback{URL: url, Callback: cb, Attempts: 1})
+}
+
+// Close waits for the deliveries in progress and records the pending
+// retries as dead letters.
+func (d *CallbackDispatcher) Close() {
+	d.mu.Lock()
+	d.closed = true
+	for id, r := range d.timers {
+		if r.timer.Stop() {
+			r.dead.Error = "dispatcher closed: " + r.dead.Error
+			d.deadLetter(r.dead)
+			d.wg.Done()
+		}
+		delete(d.timers, id)
+	}
+	d.mu.Unlock()
+	d.wg.Wait()
+}
+
+func (d *CallbackDispatcher) attempt(dc DeadCallback) {
+	defer d.wg.Done()
+	err := d.post(dc.URL, dc.Callback)
+	if err == nil {
+		return
+	}
+	dc.Error = err.Error()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if dc.Attempts >= d.opts.MaxAttempts || d.closed {
+		d.deadLetter(dc)
+		return
+	}
+	log.Error().Err(err).Str("url", dc.URL).Str("callback_id", dc.Callback.ID).Int("attempt", dc.Attempts).Msg("Unable to deliver callback")
+	r := &retry{dead: dc}
+	d.wg.Add(1)
+	r.timer = time.AfterFunc(d.opts.Backoff(dc.Attempts), func() {
+		d.mu.Lock()
+		delete(d.timers, dc.Callback.ID)
+		d.mu.Unlock()
+		dc.Attempts++
+		d.attempt(dc)
+	})
+	d.timers[dc.Callback.ID] = r
+}
+
+func (d *CallbackDispatcher) post(url string, cb Callback) error {
+	body, err := json.Marshal(cb)
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{"Content-Type": "application/json"}
+	if d.opts.Secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		headers[TimestampHeader] = ts
+		headers[SignatureHeader] = SignCallback(d.opts.Secret, ts, body)
+	}
+	resp, err := d.opts.Client.Request("POST", url, body, headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback rejected with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// deadLetter records dc, d.mu is held.
+func (d *CallbackDispatcher) deadLetter(dc DeadCallback) {
+	dc.DiedAt = time.Now()
+	log.Error().Str("url", dc.URL).Str("callback_id", dc.Callback.ID).Int("attempts", dc.Attempts).Str("error", dc.Error).Msg("Callback undeliverable")
+	if err := d.opts.DeadLetter.Record(dc); err != nil {
+		log.Error().Err(err).Str("callback_id", dc.Callback.ID).Msg("Unable to record dead callback")
+	}
+}
+
+// SignCallback returns the signature header value of body sent at
+// timestamp, a Unix time.
+func SignCallback(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyCallback checks the signature of a received callback body and
+// that its timestamp is within tolerance of now.
+func VerifyCallback(secret, timestamp, signature string, body []byte, tolerance time.Duration) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("Invalid callback timestamp")
+	}
+	if d := time.Since(time.Unix(ts, 0)); d > tolerance || d < -tolerance {
+		return errors.New("Callback timestamp out of tolerance")
+	}
+	if !hmac.Equal([]byte(signature), []byte(SignCallback(secret, timestamp, body))) {
+		return errors.New("Invalid callback signature")
+	}
+	return nil
+}
+
+// newCallback returns the callback of event for payload handled by
+// service.
+func newCallback(service Service, payload Payload, event CallbackEvent) Callback {
+	return Callback{
+		Event:       event,
+		PayloadID:   payload.ID,
+		Type:        service.GetType(),
+		Service:     service.GetServiceType(),
+		To:          payload.To,
+		SentAt:      payload.SentAt,
+		DeliveredAt: payload.DeliveredAt,
+		FailedAt:    payload.FailedAt,
+	}
+}
+
+// notify dispatches the outcome of handling payload, event if
+// handling succeeded.
+func (d *CallbackDispatcher) notify(service Service, payload Payload, event CallbackEvent, res Response, err error) {
+	if d == nil || payload.CallbackURL == "" {
+		return
+	}
+	now := time.Now()
+	if err != nil {
+		event = EventFailed
+		if payload.FailedAt.IsZero() {
+			payload.FailedAt = now
+		}
+	} else {
+		if payload.SentAt.IsZero() {
+			payload.SentAt = now
+		}
+		if event == EventDelivered && payload.DeliveredAt.IsZero() {
+			payload.DeliveredAt = now
+		}
+	}
+	cb := newCallback(service, payload, event)
+	if err != nil {
+		cb.Error = err.Error()
+	}
+	switch res := res.(type) {
+	case string:
+		cb.Response = truncate(res)
+	case []byte:
+		cb.Response = truncate(string(res))
+	}
+	d.Dispatch(payload.CallbackURL, cb)
+}
+
+// truncate cuts s to at most 1024 bytes, on a rune boundary.
+func truncate(s string) string {
+	if len(s) <= 1024 {
+		return s
+	}
+	n := 1024
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// smsCallback returns the callback of sms and its parts, the event
+// being derived from the status of sms.
+func smsCallback(service Service, sms *smpp.Message, parts []*smpp.Part) (Callback, bool) {
+	cb := Callback{
+		PayloadID:   sms.ID,
+		Type:        service.GetType(),
+		Service:     service.GetServiceType(),
+		To:          sms.To,
+		Error:       sms.Error,
+		SentAt:      sms.SentAt,
+		DeliveredAt: sms.DeliveredAt,
+		FailedAt:    sms.FailedAt,
+	}
+	switch sms.MessageStatus {
+	case smpp.SENT:
+		cb.Event = EventSent
+	case smpp.DELIVERED:
+		cb.Event = EventDelivered
+	case smpp.FAILED:
+		cb.Event = EventFailed
+	default:
+		return cb, false
+	}
+	for _, p := range parts {
+		cb.Parts = append(cb.Parts, CallbackPart{
+			ID:          p.ID,
+			MessageID:   p.MessageID,
+			Status:      p.MessageStatus,
+			Error:       p.Error,
+			SentAt:      p.SentAt,
+			DeliveredAt: p.DeliveredAt,
+			FailedAt:    p.FailedAt,
+		})
+	}
+	return cb, true
+}
@@ -0,0 +1,181 @@
+package protocol
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oarkflow/protocol/smtp"
+	"github.com/oarkflow/protocol/smtp/smtptest"
+)
+
+type deadLetters chan DeadCallback
+
+func (d deadLetters) Record(dead DeadCallback) error {
+	d <- dead
+	return nil
+}
+
+func TestCallbackDispatcher(t *testing.T) {
+	var calls atomic.Int32
+	received := make(chan Callback, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := VerifyCallback("secret", r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), body, time.Minute); err != nil {
+			t.Errorf("want valid signature, have %v", err)
+		}
+		// The first attempt is rejected.
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var cb Callback
+		json.Unmarshal(body, &cb)
+		received <- cb
+	}))
+	defer srv.Close()
+
+	dead := make(deadLetters, 1)
+	d := NewCallbackDispatcher(CallbackOptions{
+		Secret:     "secret",
+		Backoff:    func(int) time.Duration { return time.Millisecond },
+		DeadLetter: dead,
+	})
+	d.Dispatch(srv.URL, Callback{Event: EventSent, PayloadID: "p1"})
+	select {
+	case cb := <-received:
+		if cb.Event != EventSent || cb.PayloadID != "p1" || cb.ID == "" {
+			t.Fatalf("want sent callback, have %+v", cb)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback not retried")
+	}
+	d.Close()
+
+	if err := VerifyCallback("secret", "1", SignCallback("secret", "1", nil), nil, time.Minute); err == nil {
+		t.Fatal("want error for stale timestamp")
+	}
+}
+
+func TestCallbackDeadLetter(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	dead := make(deadLetters, 1)
+	d := NewCallbackDispatcher(CallbackOptions{
+		MaxAttempts: 3,
+		Backoff:     func(int) time.Duration { return time.Millisecond },
+		DeadLetter:  dead,
+	})
+	defer d.Close()
+	d.Dispatch(srv.URL, Callback{Event: EventFailed, PayloadID: "p1"})
+	select {
+	case dc := <-dead:
+		if dc.Attempts != 3 || calls.Load() != 3 || dc.URL != srv.URL || dc.Callback.PayloadID != "p1" {
+			t.Fatalf("want dead letter after 3 attempts, have %+v", dc)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback not dead-lettered")
+	}
+}
+
+func TestCallbackQueue(t *testing.T) {
+	events := make(chan Callback, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cb Callback
+		json.NewDecoder(r.Body).Decode(&cb)
+		events <- cb
+	}))
+	defer srv.Close()
+
+	d := NewCallbackDispatcher(CallbackOptions{DeadLetter: make(deadLetters, 1)})
+	defer d.Close()
+	s := &fakeService{name: "mail", typ: Smtp}
+	var q serviceQueue
+	res, err := q.enqueue(s, func(p *Payload) (Response, error) {
+		res, err := s.Handle(*p)
+		d.notify(s, *p, EventSent, res, err)
+		return res, err
+	}, QueueOptions{DisableJournal: true}, d, Payload{To: "jane", CallbackURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.started().Close()
+	seen := map[CallbackEvent]Callback{}
+	for len(seen) < 2 {
+		select {
+		case cb := <-events:
+			seen[cb.Event] = cb
+		case <-time.After(5 * time.Second):
+			t.Fatalf("want queued and sent callbacks, have %+v", seen)
+		}
+	}
+	if cb := seen[EventQueued]; cb.JobID != res || cb.PayloadID == "" {
+		t.Fatalf("want queued callback of the job, have %+v", cb)
+	}
+	if cb := seen[EventSent]; cb.Response != "mail" || cb.SentAt.IsZero() {
+		t.Fatalf("want sent callback with response, have %+v", cb)
+	}
+}
+
+func TestCallbackFallback(t *testing.T) {
+	events := make(chan Callback, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cb Callback
+		json.NewDecoder(r.Body).Decode(&cb)
+		events <- cb
+	}))
+	defer srv.Close()
+	d := NewCallbackDispatcher(CallbackOptions{Backoff: func(int) time.Duration { return time.Millisecond }})
+	defer d.Close()
+
+	primary, backup := smtptest.NewServer(), smtptest.NewServer()
+	defer primary.Close()
+	defer backup.Close()
+	primary.Recipient = func(string) *smtptest.Reply {
+		return &smtptest.Reply{Code: 550, Message: "5.7.1 Relaying denied"}
+	}
+	var services []*SMTP
+	for _, mail := range []*smtptest.Server{primary, backup} {
+		s, err := NewSMTP(smtp.Config{Host: mail.Host, Port: mail.Port, Encryption: "none", FromAddress: "noreply@example.com"}, nil, "mail")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Callbacks = d
+		services = append(services, s)
+	}
+	services[0].SetService(services[1])
+	payload := Payload{ID: "p1", To: "jane@example.com", Subject: "Hi", Message: "Hello", CallbackURL: srv.URL}
+	if _, err := services[0].Handle(payload); err != nil {
+		t.Fatal(err)
+	}
+	if cb := <-events; cb.Event != EventSent {
+		t.Fatalf("want sent callback only, have %+v", cb)
+	}
+
+	backup.Recipient = primary.Recipient
+	if _, err := services[0].Handle(payload); err == nil {
+		t.Fatal("want failure of both services")
+	}
+	if cb := <-events; cb.Event != EventFailed {
+		t.Fatalf("want failed callback, have %+v", cb)
+	}
+	select {
+	case cb := <-events:
+		t.Fatalf("want one callback per payload, have %+v", cb)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if s := truncate(strings.Repeat("a", 1023) + "é"); s != strings.Repeat("a", 1023) {
+		t.Fatalf("want truncation before the split rune, have %d bytes", len(s))
+	}
+}
@@ -0,0 +1,183 @@
+// Command smppcap pretty-prints and replays SMPP capture files
+// written by capture.FileWriter.
+//
+// Usage:
+//
+//	smppcap print [-json] [-dir in|out] [-conn id] file
+//	smppcap replay -addr host:port [-tls] [-realtime] [-conn id] file
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/protocol/smpp"
+	"github.com/oarkflow/protocol/smpp/capture"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "print":
+		err = printCmd(os.Args[2:])
+	case "replay":
+		err = replayCmd(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "smppcap:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: smppcap print [-json] [-dir in|out] [-conn id] file")
+	fmt.Fprintln(os.Stderr, "       smppcap replay -addr host:port [-tls] [-realtime] [-conn id] file")
+	os.Exit(2)
+}
+
+func printCmd(args []string) error {
+	fs := flag.NewFlagSet("print", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print one JSON object per PDU")
+	dir := fs.String("dir", "", "only print PDUs in the given direction (in, out)")
+	conn := fs.String("conn", "", "only print PDUs of the given connection ID")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+	opt, err := options(*dir, *conn)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := capture.NewReader(f)
+	enc := json.NewEncoder(os.Stdout)
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if (opt.Direction != 0 && rec.Direction != opt.Direction) || (opt.ConnID != "" && rec.ConnID != opt.ConnID) {
+			continue
+		}
+		e := capture.Decode(rec)
+		if *asJSON {
+			if err = enc.Encode(e); err != nil {
+				return err
+			}
+			continue
+		}
+		pretty(os.Stdout, e)
+	}
+}
+
+func pretty(w io.Writer, e capture.Entry) {
+	arrow := "<-"
+	if e.Direction == capture.Outbound {
+		arrow = "->"
+	}
+	fmt.Fprintf(w, "%s %s %s %s seq=%d status=%q\n",
+		e.Time.Format(time.RFC3339Nano), e.ConnID, arrow, e.Command, e.Seq, e.Status)
+	if e.Error != "" {
+		fmt.Fprintf(w, "    error: %s\n    raw: %s\n", e.Error, e.Raw)
+		return
+	}
+	for _, k := range sortedKeys(e.Fields) {
+		fmt.Fprintf(w, "    %-24s %q\n", k, e.Fields[k])
+	}
+	tlvs := make(map[string]string, len(e.TLVs))
+	for k, v := range e.TLVs {
+		tlvs[k] = fmt.Sprint(v)
+	}
+	for _, k := range sortedKeys(tlvs) {
+		fmt.Fprintf(w, "    tlv %-20s %s\n", k, tlvs[k])
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func replayCmd(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:2775", "SMSC or simulator address")
+	useTLS := fs.Bool("tls", false, "connect using TLS")
+	realtime := fs.Bool("realtime", false, "preserve the original delay between PDUs")
+	conn := fs.String("conn", "", "only replay PDUs of the given connection ID")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var tc *tls.Config
+	if *useTLS {
+		tc = &tls.Config{InsecureSkipVerify: true}
+	}
+	c, err := smpp.Dial(*addr, tc)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			p, err := c.Read()
+			if err != nil {
+				return
+			}
+			fmt.Printf("<- %s seq=%d status=%q\n", p.Header().ID, p.Header().Seq, p.Header().Status.Error())
+		}
+	}()
+	// Only outbound PDUs are replayed, the simulator produces the responses.
+	err = capture.ReplayTo(f, c, capture.Options{
+		Direction: capture.Outbound,
+		ConnID:    *conn,
+		Realtime:  *realtime,
+	})
+	if err != nil {
+		return err
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+	}
+	return nil
+}
+
+func options(dir, conn string) (capture.Options, error) {
+	opt := capture.Options{ConnID: conn}
+	if dir != "" {
+		if err := opt.Direction.UnmarshalText([]byte(strings.ToLower(dir))); err != nil {
+			return opt, err
+		}
+	}
+	return opt, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oarkflow/protocol/smpp"
+)
+
+func benchCmd(args []string) error {
+	var o options
+	fs := newFlagSet("bench", &o)
+	from := fs.String("from", "", "source address")
+	to := fs.String("to", "", "destination address")
+	text := fs.String("text", "smppctl benchmark", "message text")
+	n := fs.Int("n", 1000, "number of messages")
+	c := fs.Int("c", 10, "number of concurrent senders")
+	fs.Parse(args)
+	if *to == "" || *n <= 0 || *c <= 0 {
+		fs.Usage()
+		return fmt.Errorf("-to is required, -n and -c must be positive")
+	}
+	s, err := open(&o, nil)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	send := func() error {
+		_, err := s.manager.Send(smpp.Message{From: *from, To: *to, Message: *text})
+		return err
+	}
+
+	var (
+		next    atomic.Int64
+		failed  atomic.Int64
+		mu      sync.Mutex
+		latency = make([]time.Duration, 0, *n)
+		wg      sync.WaitGroup
+	)
+	start := time.Now()
+	for i := 0; i < *c; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for next.Add(1) <= int64(*n) {
+				t := time.Now()
+				if err := send(); err != nil {
+					failed.Add(1)
+					continue
+				}
+				d := time.Since(t)
+				mu.Lock()
+				latency = append(latency, d)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	sort.Slice(latency, func(i, j int) bool { return latency[i] < latency[j] })
+	pct := func(p float64) string {
+		if len(latency) == 0 {
+			return "0s"
+		}
+		return latency[int(float64(len(latency)-1)*p)].String()
+	}
+	printJSON(map[string]any{
+		"bind":        s.setting.BindMode,
+		"sent":        len(latency),
+		"failed":      failed.Load(),
+		"concurrency": *c,
+		"elapsed":     elapsed.String(),
+		"rate":        float64(len(latency)) / elapsed.Seconds(),
+		"latency_p50": pct(0.50),
+		"latency_p90": pct(0.90),
+		"latency_p99": pct(0.99),
+	})
+	return nil
+}
@@ -0,0 +1,82 @@
+// Command smppctl binds to an SMSC and sends, queries and cancels
+// short messages, tails incoming deliver_sm PDUs and receipts, and
+// runs throughput benchmarks.
+//
+// Usage:
+//
+//	smppctl send   [common flags] -from src -to dst[,dst...] -text msg [-coding name] [-tlv name=value] [-multi] [-at time] [-valid-for duration]
+//	smppctl query  [common flags] -from src -id message_id
+//	smppctl cancel [common flags] -from src [-to dst] -id message_id
+//	smppctl tail   [common flags]
+//	smppctl bench  [common flags] -from src -to dst -text msg [-n count] [-c concurrency]
+//
+// Common flags are -config, -addr, -user, -password, -system-type,
+// -bind (trx, tx, rx, split), -conns, -throttle, -country and -tls.
+// The config file is the JSON encoding of smpp.Setting with an
+// optional "bind" key; flags override the values read from it.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	args := os.Args[2:]
+	switch os.Args[1] {
+	case "send":
+		err = sendCmd(args)
+	case "query":
+		err = queryCmd(args)
+	case "cancel":
+		err = cancelCmd(args)
+	case "tail":
+		err = tailCmd(args)
+	case "bench":
+		err = benchCmd(args)
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "smppctl:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: smppctl send   [common flags] -from src -to dst[,dst...] -text msg [-coding name] [-tlv name=value] [-multi] [-at time] [-valid-for duration]")
+	fmt.Fprintln(os.Stderr, "       smppctl query  [common flags] -from src -id message_id")
+	fmt.Fprintln(os.Stderr, "       smppctl cancel [common flags] -from src [-to dst] -id message_id")
+	fmt.Fprintln(os.Stderr, "       smppctl tail   [common flags]")
+	fmt.Fprintln(os.Stderr, "       smppctl bench  [common flags] -from src -to dst -text msg [-n count] [-c concurrency]")
+	fmt.Fprintln(os.Stderr, "run 'smppctl <command> -h' for the flags of a command")
+	os.Exit(2)
+}
+
+// printJSON writes v to stdout as a single line of JSON.
+func printJSON(v any) {
+	if err := json.NewEncoder(os.Stdout).Encode(v); err != nil {
+		fmt.Fprintln(os.Stderr, "smppctl:", err)
+	}
+}
+
+// waitSignal blocks until SIGINT or SIGTERM is received.
+func waitSignal() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	<-sigs
+}
+
+func newFlagSet(name string, o *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	o.register(fs)
+	return fs
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/protocol/smpp"
+	"github.com/oarkflow/protocol/smpp/number"
+	"github.com/oarkflow/protocol/smpp/pdu/pdutext"
+	"github.com/oarkflow/protocol/smpp/pdu/pdutlv"
+)
+
+// tlvFlag collects repeated -tlv name=value flags.
+type tlvFlag pdutlv.Fields
+
+func (f *tlvFlag) String() string {
+	return fmt.Sprint(pdutlv.Fields(*f))
+}
+
+// Set parses name=value, where name is a registered TLV name such as
+// "user_message_reference" or a tag in hex such as "0x1401". Integer
+// values are encoded according to the registry, values prefixed with
+// "hex:" are sent as the decoded octets.
+func (f *tlvFlag) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("want name=value, have %q", s)
+	}
+	var tag pdutlv.Tag
+	def, ok := pdutlv.LookupName(name)
+	if ok {
+		tag = def.Tag
+	} else {
+		n, err := strconv.ParseUint(name, 0, 16)
+		if err != nil {
+			return fmt.Errorf("unknown TLV: %q", name)
+		}
+		tag = pdutlv.Tag(n)
+		def, ok = pdutlv.Lookup(tag)
+	}
+	if *f == nil {
+		*f = make(tlvFlag)
+	}
+	if strings.HasPrefix(value, "hex:") {
+		b, err := hex.DecodeString(value[4:])
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		(*f)[tag] = b
+		return nil
+	}
+	if !ok {
+		(*f)[tag] = []byte(value)
+		return nil
+	}
+	var v any = value
+	switch def.Kind {
+	case pdutlv.KindUint8, pdutlv.KindUint16, pdutlv.KindUint32:
+		n, err := strconv.ParseUint(value, 0, 32)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		v = uint32(n)
+	}
+	b, err := def.Encode(v)
+	if err != nil {
+		return err
+	}
+	(*f)[tag] = b
+	return nil
+}
+
+// address normalizes addr for the submits done without the manager,
+// see number.Parse.
+func (s *session) address(addr string) (string, uint8, uint8, error) {
+	if addr == "" {
+		return "", 0, 0, nil
+	}
+	n, err := number.Parse(addr, s.setting.DefaultCountry)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	return n.Address(), n.TON, n.NPI, nil
+}
+
+func sendCmd(args []string) error {
+	var o options
+	var tlvs tlvFlag
+	fs := newFlagSet("send", &o)
+	from := fs.String("from", "", "source address")
+	to := fs.String("to", "", "destination address, comma separated for several")
+	text := fs.String("text", "", "message text")
+	coding := fs.String("coding", "", "data coding: gsm7, latin1, iso88595, ucs2 or binary (default detected)")
+	multi := fs.Bool("multi", false, "send to all destinations with a single submit_multi")
+	at := fs.String("at", "", "delivery time in RFC 3339 format, immediate if empty")
+	validFor := fs.Duration("valid-for", 0, "validity period from now, the setting's validity if zero")
+	fs.Var(&tlvs, "tlv", "optional parameter as name=value, repeatable")
+	fs.Parse(args)
+	if *to == "" || *text == "" {
+		fs.Usage()
+		return fmt.Errorf("-to and -text are required")
+	}
+	var scheduleAt, validUntil time.Time
+	if *at != "" {
+		t, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			return fmt.Errorf("-at: %w", err)
+		}
+		scheduleAt = t
+	}
+	if *validFor > 0 {
+		validUntil = time.Now().Add(*validFor)
+	}
+	var dsts []string
+	for _, d := range strings.Split(*to, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			dsts = append(dsts, d)
+		}
+	}
+	s, err := open(&o, nil)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if !*multi {
+		for _, dst := range dsts {
+			sms, err := s.manager.Send(smpp.Message{
+				From:       *from,
+				To:         dst,
+				Message:    *text,
+				Coding:     *coding,
+				TLVs:       pdutlv.Fields(tlvs),
+				ScheduleAt: scheduleAt,
+				ValidUntil: validUntil,
+			})
+			if err != nil {
+				return err
+			}
+			printJSON(sms)
+		}
+		return nil
+	}
+
+	// The manager sends to a single destination, submit_multi is
+	// done on one of its binds directly.
+	t, err := s.transmitter()
+	if err != nil {
+		return err
+	}
+	codec, long := pdutext.FindCoding([]byte(*text))
+	if *coding != "" {
+		c, err := pdutext.ParseCoding(*coding)
+		if err != nil {
+			return err
+		}
+		codec, long = c.Encode([]byte(*text))
+	}
+	if long {
+		return fmt.Errorf("long messages cannot be sent with submit_multi")
+	}
+	src, srcTON, srcNPI, err := s.address(*from)
+	if err != nil {
+		return err
+	}
+	tons, npis := make([]uint8, len(dsts)), make([]uint8, len(dsts))
+	for i, dst := range dsts {
+		if dsts[i], tons[i], npis[i], err = s.address(dst); err != nil {
+			return err
+		}
+	}
+	sm := &smpp.ShortMessage{
+		Src:           src,
+		DstList:       dsts,
+		DstListTON:    tons,
+		DstListNPI:    npis,
+		Text:          codec,
+		TLVFields:     pdutlv.Fields(tlvs),
+		SourceAddrTON: srcTON,
+		SourceAddrNPI: srcNPI,
+		Register:      s.setting.Register,
+		Validity:      s.setting.Validity,
+	}
+	if _, err = t.Submit(sm); err != nil {
+		return err
+	}
+	printJSON(result(sm, strings.Join(dsts, ",")))
+	return nil
+}
+
+type submitResult struct {
+	To        string `json:"to"`
+	MessageID string `json:"message_id"`
+	Status    string `json:"status"`
+}
+
+func result(sm *smpp.ShortMessage, to string) submitResult {
+	r := submitResult{To: to, MessageID: sm.RespID(), Status: "SENT"}
+	if p := sm.Resp(); p != nil && p.Header().Status != 0 {
+		r.Status = p.Header().Status.Error()
+	}
+	return r
+}
+
+func queryCmd(args []string) error {
+	var o options
+	fs := newFlagSet("query", &o)
+	from := fs.String("from", "", "source address of the submitted message")
+	id := fs.String("id", "", "message ID returned by the SMSC")
+	fs.Parse(args)
+	if *id == "" {
+		fs.Usage()
+		return fmt.Errorf("-id is required")
+	}
+	s, err := open(&o, nil)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	t, err := s.transmitter()
+	if err != nil {
+		return err
+	}
+	src, ton, npi, err := s.address(*from)
+	if err != nil {
+		return err
+	}
+	resp, err := t.QuerySM(src, *id, ton, npi)
+	if err != nil {
+		return err
+	}
+	printJSON(map[string]any{
+		"message_id":    resp.MsgID,
+		"message_state": resp.MsgState,
+		"final_date":    resp.FinalDate,
+		"error_code":    resp.ErrCode,
+	})
+	return nil
+}
+
+func cancelCmd(args []string) error {
+	var o options
+	fs := newFlagSet("cancel", &o)
+	from := fs.String("from", "", "source address of the submitted message")
+	to := fs.String("to", "", "destination address of the submitted message, optional")
+	id := fs.String("id", "", "message ID returned by the SMSC")
+	fs.Parse(args)
+	if *id == "" {
+		fs.Usage()
+		return fmt.Errorf("-id is required")
+	}
+	s, err := open(&o, nil)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	t, err := s.transmitter()
+	if err != nil {
+		return err
+	}
+	src, ton, npi, err := s.address(*from)
+	if err != nil {
+		return err
+	}
+	dst, _, _, err := s.address(*to)
+	if err != nil {
+		return err
+	}
+	if err = t.CancelSM(src, dst, *id, ton, npi); err != nil {
+		return err
+	}
+	printJSON(map[string]string{"message_id": *id, "status": "CANCELLED"})
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/oarkflow/protocol/smpp"
+	"github.com/oarkflow/protocol/smpp/pdu"
+)
+
+// options are the flags shared by all commands.
+type options struct {
+	config     string
+	addr       string
+	user       string
+	password   string
+	systemType string
+	bind       string
+	conns      int
+	throttle   int
+	tls        bool
+	country    string
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.config, "config", "", "JSON file holding an smpp.Setting and an optional \"bind\" key")
+	fs.StringVar(&o.addr, "addr", "", "SMSC address (default localhost:2775)")
+	fs.StringVar(&o.user, "user", "", "system_id used to bind")
+	fs.StringVar(&o.password, "password", "", "password used to bind")
+	fs.StringVar(&o.systemType, "system-type", "", "system_type used to bind")
+	fs.StringVar(&o.bind, "bind", "", "bind mode: trx, tx, rx or split (default trx)")
+	fs.IntVar(&o.conns, "conns", 0, "number of transceiver or transmitter connections")
+	fs.IntVar(&o.throttle, "throttle", 0, "maximum submits per second and connection")
+	fs.StringVar(&o.country, "country", "", "ISO 3166-1 alpha-2 code of the country of national numbers")
+	fs.BoolVar(&o.tls, "tls", false, "connect using TLS, see the \"tls\" key of the config file for certificates")
+}
+
+// config is the layout of the -config file. Bind takes precedence
+// over the bind_mode of the setting.
+type config struct {
+	smpp.Setting
+	Bind string `json:"bind,omitempty"`
+}
+
+var bindModes = map[string]smpp.BindMode{
+	"trx":   smpp.BindTransceiver,
+	"tx":    smpp.BindTransmitter,
+	"rx":    smpp.BindReceiver,
+	"split": smpp.BindSplit,
+}
+
+// setting merges the config file and the flags.
+func (o *options) setting() (smpp.Setting, error) {
+	var c config
+	if o.config != "" {
+		b, err := os.ReadFile(o.config)
+		if err != nil {
+			return c.Setting, err
+		}
+		if err = json.Unmarshal(b, &c); err != nil {
+			return c.Setting, fmt.Errorf("%s: %w", o.config, err)
+		}
+	}
+	s := c.Setting
+	if o.addr != "" {
+		s.URL = o.addr
+	}
+	if s.URL == "" {
+		s.URL = "localhost:2775"
+	}
+	if o.user != "" {
+		s.Auth.SystemID = o.user
+	}
+	if o.password != "" {
+		s.Auth.Password = o.password
+	}
+	if o.systemType != "" {
+		s.Auth.SystemType = o.systemType
+	}
+	if o.conns > 0 {
+		s.MaxConnection = o.conns
+	}
+	if s.MaxConnection > 1 {
+		s.UseAllConnection = true
+	}
+	if o.throttle > 0 {
+		s.Throttle = o.throttle
+	}
+	if o.country != "" {
+		s.DefaultCountry = o.country
+	}
+	if o.tls && s.TLS == nil {
+		s.TLS = &smpp.TLSSetting{}
+	}
+	bind := c.Bind
+	if o.bind != "" {
+		bind = o.bind
+	}
+	if bind != "" {
+		mode, ok := bindModes[strings.ToLower(bind)]
+		if !ok {
+			return s, fmt.Errorf("unknown bind mode: %q", bind)
+		}
+		s.BindMode = mode
+	}
+	return s, nil
+}
+
+// session is a Manager bound according to the options.
+type session struct {
+	setting smpp.Setting
+	manager *smpp.Manager
+}
+
+// open binds according to the options. h, when not nil, is called
+// for every PDU sent by the SMSC that is not a response, i.e.
+// deliver_sm, before the manager updates the state of the messages.
+func open(o *options, h smpp.HandlerFunc) (*session, error) {
+	setting, err := o.setting()
+	if err != nil {
+		return nil, err
+	}
+	s := &session{setting: setting}
+	setting.HandlePDU = func(p pdu.Body) {
+		if h != nil {
+			h(p)
+		}
+		s.manager.DefaultPDUHandler(p)
+	}
+	if s.manager, err = smpp.NewManager(setting); err == nil {
+		err = s.manager.Start()
+	}
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// transmitter returns one of the binds able to submit.
+func (s *session) transmitter() (smpp.Connection, error) {
+	if s.setting.BindMode == smpp.BindReceiver {
+		return nil, fmt.Errorf("cannot transmit with a receiver-only bind")
+	}
+	c, err := s.manager.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+	return c.(smpp.Connection), nil
+}
+
+// Close unbinds and releases the capture file, if any, see
+// smpp.Manager.Close.
+func (s *session) Close() error {
+	if s.manager == nil {
+		return nil
+	}
+	return s.manager.Close()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/oarkflow/protocol/smpp"
+	"github.com/oarkflow/protocol/smpp/pdu"
+	"github.com/oarkflow/protocol/smpp/pdu/pdufield"
+	"github.com/oarkflow/protocol/smpp/pdu/pdutext"
+)
+
+// event is the JSON form of an incoming PDU printed by tail.
+type event struct {
+	Time        time.Time         `json:"time"`
+	Command     string            `json:"command"`
+	Seq         uint32            `json:"seq"`
+	Source      string            `json:"source,omitempty"`
+	Destination string            `json:"destination,omitempty"`
+	ESMClass    uint8             `json:"esm_class"`
+	Coding      string            `json:"coding"`
+	Text        string            `json:"text,omitempty"`
+	Receipt     map[string]string `json:"receipt,omitempty"`
+	TLVs        map[string]any    `json:"tlvs,omitempty"`
+}
+
+func tailCmd(args []string) error {
+	var o options
+	fs := newFlagSet("tail", &o)
+	fs.Parse(args)
+	s, err := open(&o, func(p pdu.Body) {
+		printJSON(newEvent(p))
+	})
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	waitSignal()
+	return nil
+}
+
+func newEvent(p pdu.Body) event {
+	f := p.Fields()
+	e := event{
+		Time:    time.Now(),
+		Command: p.Header().ID.String(),
+		Seq:     p.Header().Seq,
+	}
+	if v := f[pdufield.SourceAddr]; v != nil {
+		e.Source = v.String()
+	}
+	if v := f[pdufield.DestinationAddr]; v != nil {
+		e.Destination = v.String()
+	}
+	if v := f[pdufield.ESMClass]; v != nil {
+		e.ESMClass = v.Bytes()[0]
+	}
+	var coding pdutext.DataCoding
+	if v := f[pdufield.DataCoding]; v != nil {
+		coding = pdutext.DataCoding(v.Bytes()[0])
+	}
+	e.Coding = coding.String()
+	if v := f[pdufield.ShortMessage]; v != nil {
+		e.Text = string(coding.Decode(v.Bytes()))
+	}
+	// Bit 2 of esm_class flags an SMSC delivery receipt.
+	if e.ESMClass&0x04 != 0 {
+		e.Receipt = smpp.Unmarshal(e.Text)
+	}
+	if len(p.TLVFields()) > 0 {
+		e.TLVs = make(map[string]any, len(p.TLVFields()))
+		for t := range p.TLVFields() {
+			v, err := p.TLVFields().Get(t)
+			if err != nil {
+				v = hex.EncodeToString(p.TLVFields()[t].Bytes())
+			}
+			e.TLVs[t.Name()] = v
+		}
+	}
+	return e
+}
@@ -0,0 +1,250 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/render"
+
+	"github.com/oarkflow/protocol"
+	"github.com/oarkflow/protocol/http"
+	"github.com/oarkflow/protocol/i18n"
+	"github.com/oarkflow/protocol/smpp"
+	"github.com/oarkflow/protocol/smpp/balancer"
+	"github.com/oarkflow/protocol/smtp"
+)
+
+// Options completes the services built from a document with what
+// cannot be configured in it.
+type Options struct {
+	HtmlEngine *render.HtmlEngine // Template engine of the SMTP services.
+	// SMPP, if set, is called with the setting of each SMPP service
+	// before it is created, e.g. to set its report handlers.
+	SMPP func(name string, setting *smpp.Setting)
+	// SkipSetup leaves the services to be set up by the caller,
+	// otherwise Build fails if a service cannot be set up, e.g. an
+	// SMPP service cannot bind.
+	SkipSetup bool
+}
+
+// Build creates the services of d and returns them in a registry with
+// the defaults, rules and fallback chains of d. If it fails, what was
+// already built is closed, e.g. the binds of the SMPP services.
+func Build(d *Document, opts Options) (_ *protocol.Registry, err error) {
+	if err = d.Validate(); err != nil {
+		return nil, err
+	}
+	r := protocol.NewRegistry()
+	var callbacks *protocol.CallbackDispatcher
+	var built []protocol.Service
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, s := range built {
+			if c, ok := s.(io.Closer); ok {
+				c.Close()
+			}
+		}
+		if r.Idempotency != nil {
+			if c, ok := r.Idempotency.Store.(io.Closer); ok {
+				c.Close()
+			}
+		}
+		if callbacks != nil {
+			callbacks.Close()
+		}
+	}()
+	if c := d.Callbacks; c != nil {
+		callbacks = protocol.NewCallbackDispatcher(protocol.CallbackOptions{
+			Secret:         c.Secret,
+			MaxAttempts:    c.MaxAttempts,
+			DeadLetterPath: c.DeadLetterPath,
+		})
+	}
+	if i := d.Idempotency; i != nil {
+		r.Idempotency = &protocol.Idempotency{TTL: time.Duration(i.TTL), CacheErrors: i.CacheErrors}
+		if i.Path == "" {
+			r.Idempotency.Store = protocol.NewMemoryIdempotencyStore()
+		} else {
+			store, err := protocol.OpenFileIdempotencyStore(i.Path)
+			if err != nil {
+				return nil, fmt.Errorf("idempotency: %w", err)
+			}
+			r.Idempotency.Store = store
+		}
+	}
+	var catalog *i18n.Catalog
+	if t := d.Templates; t != nil {
+		catalog = i18n.NewCatalog(t.Default)
+		if err := catalog.Load(t.Path); err != nil {
+			return nil, fmt.Errorf("templates: %w", err)
+		}
+		for locale, fallbacks := range t.Fallbacks {
+			catalog.SetFallback(locale, fallbacks...)
+		}
+	}
+	for _, s := range d.Services {
+		service, err := build(s, opts, callbacks, catalog)
+		if err != nil {
+			return nil, fmt.Errorf("service %q: %w", s.Name, err)
+		}
+		built = append(built, service)
+		if !opts.SkipSetup {
+			if err = service.Setup(); err != nil {
+				return nil, fmt.Errorf("service %q: setup: %w", s.Name, err)
+			}
+		}
+		if err = r.Register(s.Name, service); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range d.Services {
+		if len(s.Fallback) > 0 {
+			if err := r.Chain(s.Name, s.Fallback...); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for t, name := range d.Defaults {
+		if err := r.SetDefault(t, name); err != nil {
+			return nil, err
+		}
+	}
+	for _, rule := range d.Rules {
+		r.AddRule(protocol.Rule{Name: rule.Name, Type: rule.Type, ServiceType: rule.ServiceType, Service: rule.Service})
+	}
+	return r, nil
+}
+
+// LoadRegistry reads, validates and builds the document at path.
+func LoadRegistry(path string, opts Options) (*protocol.Registry, error) {
+	d, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return Build(d, opts)
+}
+
+func build(s Service, opts Options, callbacks *protocol.CallbackDispatcher, catalog *i18n.Catalog) (protocol.Service, error) {
+	var queue protocol.QueueOptions
+	if q := s.Queue; q != nil {
+		queue = protocol.QueueOptions{
+			Name:           q.Name,
+			Concurrency:    q.Concurrency,
+			Size:           q.Size,
+			Retention:      time.Duration(q.Retention),
+			JournalPath:    q.JournalPath,
+			DisableJournal: q.DisableJournal,
+		}
+	}
+	switch s.Type {
+	case protocol.Smtp:
+		providers := s.Providers
+		if s.SMTP != nil {
+			providers = []smtp.Provider{{Config: *s.SMTP}}
+		}
+		service, err := protocol.NewSMTPProviders(providers, opts.HtmlEngine, s.ServiceType)
+		if err != nil {
+			return nil, err
+		}
+		service.QueueOptions, service.Callbacks, service.Catalog = queue, callbacks, catalog
+		return service, nil
+	case protocol.Http:
+		service, err := protocol.NewHTTP(s.HTTP.options(), s.ServiceType)
+		if err != nil {
+			return nil, err
+		}
+		service.QueueOptions, service.Callbacks, service.Catalog = queue, callbacks, catalog
+		return service, nil
+	case protocol.Smpp:
+		setting, err := s.SMPP.setting(s.Name)
+		if err != nil {
+			return nil, err
+		}
+		if opts.SMPP != nil {
+			opts.SMPP(s.Name, &setting)
+		}
+		service, err := protocol.NewSMPP(setting, s.ServiceType)
+		if err != nil {
+			return nil, err
+		}
+		service.QueueOptions, service.Callbacks, service.Catalog = queue, callbacks, catalog
+		return service, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", s.Type)
+}
+
+func (h *HTTP) options() *http.Options {
+	headers := make(map[string]string, len(h.Headers)+1)
+	for k, v := range h.Headers {
+		headers[k] = v
+	}
+	if a := h.Auth; a != nil {
+		switch a.Type {
+		case "basic":
+			headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(a.Username+":"+a.Password))
+		case "bearer":
+			headers["Authorization"] = "Bearer " + a.Token
+		}
+	}
+	return &http.Options{
+		URL:          h.URL,
+		Method:       strings.ToUpper(h.Method),
+		Headers:      headers,
+		Timeout:      time.Duration(h.Timeout),
+		RetryMax:     h.RetryMax,
+		RetryWaitMin: time.Duration(h.RetryWaitMin),
+		RetryWaitMax: time.Duration(h.RetryWaitMax),
+		MaxPoolSize:  h.MaxPoolSize,
+		ReqPerSec:    h.ReqPerSec,
+	}
+}
+
+func (c *SMPP) setting(name string) (smpp.Setting, error) {
+	b, err := balancer.New(c.Balancer)
+	if err != nil {
+		return smpp.Setting{}, err
+	}
+	setting := smpp.Setting{
+		Name: name,
+		Slug: name,
+		URL:  c.URL,
+		Auth: smpp.Auth{
+			SystemID:   c.Auth.SystemID,
+			Password:   c.Auth.Password,
+			SystemType: c.Auth.SystemType,
+		},
+		ReadTimeout:          time.Duration(c.ReadTimeout),
+		WriteTimeout:         time.Duration(c.WriteTimeout),
+		EnquiryInterval:      time.Duration(c.EnquiryInterval),
+		EnquiryTimeout:       time.Duration(c.EnquiryTimeout),
+		BindMode:             smpp.BindMode(c.BindMode),
+		MaxConnection:        c.MaxConnection,
+		MaxReceiver:          c.MaxReceiver,
+		MergeInterval:        time.Duration(c.MergeInterval),
+		Balancer:             b,
+		Throttle:             c.Throttle,
+		UseAllConnection:     c.UseAllConnection,
+		AutoRebind:           c.AutoRebind,
+		Validity:             time.Duration(c.Validity),
+		Register:             registers[c.Register],
+		ServiceType:          c.ServiceType,
+		ESMClass:             c.ESMClass,
+		ProtocolID:           c.ProtocolID,
+		PriorityFlag:         c.PriorityFlag,
+		DefaultCountry:       c.DefaultCountry,
+		RelativeTime:         c.RelativeTime,
+		LocalSchedule:        c.LocalSchedule,
+		ReplaceIfPresentFlag: c.ReplaceIfPresentFlag,
+		CaptureFile:          c.CaptureFile,
+	}
+	if c.TLS != nil {
+		tls := *c.TLS
+		setting.TLS = &tls
+	}
+	return setting, nil
+}
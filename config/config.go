@@ -0,0 +1,314 @@
+// Package config loads the services of a protocol.Registry from a
+// YAML or JSON document.
+//
+// String values may reference environment variables as ${NAME}, or
+// ${NAME:-default} to use default when NAME is unset or empty. "$$"
+// is a literal "$". Unquoted values are typed after interpolation so
+// "port: ${MAIL_PORT}" is a number.
+//
+//	services:
+//	  - name: mail
+//	    type: smtp
+//	    smtp:
+//	      host: smtp.example.com
+//	      port: 587
+//	      username: ${MAIL_USERNAME}
+//	      password: ${MAIL_PASSWORD}
+//	      encryption: tls
+//	    fallback: [mail-backup]
+//	  - name: mail-backup
+//	    type: smtp
+//	    providers:
+//	      - {name: primary, weight: 3, config: {host: smtp.example.com, port: 587}}
+//	      - {name: secondary, weight: 1, config: {host: smtp.example.org, port: 587}}
+//	  - name: carrier
+//	    type: smpp
+//	    smpp:
+//	      url: smsc.example.com:2775
+//	      auth: {system_id: acme, password: "${SMPP_PASSWORD}"}
+//	      balancer: round_robin
+//	      register: final
+//	defaults:
+//	  smtp: mail
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/oarkflow/protocol"
+	"github.com/oarkflow/protocol/smpp"
+	"github.com/oarkflow/protocol/smtp"
+)
+
+// Document is a configuration of services.
+type Document struct {
+	Services    []Service                `json:"services" yaml:"services"`
+	Defaults    map[protocol.Type]string `json:"defaults,omitempty" yaml:"defaults,omitempty"` // Default service name of each type.
+	Rules       []Rule                   `json:"rules,omitempty" yaml:"rules,omitempty"`
+	Callbacks   *Callbacks               `json:"callbacks,omitempty" yaml:"callbacks,omitempty"`
+	Idempotency *Idempotency             `json:"idempotency,omitempty" yaml:"idempotency,omitempty"`
+	Templates   *Templates               `json:"templates,omitempty" yaml:"templates,omitempty"`
+}
+
+// Service is a named service, configured by the section of its type.
+type Service struct {
+	Name        string        `json:"name" yaml:"name"`
+	Type        protocol.Type `json:"type" yaml:"type"`
+	ServiceType string        `json:"service_type,omitempty" yaml:"service_type,omitempty"`
+	SMTP        *smtp.Config  `json:"smtp,omitempty" yaml:"smtp,omitempty"`
+	// Providers are the mail servers of an smtp service sending over
+	// several, instead of its smtp section.
+	Providers []smtp.Provider `json:"providers,omitempty" yaml:"providers,omitempty"`
+	HTTP      *HTTP           `json:"http,omitempty" yaml:"http,omitempty"`
+	SMPP      *SMPP           `json:"smpp,omitempty" yaml:"smpp,omitempty"`
+	Queue     *Queue          `json:"queue,omitempty" yaml:"queue,omitempty"`
+	Fallback  []string        `json:"fallback,omitempty" yaml:"fallback,omitempty"` // Services tried in order when this one fails.
+}
+
+// HTTP configures a protocol.HTTP service.
+type HTTP struct {
+	URL          string            `json:"url" yaml:"url"`
+	Method       string            `json:"method,omitempty" yaml:"method,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Auth         *HTTPAuth         `json:"auth,omitempty" yaml:"auth,omitempty"`
+	Timeout      Duration          `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	RetryMax     int               `json:"retry_max,omitempty" yaml:"retry_max,omitempty"`
+	RetryWaitMin Duration          `json:"retry_wait_min,omitempty" yaml:"retry_wait_min,omitempty"`
+	RetryWaitMax Duration          `json:"retry_wait_max,omitempty" yaml:"retry_wait_max,omitempty"`
+	MaxPoolSize  int               `json:"max_pool_size,omitempty" yaml:"max_pool_size,omitempty"`
+	ReqPerSec    int               `json:"req_per_sec,omitempty" yaml:"req_per_sec,omitempty"`
+}
+
+// HTTPAuth sets the Authorization header of an HTTP service.
+type HTTPAuth struct {
+	Type     string `json:"type" yaml:"type"` // "basic" or "bearer".
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	Token    string `json:"token,omitempty" yaml:"token,omitempty"`
+}
+
+// SMPP configures a protocol.SMPP service, see smpp.Setting.
+type SMPP struct {
+	URL                  string           `json:"url" yaml:"url"`
+	Auth                 SMPPAuth         `json:"auth" yaml:"auth"`
+	ReadTimeout          Duration         `json:"read_timeout,omitempty" yaml:"read_timeout,omitempty"`
+	WriteTimeout         Duration         `json:"write_timeout,omitempty" yaml:"write_timeout,omitempty"`
+	EnquiryInterval      Duration         `json:"enquiry_interval,omitempty" yaml:"enquiry_interval,omitempty"`
+	EnquiryTimeout       Duration         `json:"enquiry_timeout,omitempty" yaml:"enquiry_timeout,omitempty"`
+	BindMode             string           `json:"bind_mode,omitempty" yaml:"bind_mode,omitempty"`
+	MaxConnection        int              `json:"max_connection,omitempty" yaml:"max_connection,omitempty"`
+	MaxReceiver          int              `json:"max_receiver,omitempty" yaml:"max_receiver,omitempty"`
+	MergeInterval        Duration         `json:"merge_interval,omitempty" yaml:"merge_interval,omitempty"`
+	Balancer             string           `json:"balancer,omitempty" yaml:"balancer,omitempty"` // Name of a registered balancer, see balancer.Names.
+	Throttle             int              `json:"throttle,omitempty" yaml:"throttle,omitempty"`
+	UseAllConnection     bool             `json:"use_all_connection,omitempty" yaml:"use_all_connection,omitempty"`
+	AutoRebind           bool             `json:"auto_rebind,omitempty" yaml:"auto_rebind,omitempty"`
+	Validity             Duration         `json:"validity,omitempty" yaml:"validity,omitempty"`
+	Register             string           `json:"register,omitempty" yaml:"register,omitempty"` // Delivery receipts: "none", "final" or "failure".
+	ServiceType          string           `json:"service_type,omitempty" yaml:"service_type,omitempty"`
+	ESMClass             uint8            `json:"esm_class,omitempty" yaml:"esm_class,omitempty"`
+	ProtocolID           uint8            `json:"protocol_id,omitempty" yaml:"protocol_id,omitempty"`
+	PriorityFlag         uint8            `json:"priority_flag,omitempty" yaml:"priority_flag,omitempty"`
+	DefaultCountry       string           `json:"default_country,omitempty" yaml:"default_country,omitempty"`
+	RelativeTime         bool             `json:"relative_time,omitempty" yaml:"relative_time,omitempty"`
+	LocalSchedule        bool             `json:"local_schedule,omitempty" yaml:"local_schedule,omitempty"`
+	ReplaceIfPresentFlag uint8            `json:"replace_if_present_flag,omitempty" yaml:"replace_if_present_flag,omitempty"`
+	TLS                  *smpp.TLSSetting `json:"tls,omitempty" yaml:"tls,omitempty"`
+	CaptureFile          string           `json:"capture_file,omitempty" yaml:"capture_file,omitempty"`
+}
+
+type SMPPAuth struct {
+	SystemID   string `json:"system_id" yaml:"system_id"`
+	Password   string `json:"password" yaml:"password"`
+	SystemType string `json:"system_type,omitempty" yaml:"system_type,omitempty"`
+}
+
+// Queue configures the worker pool of a service, see
+// protocol.QueueOptions.
+type Queue struct {
+	Name           string   `json:"name,omitempty" yaml:"name,omitempty"`
+	Concurrency    int      `json:"concurrency,omitempty" yaml:"concurrency,omitempty"`
+	Size           int      `json:"size,omitempty" yaml:"size,omitempty"`
+	Retention      Duration `json:"retention,omitempty" yaml:"retention,omitempty"`
+	JournalPath    string   `json:"journal_path,omitempty" yaml:"journal_path,omitempty"`
+	DisableJournal bool     `json:"disable_journal,omitempty" yaml:"disable_journal,omitempty"`
+}
+
+// Rule routes payloads to Service, see protocol.Rule.
+type Rule struct {
+	Name        string        `json:"name,omitempty" yaml:"name,omitempty"`
+	Type        protocol.Type `json:"type,omitempty" yaml:"type,omitempty"`
+	ServiceType string        `json:"service_type,omitempty" yaml:"service_type,omitempty"`
+	Service     string        `json:"service" yaml:"service"`
+}
+
+// Callbacks configures the status callbacks shared by the services.
+type Callbacks struct {
+	Secret         string `json:"secret,omitempty" yaml:"secret,omitempty"`
+	MaxAttempts    int    `json:"max_attempts,omitempty" yaml:"max_attempts,omitempty"`
+	DeadLetterPath string `json:"dead_letter_path,omitempty" yaml:"dead_letter_path,omitempty"`
+}
+
+// Idempotency configures the duplicate suppression of the registry,
+// the IDs being kept in memory if Path is empty.
+type Idempotency struct {
+	TTL         Duration `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	Path        string   `json:"path,omitempty" yaml:"path,omitempty"`
+	CacheErrors bool     `json:"cache_errors,omitempty" yaml:"cache_errors,omitempty"`
+}
+
+// Templates configures the template catalog shared by the services,
+// loaded from the file at Path, see i18n.Catalog.
+type Templates struct {
+	Path      string              `json:"path" yaml:"path"`
+	Default   string              `json:"default,omitempty" yaml:"default,omitempty"`     // Default locale.
+	Fallbacks map[string][]string `json:"fallbacks,omitempty" yaml:"fallbacks,omitempty"` // Fallback locales by locale.
+}
+
+// Duration is a time.Duration written as a string like "1m30s", or as
+// a number of seconds.
+type Duration time.Duration
+
+func (d *Duration) parse(s string) error {
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		*d = Duration(secs * float64(time.Second))
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	return d.parse(value.Value)
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		return d.parse(s)
+	}
+	return d.parse(string(b))
+}
+
+func (d Duration) MarshalYAML() (any, error) {
+	return time.Duration(d).String(), nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Load reads the document at path, see Parse.
+func Load(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// Parse decodes a YAML or JSON document, interpolates the environment
+// variables and validates it. Unknown fields are errors.
+func Parse(data []byte) (*Document, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if err := interpolate(&root, os.LookupEnv); err != nil {
+		return nil, err
+	}
+	expanded, err := yaml.Marshal(&root)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{}
+	dec := yaml.NewDecoder(bytes.NewReader(expanded))
+	dec.KnownFields(true)
+	if err = dec.Decode(doc); err != nil {
+		return nil, err
+	}
+	if err = doc.Validate(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// interpolate expands the environment variables of the scalars of n.
+func interpolate(n *yaml.Node, lookup func(string) (string, bool)) error {
+	var missing []string
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Kind == yaml.ScalarNode && strings.Contains(n.Value, "$") {
+			var unset []string
+			n.Value, unset = Expand(n.Value, lookup)
+			missing = append(missing, unset...)
+			// Plain scalars are typed again, e.g. as numbers.
+			if n.Style == 0 {
+				n.Tag = ""
+			}
+		}
+		for _, c := range n.Content {
+			walk(c)
+		}
+	}
+	walk(n)
+	if len(missing) > 0 {
+		return fmt.Errorf("undefined environment variables: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Expand replaces the ${NAME} and ${NAME:-default} references of s
+// using lookup and returns the names without value nor default.
+func Expand(s string, lookup func(string) (string, bool)) (string, []string) {
+	var b strings.Builder
+	var missing []string
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		switch s[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+			continue
+		case '{':
+		default:
+			b.WriteByte('$')
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			b.WriteString(s[i:])
+			break
+		}
+		ref := s[i+2 : i+end]
+		name, def, hasDef := strings.Cut(ref, ":-")
+		if v, ok := lookup(name); ok && (v != "" || !hasDef) {
+			b.WriteString(v)
+		} else if hasDef {
+			b.WriteString(def)
+		} else {
+			missing = append(missing, name)
+		}
+		i += end
+	}
+	return b.String(), missing
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oarkflow/protocol"
+	"github.com/oarkflow/protocol/smpp"
+	"github.com/oarkflow/protocol/smpp/capture"
+)
+
+const document = `
+services:
+  - name: mail
+    type: smtp
+    smtp:
+      host: ${MAIL_HOST:-localhost}
+      port: ${MAIL_PORT}
+      password: "${MAIL_PASSWORD}"
+    fallback: [mail-backup]
+  - name: mail-backup
+    type: smtp
+    smtp: {host: backup.example.com, port: 25}
+  - name: hook
+    type: http
+    http:
+      url: https://example.com/hook
+      method: post
+      auth: {type: bearer, token: "$${literal}"}
+      timeout: 30s
+  - name: carrier
+    type: smpp
+    service_type: otp
+    smpp:
+      url: 127.0.0.1:2775
+      auth: {system_id: acme, password: secret}
+      balancer: random
+      register: final
+      enquiry_interval: 10
+      tls: {server_name: smsc.example, min_version: "1.3"}
+defaults:
+  smtp: mail-backup
+rules:
+  - {type: smtp, service_type: "", service: mail}
+idempotency:
+  ttl: 1h
+`
+
+func TestParse(t *testing.T) {
+	t.Setenv("MAIL_PORT", "587")
+	t.Setenv("MAIL_PASSWORD", "123456")
+	doc, err := Parse([]byte(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mail := doc.Services[0].SMTP
+	if mail.Host != "localhost" || mail.Port != 587 || mail.Password != "123456" {
+		t.Fatalf("want interpolated smtp config, have %+v", mail)
+	}
+	if token := doc.Services[2].HTTP.Auth.Token; token != "${literal}" {
+		t.Fatalf("want escaped dollar, have %q", token)
+	}
+	if d := time.Duration(doc.Services[3].SMPP.EnquiryInterval); d != 10*time.Second {
+		t.Fatalf("want seconds duration, have %v", d)
+	}
+
+	var balancerSet, tlsSet bool
+	r, err := Build(doc, Options{SkipSetup: true, SMPP: func(name string, setting *smpp.Setting) {
+		balancerSet = name == "carrier" && setting.Balancer != nil
+		tlsSet = setting.TLS != nil && setting.TLS.ServerName == "smsc.example" && setting.TLS.MinVersion == "1.3"
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !balancerSet || !tlsSet {
+		t.Fatalf("want balancer resolved and TLS set, have %v, %v", balancerSet, tlsSet)
+	}
+	if s, err := r.Route(protocol.Payload{Type: protocol.Smpp}); err != nil || s.GetServiceType() != "otp" {
+		t.Fatalf("want smpp service, have %v, %v", s, err)
+	}
+	if s, _ := r.Route(protocol.Payload{Type: protocol.Smtp}); s != mustGet(t, r, "mail") {
+		t.Fatal("want rule applied before default")
+	}
+	if r.Idempotency == nil || r.Idempotency.TTL != time.Hour {
+		t.Fatalf("want idempotency configured, have %+v", r.Idempotency)
+	}
+}
+
+func mustGet(t *testing.T, r *protocol.Registry, name string) protocol.Service {
+	t.Helper()
+	s, ok := r.Get(name)
+	if !ok {
+		t.Fatalf("want service %s registered", name)
+	}
+	return s
+}
+
+func TestParseErrors(t *testing.T) {
+	os.Unsetenv("MAIL_PORT")
+	if _, err := Parse([]byte(document)); err == nil || !strings.Contains(err.Error(), "MAIL_PORT") {
+		t.Fatalf("want undefined variable error, have %v", err)
+	}
+	if _, err := Parse([]byte(`{"services": [{"name": "x", "type": "smtp", "smtp": {"host": "h", "port": 25, "hots": "h"}}]}`)); err == nil {
+		t.Fatal("want error for unknown field")
+	}
+
+	_, err := Parse([]byte(`
+services:
+  - name: carrier
+    type: smpp
+    smpp: {url: "", auth: {system_id: ""}, balancer: fastest, register: always}
+    fallback: [missing]
+  - name: carrier
+    type: fax
+defaults:
+  http: carrier
+templates: {default: en}
+`))
+	var verr ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("want ValidationError, have %v", err)
+	}
+	for _, want := range []string{"url is required", "system_id is required", `balancer "fastest"`, `register "always"`, `fallback "missing"`, "duplicate name", `type "fax"`, "not of type http", "templates: path is required"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("want %q in %v", want, err)
+		}
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "services.json")
+	os.WriteFile(path, []byte(`{"services": [{"name": "hook", "type": "http", "http": {"url": "https://example.com", "retry_wait_max": "2s"}}]}`), 0o644)
+	doc, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Duration(doc.Services[0].HTTP.RetryWaitMax); d != 2*time.Second {
+		t.Fatalf("want 2s, have %v", d)
+	}
+}
+
+type closingCapturer struct{ closed bool }
+
+func (c *closingCapturer) Capture(capture.Record) error { return nil }
+func (c *closingCapturer) Close() error                 { c.closed = true; return nil }
+
+func TestBuildCloses(t *testing.T) {
+	doc, err := Parse([]byte(`
+services:
+  - name: carrier
+    type: smpp
+    smpp: {url: "127.0.0.1:1", auth: {system_id: acme}}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := &closingCapturer{}
+	_, err = Build(doc, Options{SMPP: func(name string, setting *smpp.Setting) {
+		setting.Capture = cp
+	}})
+	if err == nil || !strings.Contains(err.Error(), `service "carrier": setup`) {
+		t.Fatalf("want setup error, have %v", err)
+	}
+	if !cp.closed {
+		t.Fatal("want SMPP service closed")
+	}
+}
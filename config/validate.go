@@ -0,0 +1,153 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/oarkflow/protocol"
+	"github.com/oarkflow/protocol/smpp"
+	"github.com/oarkflow/protocol/smpp/balancer"
+	"github.com/oarkflow/protocol/smpp/pdu/pdufield"
+	"github.com/oarkflow/protocol/smtp"
+)
+
+// ValidationError lists the problems of a document.
+type ValidationError []string
+
+func (e ValidationError) Error() string {
+	return "invalid config: " + strings.Join(e, "; ")
+}
+
+var registers = map[string]pdufield.DeliverySetting{
+	"":        pdufield.NoDeliveryReceipt,
+	"none":    pdufield.NoDeliveryReceipt,
+	"final":   pdufield.FinalDeliveryReceipt,
+	"failure": pdufield.FailureDeliveryReceipt,
+}
+
+var bindModes = map[string]bool{
+	"":                           true,
+	string(smpp.BindTransceiver): true,
+	string(smpp.BindTransmitter): true,
+	string(smpp.BindReceiver):    true,
+	string(smpp.BindSplit):       true,
+}
+
+// Validate returns a ValidationError listing the problems of d, if
+// any.
+func (d *Document) Validate() error {
+	var errs ValidationError
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+	types := make(map[string]protocol.Type)
+	for i, s := range d.Services {
+		name := s.Name
+		if name == "" {
+			add("services[%d]: name is required", i)
+			name = fmt.Sprintf("services[%d]", i)
+		} else if _, ok := types[name]; ok {
+			add("service %q: duplicate name", name)
+		}
+		types[s.Name] = s.Type
+		sections := 0
+		for _, set := range []bool{s.SMTP != nil || len(s.Providers) > 0, s.HTTP != nil, s.SMPP != nil} {
+			if set {
+				sections++
+			}
+		}
+		if sections > 1 {
+			add("service %q: only the section of its type may be set", name)
+		}
+		switch s.Type {
+		case protocol.Smtp:
+			var names []string
+			var configs []*smtp.Config
+			switch {
+			case s.SMTP != nil && len(s.Providers) > 0:
+				add("service %q: only one of smtp and providers may be set", name)
+			case s.SMTP != nil:
+				names, configs = []string{"smtp"}, []*smtp.Config{s.SMTP}
+			case len(s.Providers) > 0:
+				for i := range s.Providers {
+					names = append(names, fmt.Sprintf("providers[%d]", i))
+					configs = append(configs, &s.Providers[i].Config)
+				}
+			default:
+				add("service %q: smtp section is required", name)
+			}
+			for i, c := range configs {
+				section := names[i]
+				switch {
+				case c.Host == "":
+					add("service %q: %s host is required", name, section)
+				case c.Port <= 0 || c.Port > 65535:
+					add("service %q: invalid %s port %d", name, section, c.Port)
+				}
+				if k := c.DKIM; k != nil && (k.Domain == "" || k.Selector == "" || k.PrivateKeyFile == "") {
+					add("service %q: %s dkim domain, selector and private_key are required", name, section)
+				}
+			}
+		case protocol.Http:
+			if s.HTTP == nil {
+				add("service %q: http section is required", name)
+				break
+			}
+			if u, err := url.Parse(s.HTTP.URL); err != nil || u.Scheme == "" || u.Host == "" {
+				add("service %q: invalid http url %q", name, s.HTTP.URL)
+			}
+			if a := s.HTTP.Auth; a != nil && a.Type != "basic" && a.Type != "bearer" {
+				add("service %q: unknown http auth type %q", name, a.Type)
+			}
+		case protocol.Smpp:
+			if s.SMPP == nil {
+				add("service %q: smpp section is required", name)
+				break
+			}
+			if s.SMPP.URL == "" {
+				add("service %q: smpp url is required", name)
+			}
+			if s.SMPP.Auth.SystemID == "" {
+				add("service %q: smpp system_id is required", name)
+			}
+			if !bindModes[s.SMPP.BindMode] {
+				add("service %q: unknown bind mode %q", name, s.SMPP.BindMode)
+			}
+			if _, ok := registers[s.SMPP.Register]; !ok {
+				add("service %q: unknown register %q, want none, final or failure", name, s.SMPP.Register)
+			}
+			if _, err := balancer.New(s.SMPP.Balancer); err != nil {
+				add("service %q: unknown balancer %q, want one of %s", name, s.SMPP.Balancer, strings.Join(balancer.Names(), ", "))
+			}
+		default:
+			add("service %q: unknown type %q", name, s.Type)
+		}
+	}
+	for _, s := range d.Services {
+		for _, next := range s.Fallback {
+			if _, ok := types[next]; !ok {
+				add("service %q: unknown fallback %q", s.Name, next)
+			}
+		}
+	}
+	for t, name := range d.Defaults {
+		if typ, ok := types[name]; !ok {
+			add("defaults: unknown service %q", name)
+		} else if typ != t {
+			add("defaults: service %q is not of type %s", name, t)
+		}
+	}
+	for i, r := range d.Rules {
+		if _, ok := types[r.Service]; !ok {
+			add("rules[%d]: unknown service %q", i, r.Service)
+		}
+	}
+	if t := d.Templates; t != nil && t.Path == "" {
+		add("templates: path is required")
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -8,15 +8,13 @@ require (
 	github.com/oarkflow/errors v0.0.6
 	github.com/oarkflow/log v1.0.79
 	github.com/oarkflow/render v0.0.1
+	github.com/toorop/go-dkim v0.0.0-20240103092955-90b7d1423f92
 	github.com/xhit/go-simple-mail/v2 v2.16.0
 	golang.org/x/exp v0.0.0-20240525044651-4c93da0ed11d
 	golang.org/x/net v0.25.0
 	golang.org/x/text v0.15.0
 	golang.org/x/time v0.5.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
-require (
-	github.com/go-test/deep v1.1.0 // indirect
-	github.com/toorop/go-dkim v0.0.0-20240103092955-90b7d1423f92 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
-)
+require github.com/go-test/deep v1.1.0 // indirect
@@ -11,12 +11,18 @@ import (
 	"github.com/oarkflow/errors"
 
 	"github.com/oarkflow/protocol/http"
+	"github.com/oarkflow/protocol/i18n"
 )
 
 type HTTP struct {
-	client  *http.Client
-	Config  *http.Options
-	Service string
+	client       *http.Client
+	Config       *http.Options
+	Service      string
+	QueueOptions QueueOptions        // Worker pool used by Queue.
+	Callbacks    *CallbackDispatcher // Posts the lifecycle events of payloads to their CallbackURL.
+	Catalog      *i18n.Catalog       // Templates of the payloads naming one, see Payload.Localize.
+	next         Service
+	queue        serviceQueue
 }
 
 func (s *HTTP) Setup() error {
@@ -39,29 +45,82 @@ func (s *HTTP) Setup() error {
 	return nil
 }
 
+// Close stops the worker pool of the service, if started.
+func (s *HTTP) Close() error {
+	return s.queue.close()
+}
+
 func (s *HTTP) GetType() Type {
 	return Http
 }
 
 func (s *HTTP) SetService(service Service) {
-
+	s.next = service
 }
 
 func (s *HTTP) GetServiceType() string {
 	return s.Service
 }
 
+// Queue queues payload on the worker pool of the service and returns
+// the job ID.
 func (s *HTTP) Queue(payload Payload) (Response, error) {
-	return s.Handle(payload)
+	return s.queue.enqueue(s, s.queued, s.QueueOptions, s.Callbacks, payload)
+}
+
+// Jobs returns the worker pool of the service, started with
+// QueueOptions on first use.
+func (s *HTTP) Jobs() (*JobQueue, error) {
+	return s.queue.get(s, s.queued, s.QueueOptions)
+}
+
+// queued handles a queued payload. The response of the endpoint is
+// its delivery.
+func (s *HTTP) queued(payload *Payload) (Response, error) {
+	res, err := s.Handle(*payload)
+	if err == nil {
+		payload.SentAt = time.Now()
+		payload.DeliveredAt = payload.SentAt
+	}
+	return res, err
 }
 
 func (s *HTTP) Handle(payload Payload) (Response, error) {
+	return fallback(s.next, payload, s.handle, func(res Response, err error) {
+		s.Callbacks.notify(s, payload, EventDelivered, res, err)
+	})
+}
+
+// SendWebhook sends webhook, falling back to the next service on
+// failure.
+func (s *HTTP) SendWebhook(webhook WebhookPayload) (Response, error) {
+	return s.Handle(webhook.Payload())
+}
+
+func (s *HTTP) handle(payload Payload) (Response, error) {
+	res, err := s.request(payload)
+	if err == nil {
+		// The response of the endpoint is its delivery.
+		s.Callbacks.notify(s, payload, EventDelivered, res, nil)
+	}
+	return res, err
+}
+
+func (s *HTTP) request(payload Payload) (Response, error) {
+	if payload.Template != "" {
+		if _, err := payload.Localize(s.Catalog); err != nil {
+			return nil, err
+		}
+	}
 	if payload.URL == "" {
 		payload.URL = s.Config.URL
 	}
 	if payload.Method == "" {
 		payload.Method = s.Config.Method
 	}
+	if err := payload.Webhook().Validate(); err != nil {
+		return nil, err
+	}
 	switch strings.ToUpper(payload.Method) {
 	case "POST":
 		response, err := s.client.Post(payload.URL, payload.Data, payload.Headers)
@@ -0,0 +1,172 @@
+// Package i18n localizes the messages sent by the services.
+//
+// A Catalog holds templates keyed by name and locale. A template is
+// looked up along the fallback chain of the requested locale, e.g.
+// "pt-BR", "pt" and then the default locale of the catalog, and
+// rendered with the locale filters of the package:
+//
+//	{{ count | plural "# message" "# messages" }}
+//	{{ amount | number 2 }}
+//	{{ created | date "long" }}
+//
+// which follow the plural rules and formats registered for the
+// language of the locale, see RegisterPluralRule and RegisterFormat.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/oarkflow/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/oarkflow/protocol/utils/template"
+)
+
+var ErrNotFound = errors.New("i18n: template not found")
+
+// Template is the content of a message in a locale. Its fields are
+// templates rendered with the data of the message, see Render.
+type Template struct {
+	Subject string `json:"subject,omitempty" yaml:"subject,omitempty"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"` // SMS text or email body.
+	// View is the mailer view rendering the email body instead of
+	// Message, e.g. "emails/fr/welcome".
+	View             string `json:"view,omitempty" yaml:"view,omitempty"`
+	RequestStructure string `json:"request_structure,omitempty" yaml:"request_structure,omitempty"` // Webhook body.
+}
+
+// Render returns t with its Subject, Message and RequestStructure
+// rendered with data in locale, the values output in RequestStructure
+// being JSON escaped.
+func (t Template) Render(locale string, data any) (Template, error) {
+	var err error
+	text := template.Options{Locale: locale}
+	if t.Subject, err = template.Render(t.Subject, text, data); err != nil {
+		return t, fmt.Errorf("subject: %w", err)
+	}
+	if t.Message, err = template.Render(t.Message, text, data); err != nil {
+		return t, fmt.Errorf("message: %w", err)
+	}
+	t.RequestStructure, err = template.Render(t.RequestStructure, template.Options{Escape: template.JSON, Locale: locale}, data)
+	if err != nil {
+		return t, fmt.Errorf("request structure: %w", err)
+	}
+	return t, nil
+}
+
+// Catalog holds templates by name and locale, safe for concurrent use.
+type Catalog struct {
+	// Default is the locale ending every fallback chain, "en" if
+	// empty.
+	Default   string
+	mu        sync.RWMutex
+	templates map[string]map[string]Template
+	fallbacks map[string][]string
+}
+
+func NewCatalog(defaultLocale string) *Catalog {
+	return &Catalog{
+		Default:   defaultLocale,
+		templates: make(map[string]map[string]Template),
+		fallbacks: make(map[string][]string),
+	}
+}
+
+// Add adds the template name in locale, replacing any previous one.
+func (c *Catalog) Add(name, locale string, t Template) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.templates == nil {
+		c.templates = make(map[string]map[string]Template)
+	}
+	if c.templates[name] == nil {
+		c.templates[name] = make(map[string]Template)
+	}
+	c.templates[name][Normalize(locale)] = t
+}
+
+// Load adds the templates of the YAML or JSON file at path, keyed by
+// name and then locale:
+//
+//	welcome:
+//	  en: {subject: "Welcome {{ name }}", view: emails/welcome}
+//	  fr: {subject: "Bienvenue {{ name }}", view: emails/fr/welcome}
+func (c *Catalog) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var templates map[string]map[string]Template
+	if err = yaml.Unmarshal(data, &templates); err != nil {
+		return fmt.Errorf("i18n: %s: %w", path, err)
+	}
+	for name, locales := range templates {
+		for locale, t := range locales {
+			c.Add(name, locale, t)
+		}
+	}
+	return nil
+}
+
+// SetFallback makes locale fall back to the given locales instead of
+// its parent, e.g. "es-MX" to "es-419".
+func (c *Catalog) SetFallback(locale string, fallbacks ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fallbacks == nil {
+		c.fallbacks = make(map[string][]string)
+	}
+	normalized := make([]string, len(fallbacks))
+	for i, fallback := range fallbacks {
+		normalized[i] = Normalize(fallback)
+	}
+	c.fallbacks[Normalize(locale)] = normalized
+}
+
+// Chain returns the locales looked up for locale in order: locale,
+// its fallbacks or else its parents, and the default locale with its
+// parents.
+func (c *Catalog) Chain(locale string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var chain []string
+	seen := make(map[string]bool)
+	var add func(string)
+	add = func(l string) {
+		for ; l != "" && !seen[l]; l = Parent(l) {
+			seen[l] = true
+			chain = append(chain, l)
+			if fallbacks, ok := c.fallbacks[l]; ok {
+				for _, f := range fallbacks {
+					add(f)
+				}
+				return
+			}
+		}
+	}
+	add(Normalize(locale))
+	def := c.Default
+	if def == "" {
+		def = "en"
+	}
+	add(Normalize(def))
+	return chain
+}
+
+// Lookup returns the template name in the first locale of the chain
+// of locale having it, and that locale.
+func (c *Catalog) Lookup(name, locale string) (Template, string, error) {
+	chain := c.Chain(locale)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	locales := c.templates[name]
+	for _, l := range chain {
+		if t, ok := locales[l]; ok {
+			return t, l, nil
+		}
+	}
+	return Template{}, "", fmt.Errorf("%w: %q in %s", ErrNotFound, name, strings.Join(chain, ", "))
+}
@@ -0,0 +1,142 @@
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/oarkflow/protocol/utils/template"
+)
+
+func init() {
+	template.RegisterLocaleFilter("number", numberFilter)
+	template.RegisterLocaleFilter("plural", pluralFilter)
+	template.RegisterLocaleFilter("date", dateFilter)
+}
+
+// Funcs are the locale filters as functions taking the locale first,
+// for html templates such as the views of the SMTP mailer:
+//
+//	engine.AddFuncMap(i18n.Funcs)
+//	{{ plural .locale .count "# message" "# messages" }}
+var Funcs = map[string]any{
+	"number": func(locale string, v any, decimals ...int) (string, error) {
+		args := make([]any, len(decimals))
+		for i, d := range decimals {
+			args[i] = d
+		}
+		return str(numberFilter(locale, v, args...))
+	},
+	"plural": func(locale string, n any, forms ...string) (string, error) {
+		args := make([]any, len(forms))
+		for i, f := range forms {
+			args[i] = f
+		}
+		return str(pluralFilter(locale, n, args...))
+	},
+	"date": func(locale string, v any, style ...string) (string, error) {
+		args := make([]any, len(style))
+		for i, s := range style {
+			args[i] = s
+		}
+		return str(dateFilter(locale, v, args...))
+	},
+}
+
+func str(v any, err error) (string, error) {
+	if err != nil || v == nil {
+		return "", err
+	}
+	return fmt.Sprint(v), nil
+}
+
+// numberFilter formats a number with the optional number of decimals,
+// e.g. {{ amount | number 2 }}.
+func numberFilter(locale string, v any, args ...any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	n, err := float(v)
+	if err != nil {
+		return nil, err
+	}
+	decimals := -1
+	if len(args) > 0 {
+		d, ok := args[0].(int)
+		if !ok {
+			return nil, fmt.Errorf("invalid decimals %v", args[0])
+		}
+		decimals = d
+	}
+	return FormatNumber(locale, n, decimals), nil
+}
+
+// pluralFilter selects the form of a number, see Pluralize.
+func pluralFilter(locale string, v any, args ...any) (any, error) {
+	n, err := float(v)
+	if err != nil {
+		return nil, err
+	}
+	forms := make([]string, len(args))
+	for i, a := range args {
+		forms[i] = fmt.Sprint(a)
+	}
+	return Pluralize(locale, n, forms...), nil
+}
+
+// dateFilter extends the date filter of the template package with the
+// styles and names of the locale, e.g. {{ created | date "long" }}.
+func dateFilter(locale string, v any, args ...any) (any, error) {
+	style := time.RFC3339
+	if len(args) > 0 {
+		style = fmt.Sprint(args[0])
+	}
+	f := FormatOf(locale)
+	date, _ := template.LookupFilter("date")
+	s, err := date(v, f.layout(style))
+	if err != nil {
+		return nil, err
+	}
+	return f.names(fmt.Sprint(s)), nil
+}
+
+func float(v any) (float64, error) {
+	switch v := v.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return float64(v), nil
+	case int8:
+		return float64(v), nil
+	case int16:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case uint8:
+		return float64(v), nil
+	case uint16:
+		return float64(v), nil
+	case uint32:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q", v)
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("invalid number %v", v)
+}
@@ -0,0 +1,243 @@
+package i18n
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Format holds how a language writes numbers and dates.
+type Format struct {
+	Decimal, Group string // Separators of the decimals and thousands.
+	// Go layouts of the date styles, written with the English names
+	// of months and days, e.g. "2 January 2006".
+	Short, Medium, Long, Full string
+	// Names of the months from January and of the days from Sunday
+	// replacing the English ones, none for English.
+	Months, ShortMonths, Days, ShortDays []string
+}
+
+var english = Format{
+	Decimal: ".", Group: ",",
+	Short: "1/2/06", Medium: "Jan 2, 2006", Long: "January 2, 2006", Full: "Monday, January 2, 2006",
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   = map[string]Format{
+		"en": english,
+		"en-GB": {
+			Decimal: ".", Group: ",",
+			Short: "02/01/2006", Medium: "2 Jan 2006", Long: "2 January 2006", Full: "Monday, 2 January 2006",
+		},
+		"de": {
+			Decimal: ",", Group: ".",
+			Short: "02.01.06", Medium: "02.01.2006", Long: "2. January 2006", Full: "Monday, 2. January 2006",
+			Months:      []string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+			ShortMonths: []string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+			Days:        []string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+			ShortDays:   []string{"So.", "Mo.", "Di.", "Mi.", "Do.", "Fr.", "Sa."},
+		},
+		"fr": {
+			Decimal: ",", Group: "\u202f", // Narrow no-break space.
+			Short: "02/01/2006", Medium: "2 Jan 2006", Long: "2 January 2006", Full: "Monday 2 January 2006",
+			Months:      []string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+			ShortMonths: []string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+			Days:        []string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+			ShortDays:   []string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+		},
+		"es": {
+			Decimal: ",", Group: ".",
+			Short: "2/1/06", Medium: "2 Jan 2006", Long: "2 de January de 2006", Full: "Monday, 2 de January de 2006",
+			Months:      []string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+			ShortMonths: []string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+			Days:        []string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+			ShortDays:   []string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+		},
+		"pt": {
+			Decimal: ",", Group: ".",
+			Short: "02/01/2006", Medium: "2 de Jan de 2006", Long: "2 de January de 2006", Full: "Monday, 2 de January de 2006",
+			Months:      []string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+			ShortMonths: []string{"jan.", "fev.", "mar.", "abr.", "mai.", "jun.", "jul.", "ago.", "set.", "out.", "nov.", "dez."},
+			Days:        []string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+			ShortDays:   []string{"dom.", "seg.", "ter.", "qua.", "qui.", "sex.", "sáb."},
+		},
+		"it": {
+			Decimal: ",", Group: ".",
+			Short: "02/01/06", Medium: "2 Jan 2006", Long: "2 January 2006", Full: "Monday 2 January 2006",
+			Months:      []string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+			ShortMonths: []string{"gen", "feb", "mar", "apr", "mag", "giu", "lug", "ago", "set", "ott", "nov", "dic"},
+			Days:        []string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+			ShortDays:   []string{"dom", "lun", "mar", "mer", "gio", "ven", "sab"},
+		},
+		"nl": {
+			Decimal: ",", Group: ".",
+			Short: "02-01-2006", Medium: "2 Jan 2006", Long: "2 January 2006", Full: "Monday 2 January 2006",
+			Months:      []string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+			ShortMonths: []string{"jan", "feb", "mrt", "apr", "mei", "jun", "jul", "aug", "sep", "okt", "nov", "dec"},
+			Days:        []string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+			ShortDays:   []string{"zo", "ma", "di", "wo", "do", "vr", "za"},
+		},
+	}
+)
+
+// RegisterFormat sets the format of locale, a language or a more
+// specific locale, e.g. "en-GB".
+func RegisterFormat(locale string, f Format) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats[Normalize(locale)] = f
+}
+
+// FormatOf returns the format of locale or its closest parent, the
+// English format if none.
+func FormatOf(locale string) Format {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	for l := Normalize(locale); l != ""; l = Parent(l) {
+		if f, ok := formats[l]; ok {
+			return f
+		}
+	}
+	return english
+}
+
+// FormatNumber formats n in locale with the given number of decimals,
+// as many as needed if negative.
+func FormatNumber(locale string, n float64, decimals int) string {
+	f := FormatOf(locale)
+	s := strconv.FormatFloat(math.Abs(n), 'f', decimals, 64)
+	integer, fraction, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	if n < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, c := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteString(f.Group)
+		}
+		b.WriteRune(c)
+	}
+	if fraction != "" {
+		b.WriteString(f.Decimal)
+		b.WriteString(fraction)
+	}
+	return b.String()
+}
+
+// FormatDate formats t in locale with a style, "short", "medium",
+// "long" or "full", or a Go layout.
+func FormatDate(locale string, t time.Time, style string) string {
+	f := FormatOf(locale)
+	return f.names(t.Format(f.layout(style)))
+}
+
+// Markers of the English names in layouts, replaced after formatting.
+const (
+	markMonth = '\x01' + iota
+	markShortMonth
+	markDay
+	markShortDay
+)
+
+// layout returns the layout of style, its names being marked to be
+// replaced by those of f, see names.
+func (f Format) layout(style string) string {
+	switch style {
+	case "short":
+		style = f.Short
+	case "medium":
+		style = f.Medium
+	case "long":
+		style = f.Long
+	case "full":
+		style = f.Full
+	}
+	if f.Months == nil {
+		return style
+	}
+	var b strings.Builder
+	for i := 0; i < len(style); {
+		name, mark := layoutName(style[i:])
+		if name == "" {
+			b.WriteByte(style[i])
+			i++
+			continue
+		}
+		b.WriteByte(mark)
+		b.WriteString(name)
+		b.WriteByte(mark)
+		i += len(name)
+	}
+	return b.String()
+}
+
+// layoutName returns the English name layout starts with and its
+// marker.
+func layoutName(layout string) (string, byte) {
+	for _, token := range []struct {
+		name string
+		mark byte
+	}{{"January", markMonth}, {"Monday", markDay}, {"Jan", markShortMonth}, {"Mon", markShortDay}} {
+		if strings.HasPrefix(layout, token.name) {
+			return token.name, token.mark
+		}
+	}
+	return "", 0
+}
+
+// names replaces the marked English names of s with those of f.
+func (f Format) names(s string) string {
+	if f.Months == nil {
+		return s
+	}
+	var b strings.Builder
+	for {
+		i := strings.IndexAny(s, "\x01\x02\x03\x04")
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(s[i+1:], s[i])
+		if j < 0 {
+			break
+		}
+		b.WriteString(s[:i])
+		b.WriteString(f.name(s[i], s[i+1:i+1+j]))
+		s = s[i+2+j:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+func (f Format) name(mark byte, english string) string {
+	var names []string
+	index := -1
+	switch mark {
+	case markMonth, markShortMonth:
+		names = f.Months
+		if mark == markShortMonth {
+			names = f.ShortMonths
+		}
+		for m := time.January; m <= time.December; m++ {
+			if m.String() == english || m.String()[:3] == english {
+				index = int(m) - 1
+			}
+		}
+	default:
+		names = f.Days
+		if mark == markShortDay {
+			names = f.ShortDays
+		}
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if d.String() == english || d.String()[:3] == english {
+				index = int(d)
+			}
+		}
+	}
+	if index < 0 || index >= len(names) {
+		return english
+	}
+	return names[index]
+}
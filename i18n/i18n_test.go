@@ -0,0 +1,133 @@
+package i18n
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCatalog(t *testing.T) {
+	c := NewCatalog("en")
+	c.SetFallback("es-MX", "es-419")
+	if chain := c.Chain("es_mx"); !reflect.DeepEqual(chain, []string{"es-MX", "es-419", "es", "en"}) {
+		t.Fatalf("want explicit fallback chain, have %q", chain)
+	}
+	if chain := c.Chain("zh-hant-tw"); !reflect.DeepEqual(chain, []string{"zh-Hant-TW", "zh-Hant", "zh", "en"}) {
+		t.Fatalf("want parent chain, have %q", chain)
+	}
+
+	path := filepath.Join(t.TempDir(), "templates.yaml")
+	os.WriteFile(path, []byte(`
+welcome:
+  en: {subject: "Welcome {{ name }}", message: "{{ count | plural \"# new message\" \"# new messages\" }}"}
+  pt: {subject: "Bem-vindo {{ name }}", message: "{{ count | plural \"# nova mensagem\" \"# novas mensagens\" }}"}
+`), 0o644)
+	if err := c.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, locale, err := c.Lookup("welcome", "pt-BR")
+	if err != nil || locale != "pt" {
+		t.Fatalf("want pt template, have %q, %v", locale, err)
+	}
+	r, err := tmpl.Render(locale, map[string]any{"name": "Ana", "count": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Subject != "Bem-vindo Ana" || r.Message != "0 nova mensagem" {
+		t.Fatalf("want rendered pt template, have %+v", r)
+	}
+	if _, locale, _ = c.Lookup("welcome", "de"); locale != "en" {
+		t.Fatalf("want default locale, have %q", locale)
+	}
+	if _, _, err = c.Lookup("missing", "en"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound, have %v", err)
+	}
+}
+
+func TestPluralize(t *testing.T) {
+	for _, tt := range []struct {
+		locale string
+		n      float64
+		want   string
+	}{
+		{"en", 1, "one"},
+		{"en", 0, "other"},
+		{"fr", 0, "one"},
+		{"pt-PT", 0, "other"},
+		{"ru", 21, "one"},
+		{"ru", 22, "few"},
+		{"ru", 12, "many"},
+		{"pl", 5, "many"},
+		{"ar", 0, "zero"},
+		{"ja", 1, "other"},
+	} {
+		if have := string(PluralOf(tt.locale, tt.n)); have != tt.want {
+			t.Errorf("%s %v: want %s, have %s", tt.locale, tt.n, tt.want, have)
+		}
+	}
+	if have := Pluralize("ru", 5, "# файл", "# файла", "# файлов"); have != "5 файлов" {
+		t.Fatalf("want many form, have %q", have)
+	}
+	if have := Pluralize("en", 1200, "# file", "# files"); have != "1,200 files" {
+		t.Fatalf("want formatted other form, have %q", have)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for _, tt := range []struct {
+		locale   string
+		n        float64
+		decimals int
+		want     string
+	}{
+		{"en", 1234567.891, 2, "1,234,567.89"},
+		{"de-AT", 1234.5, -1, "1.234,5"},
+		{"fr", -1000, 0, "-1\u202f000"},
+		{"en", -0.001, 2, "0.00"},
+	} {
+		if have := FormatNumber(tt.locale, tt.n, tt.decimals); have != tt.want {
+			t.Errorf("%s %v: want %q, have %q", tt.locale, tt.n, tt.want, have)
+		}
+	}
+
+	date := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		locale, style, want string
+	}{
+		{"en", "long", "March 5, 2024"},
+		{"en-GB", "full", "Tuesday, 5 March 2024"},
+		{"de", "full", "Dienstag, 5. März 2024"},
+		{"fr", "Mon 2 Jan", "mar. 5 mars"},
+		{"es", "long", "5 de marzo de 2024"},
+	} {
+		if have := FormatDate(tt.locale, date, tt.style); have != tt.want {
+			t.Errorf("%s %s: want %q, have %q", tt.locale, tt.style, tt.want, have)
+		}
+	}
+}
+
+func TestFilters(t *testing.T) {
+	tmpl := Template{
+		Message:          `{{ amount | number 2 }} {{ created | date "medium" }}`,
+		RequestStructure: `{"total": {{ amount | number 2 }}, "text": "{{ n | plural "# item" "# items" }}"}`,
+	}
+	data := map[string]any{"amount": 1234.5, "created": "2024-03-05T10:00:00Z", "n": 2}
+	r, err := tmpl.Render("nl", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1.234,50 5 mrt 2024"; r.Message != want {
+		t.Fatalf("want %q, have %q", want, r.Message)
+	}
+	if want := `{"total": "1.234,50", "text": "2 items"}`; r.RequestStructure != want {
+		t.Fatalf("want %q, have %q", want, r.RequestStructure)
+	}
+
+	date := Funcs["date"].(func(string, any, ...string) (string, error))
+	if s, err := date("it", time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC), "long"); err != nil || s != "1 maggio 2024" {
+		t.Fatalf("want italian date, have %q, %v", s, err)
+	}
+}
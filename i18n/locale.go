@@ -0,0 +1,46 @@
+package i18n
+
+import "strings"
+
+// Normalize returns locale in its canonical form, e.g. "pt_br" is
+// "pt-BR" and "zh_hant_tw" is "zh-Hant-TW".
+func Normalize(locale string) string {
+	parts := strings.FieldsFunc(strings.TrimSpace(locale), func(r rune) bool { return r == '-' || r == '_' })
+	for i, p := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(p)
+		case len(p) == 2 && isLetters(p):
+			parts[i] = strings.ToUpper(p)
+		case len(p) == 4 && isLetters(p):
+			parts[i] = strings.ToUpper(p[:1]) + strings.ToLower(p[1:])
+		default:
+			parts[i] = strings.ToLower(p)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// Parent returns the locale falling back from locale by dropping its
+// last subtag, e.g. "zh-Hant" for "zh-Hant-TW", and "" for a language.
+func Parent(locale string) string {
+	if i := strings.LastIndexByte(locale, '-'); i > 0 {
+		return locale[:i]
+	}
+	return ""
+}
+
+// Language returns the language of locale, e.g. "pt" for "pt-BR".
+func Language(locale string) string {
+	lang, _, _ := strings.Cut(Normalize(locale), "-")
+	return lang
+}
+
+func isLetters(s string) bool {
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
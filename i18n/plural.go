@@ -0,0 +1,174 @@
+package i18n
+
+import (
+	"math"
+	"strings"
+	"sync"
+)
+
+// Plural is a CLDR plural category.
+type Plural string
+
+const (
+	Zero  Plural = "zero"
+	One   Plural = "one"
+	Two   Plural = "two"
+	Few   Plural = "few"
+	Many  Plural = "many"
+	Other Plural = "other"
+)
+
+// PluralRule selects the plural category of numbers in a language.
+type PluralRule struct {
+	// Categories are the categories of the language, in the order of
+	// the forms given to Pluralize.
+	Categories []Plural
+	Select     func(n float64) Plural
+}
+
+var (
+	oneOther = PluralRule{[]Plural{One, Other}, func(n float64) Plural {
+		if n == 1 || n == -1 {
+			return One
+		}
+		return Other
+	}}
+
+	pluralsMu sync.RWMutex
+	plurals   = map[string]PluralRule{
+		"en": oneOther,
+		"fr": {[]Plural{One, Other}, func(n float64) Plural {
+			if i := math.Abs(n); i < 2 {
+				return One
+			}
+			return Other
+		}},
+		"ru": {[]Plural{One, Few, Many, Other}, eastSlavic},
+		"uk": {[]Plural{One, Few, Many, Other}, eastSlavic},
+		"be": {[]Plural{One, Few, Many, Other}, eastSlavic},
+		"pl": {[]Plural{One, Few, Many, Other}, func(n float64) Plural {
+			i, integer := integral(n)
+			switch {
+			case !integer:
+				return Other
+			case i == 1:
+				return One
+			case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+				return Few
+			}
+			return Many
+		}},
+		"cs": {[]Plural{One, Few, Many, Other}, westSlavic},
+		"sk": {[]Plural{One, Few, Many, Other}, westSlavic},
+		"ar": {[]Plural{Zero, One, Two, Few, Many, Other}, func(n float64) Plural {
+			i, integer := integral(n)
+			switch {
+			case !integer:
+				return Other
+			case i == 0:
+				return Zero
+			case i == 1:
+				return One
+			case i == 2:
+				return Two
+			case i%100 >= 3 && i%100 <= 10:
+				return Few
+			case i%100 >= 11:
+				return Many
+			}
+			return Other
+		}},
+	}
+)
+
+func init() {
+	// CLDR gives 0 and 1 the singular in these languages, except in
+	// Portugal.
+	for _, lang := range []string{"pt", "hi"} {
+		plurals[lang] = plurals["fr"]
+	}
+	plurals["pt-PT"] = oneOther
+	other := PluralRule{[]Plural{Other}, func(float64) Plural { return Other }}
+	for _, lang := range []string{"ja", "zh", "ko", "vi", "th", "id", "ms"} {
+		plurals[lang] = other
+	}
+}
+
+// RegisterPluralRule sets the plural rule of locale, a language or a
+// more specific locale, e.g. "pt-PT".
+func RegisterPluralRule(locale string, rule PluralRule) {
+	pluralsMu.Lock()
+	defer pluralsMu.Unlock()
+	plurals[Normalize(locale)] = rule
+}
+
+// pluralRule returns the rule of locale or its closest parent, the
+// English rule if none.
+func pluralRule(locale string) PluralRule {
+	pluralsMu.RLock()
+	defer pluralsMu.RUnlock()
+	for l := Normalize(locale); l != ""; l = Parent(l) {
+		if rule, ok := plurals[l]; ok {
+			return rule
+		}
+	}
+	return oneOther
+}
+
+// PluralOf returns the plural category of n in locale.
+func PluralOf(locale string, n float64) Plural {
+	return pluralRule(locale).Select(n)
+}
+
+// Pluralize returns the form of n in locale among forms, given in the
+// order of the categories of the plural rule of locale, the last form
+// standing for the categories missing. "#" in the form is replaced
+// with n formatted in locale.
+func Pluralize(locale string, n float64, forms ...string) string {
+	if len(forms) == 0 {
+		return FormatNumber(locale, n, -1)
+	}
+	rule := pluralRule(locale)
+	category := rule.Select(n)
+	form := forms[len(forms)-1]
+	for i, c := range rule.Categories {
+		if c == category && i < len(forms) {
+			form = forms[i]
+			break
+		}
+	}
+	return strings.ReplaceAll(form, "#", FormatNumber(locale, n, -1))
+}
+
+// integral returns the absolute integer value of n and whether n is
+// an integer.
+func integral(n float64) (int64, bool) {
+	n = math.Abs(n)
+	return int64(n), n == math.Trunc(n)
+}
+
+func eastSlavic(n float64) Plural {
+	i, integer := integral(n)
+	switch {
+	case !integer:
+		return Other
+	case i%10 == 1 && i%100 != 11:
+		return One
+	case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+		return Few
+	}
+	return Many
+}
+
+func westSlavic(n float64) Plural {
+	i, integer := integral(n)
+	switch {
+	case !integer:
+		return Many
+	case i == 1:
+		return One
+	case i >= 2 && i <= 4:
+		return Few
+	}
+	return Other
+}
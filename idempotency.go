@@ -0,0 +1,359 @@
+package protocol
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/oarkflow/errors"
+)
+
+// ErrInProgress is returned for a payload whose ID is being handled.
+var ErrInProgress = errors.New("Payload with the same ID is being handled")
+
+// IdempotencyRecord is the outcome of handling the payload with ID
+// Key.
+type IdempotencyRecord struct {
+	Key       string    `json:"key"`
+	Done      bool      `json:"done"` // False while the payload is being handled.
+	Response  Response  `json:"response,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (r IdempotencyRecord) expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
+
+// IdempotencyStore records the payload IDs handled.
+type IdempotencyStore interface {
+	// Reserve records key as being handled until ttl expires and
+	// returns true, unless key is recorded already, in which case its
+	// record is returned.
+	Reserve(key string, ttl time.Duration) (IdempotencyRecord, bool, error)
+	// Complete records the outcome of a reserved key.
+	Complete(record IdempotencyRecord) error
+	// Release removes key so it can be handled again.
+	Release(key string) error
+}
+
+// Idempotency returns the original response of payloads handled again
+// with the same ID within TTL, e.g. after a client retry. Payloads
+// without ID are always handled.
+type Idempotency struct {
+	Store IdempotencyStore
+	TTL   time.Duration // Default 24h.
+	// CacheErrors records failures too, otherwise a payload which
+	// failed can be handled again.
+	CacheErrors bool
+}
+
+// NewIdempotency returns an Idempotency keeping the IDs in memory for
+// ttl.
+func NewIdempotency(ttl time.Duration) *Idempotency {
+	return &Idempotency{Store: NewMemoryIdempotencyStore(), TTL: ttl}
+}
+
+// Handle calls handle unless the payload ID was handled, returning the
+// outcome recorded then. It returns ErrInProgress while the ID is
+// being handled, and releases the ID if handle panics. Responses are
+// recorded and returned as deep copies, so they keep their type but
+// not the later changes of the original, e.g. a message updated by
+// delivery reports.
+func (i *Idempotency) Handle(payload Payload, handle func(Payload) (Response, error)) (Response, error) {
+	if i == nil || payload.ID == "" {
+		return handle(payload)
+	}
+	ttl := i.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	record, reserved, err := i.Store.Reserve(payload.ID, ttl)
+	if err != nil {
+		return nil, errors.NewE(err, "Unable to reserve payload ID", "idempotency:handle")
+	}
+	if !reserved {
+		if !record.Done {
+			return nil, ErrInProgress
+		}
+		if record.Error != "" {
+			return clone(record.Response), errors.New(record.Error)
+		}
+		return clone(record.Response), nil
+	}
+	done := false
+	defer func() {
+		// A panicking handler must not keep the ID reserved.
+		if !done {
+			i.Store.Release(payload.ID)
+		}
+	}()
+	res, err := handle(payload)
+	done = true
+	if err != nil && !i.CacheErrors {
+		if e := i.Store.Release(payload.ID); e != nil {
+			return res, errors.NewE(e, "Unable to release payload ID", "idempotency:handle")
+		}
+		return res, err
+	}
+	record.Done, record.Response = true, clone(res)
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if e := i.Store.Complete(record); e != nil {
+		return res, errors.NewE(e, "Unable to record payload outcome", "idempotency:handle")
+	}
+	return res, err
+}
+
+// clone returns a deep copy of res. Unexported fields of structs are
+// copied as is.
+func clone(res Response) Response {
+	if res == nil {
+		return nil
+	}
+	return deepCopy(reflect.ValueOf(res), make(map[uintptr]reflect.Value)).Interface()
+}
+
+// deepCopy copies v, seen holding the copies of the pointers met so
+// that cycles and shared values are kept.
+func deepCopy(v reflect.Value, seen map[uintptr]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		if c, ok := seen[v.Pointer()]; ok {
+			return c
+		}
+		c := reflect.New(v.Type().Elem())
+		seen[v.Pointer()] = c
+		c.Elem().Set(deepCopy(v.Elem(), seen))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem(), seen))
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i), seen))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i), seen))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), deepCopy(iter.Value(), seen))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if f := c.Field(i); f.CanSet() {
+				f.Set(deepCopy(v.Field(i), seen))
+			}
+		}
+		return c
+	}
+	return v
+}
+
+// MemoryIdempotencyStore keeps the records in memory, expired ones
+// being removed at most once a minute.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+	swept   time.Time
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: make(map[string]IdempotencyRecord)}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(key string, ttl time.Duration) (IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.swept) > time.Minute {
+		s.sweep(now)
+	}
+	if record, ok := s.records[key]; ok && !record.expired(now) {
+		return record, false, nil
+	}
+	record := IdempotencyRecord{Key: key, CreatedAt: now, ExpiresAt: now.Add(ttl)}
+	s.records[key] = record
+	return record, true, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(record IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[record.Key] = record
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// Len returns the number of records, expired ones included.
+func (s *MemoryIdempotencyStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.records)
+}
+
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	for key, record := range s.records {
+		if record.expired(now) {
+			delete(s.records, key)
+		}
+	}
+	s.swept = now
+}
+
+// FileIdempotencyStore keeps the records in memory and appends them to
+// a file readable by its owner only, one JSON line each, so they
+// survive restarts. The file is compacted to the records not expired
+// when opened. Payloads being handled when the store was closed can be
+// handled again. Responses read back from the file are JSON decoded,
+// e.g. a map for a struct.
+type FileIdempotencyStore struct {
+	*MemoryIdempotencyStore
+	fileMu sync.Mutex
+	path   string
+	file   *os.File
+}
+
+// OpenFileIdempotencyStore opens or creates the store at path.
+func OpenFileIdempotencyStore(path string) (*FileIdempotencyStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	s := &FileIdempotencyStore{MemoryIdempotencyStore: NewMemoryIdempotencyStore(), path: path}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileIdempotencyStore) Reserve(key string, ttl time.Duration) (IdempotencyRecord, bool, error) {
+	record, reserved, err := s.MemoryIdempotencyStore.Reserve(key, ttl)
+	if err != nil || !reserved {
+		return record, reserved, err
+	}
+	return record, true, s.append(record)
+}
+
+func (s *FileIdempotencyStore) Complete(record IdempotencyRecord) error {
+	s.MemoryIdempotencyStore.Complete(record)
+	return s.append(record)
+}
+
+// Release records key with a zero expiry, i.e. expired.
+func (s *FileIdempotencyStore) Release(key string) error {
+	s.MemoryIdempotencyStore.Release(key)
+	return s.append(IdempotencyRecord{Key: key})
+}
+
+func (s *FileIdempotencyStore) Close() error {
+	s.fileMu.Lock()
+	defer s.fileMu.Unlock()
+	return s.file.Close()
+}
+
+func (s *FileIdempotencyStore) append(record IdempotencyRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.fileMu.Lock()
+	defer s.fileMu.Unlock()
+	_, err = s.file.Write(append(b, '\n'))
+	return err
+}
+
+// load reads the file, ignoring a truncated last line, and rewrites it
+// with the records done and not expired.
+func (s *FileIdempotencyStore) load() error {
+	now := time.Now()
+	var order []string
+	f, err := os.Open(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 64*1024), 64*1024*1024)
+		for sc.Scan() {
+			var record IdempotencyRecord
+			if json.Unmarshal(sc.Bytes(), &record) != nil {
+				continue
+			}
+			if _, ok := s.records[record.Key]; !ok {
+				order = append(order, record.Key)
+			}
+			s.records[record.Key] = record
+		}
+		err = sc.Err()
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	tmp := s.path + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+	for _, key := range order {
+		record := s.records[key]
+		if !record.Done || record.expired(now) {
+			delete(s.records, key)
+			continue
+		}
+		if err = enc.Encode(record); err != nil {
+			out.Close()
+			return err
+		}
+	}
+	if err = w.Flush(); err != nil {
+		out.Close()
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	return err
+}
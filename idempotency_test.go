@@ -0,0 +1,105 @@
+package protocol
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oarkflow/protocol/smpp"
+	"github.com/oarkflow/protocol/smpp/pdu/pdutlv"
+)
+
+func TestIdempotency(t *testing.T) {
+	r := NewRegistry()
+	r.Idempotency = NewIdempotency(time.Hour)
+	mail := &fakeService{name: "mail", typ: Smtp}
+	r.Register("mail", mail)
+
+	for i := 0; i < 2; i++ {
+		res, err := r.Handle(Payload{ID: "p1", Type: Smtp})
+		if err != nil || res != "mail" {
+			t.Fatalf("want original response, have %v, %v", res, err)
+		}
+	}
+	if mail.hits != 1 {
+		t.Fatalf("want payload handled once, have %d", mail.hits)
+	}
+	r.Handle(Payload{Type: Smtp})
+	r.Handle(Payload{Type: Smtp})
+	if mail.hits != 3 {
+		t.Fatalf("want payloads without ID always handled, have %d", mail.hits)
+	}
+
+	mail.fail = true
+	r.Handle(Payload{ID: "p2", Type: Smtp})
+	mail.fail = false
+	if res, err := r.Handle(Payload{ID: "p2", Type: Smtp}); err != nil || res != "mail" {
+		t.Fatalf("want failed payload handled again, have %v, %v", res, err)
+	}
+
+	idem := &Idempotency{Store: NewMemoryIdempotencyStore()}
+	started, block := make(chan struct{}), make(chan struct{})
+	go idem.Handle(Payload{ID: "p3"}, func(Payload) (Response, error) {
+		close(started)
+		<-block
+		return nil, nil
+	})
+	<-started
+	if _, err := idem.Handle(Payload{ID: "p3"}, nil); !errors.Is(err, ErrInProgress) {
+		t.Fatalf("want ErrInProgress while handled, have %v", err)
+	}
+	close(block)
+
+	func() {
+		defer func() { recover() }()
+		idem.Handle(Payload{ID: "p4"}, func(Payload) (Response, error) { panic("handler") })
+	}()
+	res, err := idem.Handle(Payload{ID: "p4"}, func(Payload) (Response, error) { return "retried", nil })
+	if err != nil || res != "retried" {
+		t.Fatalf("want ID released after panic, have %v, %v", res, err)
+	}
+
+	msg := &smpp.Message{ID: "m1", MessageStatus: smpp.SENT, TLVs: pdutlv.Fields{pdutlv.TagUserMessageReference: 1}}
+	idem.Handle(Payload{ID: "p5"}, func(Payload) (Response, error) { return msg, nil })
+	msg.MessageStatus = smpp.DELIVERED
+	msg.TLVs[pdutlv.TagUserMessageReference] = 2
+	res, _ = idem.Handle(Payload{ID: "p5"}, nil)
+	m, ok := res.(*smpp.Message)
+	if !ok || m == msg || m.MessageStatus != smpp.SENT || m.TLVs[pdutlv.TagUserMessageReference] != 1 {
+		t.Fatalf("want message recorded when handled, have %#v", res)
+	}
+}
+
+func TestFileIdempotencyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "idempotency")
+	store, err := OpenFileIdempotencyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idem := &Idempotency{Store: store, TTL: time.Hour, CacheErrors: true}
+	idem.Handle(Payload{ID: "sent"}, func(Payload) (Response, error) { return "ok", nil })
+	idem.Handle(Payload{ID: "failed"}, func(Payload) (Response, error) { return nil, errors.New("rejected") })
+	store.Reserve("pending", time.Hour)
+	store.Reserve("expired", -time.Second)
+	store.Close()
+
+	if store, err = OpenFileIdempotencyStore(path); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if store.Len() != 2 {
+		t.Fatalf("want done records only, have %d", store.Len())
+	}
+	idem.Store = store
+	never := func(Payload) (Response, error) {
+		t.Fatal("want recorded outcome")
+		return nil, nil
+	}
+	if res, err := idem.Handle(Payload{ID: "sent"}, never); err != nil || res != "ok" {
+		t.Fatalf("want recorded response, have %v, %v", res, err)
+	}
+	if _, err := idem.Handle(Payload{ID: "failed"}, never); err == nil || err.Error() != "rejected" {
+		t.Fatalf("want recorded error, have %v", err)
+	}
+}
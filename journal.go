@@ -0,0 +1,174 @@
+package protocol
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Journal persists the state of queued jobs so the jobs pending when
+// a JobQueue stops are run again when it starts.
+type Journal interface {
+	// Append records the current state of job.
+	Append(job Job) error
+	// Pending returns the jobs not finished, in the order they were
+	// first recorded.
+	Pending() ([]Job, error)
+	Close() error
+}
+
+// JournalCompactAfter is the number of jobs finished after which a
+// FileJournal is compacted while in use.
+const JournalCompactAfter = 1000
+
+// FileJournal is a Journal appending one JSON line per state change
+// to a file readable by its owner only, payloads holding recipients
+// and credentials. The file is compacted to the pending jobs when
+// opened and after JournalCompactAfter jobs finished.
+type FileJournal struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	finished int // Jobs finished since the last compaction.
+}
+
+// DefaultJournalPath returns the journal file of the queue name in
+// the user cache directory, or the temporary directory if unknown.
+func DefaultJournalPath(name string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "oarkflow-protocol", "queue", name+".journal")
+}
+
+// OpenFileJournal opens or creates the journal at path.
+func OpenFileJournal(path string) (*FileJournal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	j := &FileJournal{path: path}
+	pending, err := j.read()
+	if err != nil {
+		return nil, err
+	}
+	if err = j.compact(pending); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *FileJournal) Append(job Job) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err = j.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	if job.Status.Done() {
+		j.finished++
+	}
+	if j.finished < JournalCompactAfter {
+		return nil
+	}
+	pending, err := j.read()
+	if err != nil {
+		return err
+	}
+	return j.compact(pending)
+}
+
+func (j *FileJournal) Pending() ([]Job, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.read()
+}
+
+func (j *FileJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
+
+// read returns the pending jobs of the file. A truncated last line,
+// left by a crash while writing, is ignored.
+func (j *FileJournal) read() ([]Job, error) {
+	f, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var order []string
+	jobs := make(map[string]Job)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for sc.Scan() {
+		var job Job
+		if json.Unmarshal(sc.Bytes(), &job) != nil {
+			continue
+		}
+		if _, ok := jobs[job.ID]; !ok {
+			order = append(order, job.ID)
+		}
+		jobs[job.ID] = job
+	}
+	if err = sc.Err(); err != nil {
+		return nil, err
+	}
+	var pending []Job
+	for _, id := range order {
+		if job := jobs[id]; !job.Status.Done() {
+			pending = append(pending, job)
+		}
+	}
+	return pending, nil
+}
+
+// compact rewrites the file with the pending jobs only and opens it
+// for appending.
+func (j *FileJournal) compact(pending []Job) error {
+	tmp := j.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, job := range pending {
+		if err = enc.Encode(job); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err = w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, j.path); err != nil {
+		return err
+	}
+	if j.file != nil {
+		j.file.Close()
+	}
+	j.file, err = os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	j.finished = 0
+	return err
+}
+
+// memoryJournal is used when persistence is disabled.
+type memoryJournal struct{}
+
+func (memoryJournal) Append(Job) error        { return nil }
+func (memoryJournal) Pending() ([]Job, error) { return nil, nil }
+func (memoryJournal) Close() error            { return nil }
@@ -0,0 +1,45 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/oarkflow/errors"
+
+	"github.com/oarkflow/protocol/i18n"
+)
+
+var ErrNoCatalog = errors.New("No template catalog configured")
+
+// Localize replaces the content of the payload with its Template in
+// catalog, looked up along the fallback chain of its Locale and
+// rendered with its Data. The locale found becomes its Locale and the
+// request structure rendered replaces its Data, as with Prepare. The
+// template is returned for its View, rendered by SMTP.
+func (p *Payload) Localize(catalog *i18n.Catalog) (i18n.Template, error) {
+	if catalog == nil {
+		return i18n.Template{}, ErrNoCatalog
+	}
+	t, locale, err := catalog.Lookup(p.Template, p.Locale)
+	if err != nil {
+		return t, err
+	}
+	if t, err = t.Render(locale, p.Data); err != nil {
+		return t, fmt.Errorf("template %s: %w", p.Template, err)
+	}
+	if t.RequestStructure != "" {
+		var data map[string]any
+		if err = json.Unmarshal([]byte(t.RequestStructure), &data); err != nil {
+			return t, err
+		}
+		p.RequestStructure, p.Data = t.RequestStructure, data
+	}
+	if t.Subject != "" {
+		p.Subject = t.Subject
+	}
+	if t.Message != "" {
+		p.Message = t.Message
+	}
+	p.Locale = locale
+	return t, nil
+}
@@ -0,0 +1,285 @@
+package protocol
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/errors"
+
+	"github.com/oarkflow/protocol/address"
+	"github.com/oarkflow/protocol/smtp"
+)
+
+// EmailPayload is an email sent by SMTP.
+type EmailPayload struct {
+	ID          string            `json:"id"`
+	From        string            `json:"from"`
+	FromName    string            `json:"from_name"`
+	To          []string          `json:"to"`
+	Cc          []string          `json:"cc,omitempty"`
+	Bcc         []string          `json:"bcc,omitempty"`
+	ReplyTo     string            `json:"reply_to,omitempty"`
+	Subject     string            `json:"subject"`
+	Message     string            `json:"message"`
+	Text        string            `json:"text,omitempty"`
+	Attachments []smtp.Attachment `json:"attachments,omitempty"`
+	UserID      any               `json:"user_id,omitempty"`
+	Data        map[string]any    `json:"data,omitempty"`
+	CallbackURL string            `json:"callback_url,omitempty"`
+	Template    string            `json:"template,omitempty"`
+	Locale      string            `json:"locale,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// Validate checks the email has a recipient and valid addresses.
+func (p EmailPayload) Validate() error {
+	if len(p.To) == 0 {
+		return errors.New("Email has no recipient")
+	}
+	if p.From != "" {
+		if err := validAddress("from", p.From); err != nil {
+			return err
+		}
+	}
+	if p.ReplyTo != "" {
+		if err := validAddress("reply_to", p.ReplyTo); err != nil {
+			return err
+		}
+	}
+	for i, addrs := range [][]string{p.To, p.Cc, p.Bcc} {
+		for _, addr := range addrs {
+			if err := validAddress([]string{"to", "cc", "bcc"}[i], addr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Payload returns the flat payload of the email, the recipient lists
+// being joined with commas.
+func (p EmailPayload) Payload() Payload {
+	return Payload{
+		ID:          p.ID,
+		Type:        Smtp,
+		From:        p.From,
+		FromName:    p.FromName,
+		To:          strings.Join(p.To, ", "),
+		Cc:          strings.Join(p.Cc, ", "),
+		Bcc:         strings.Join(p.Bcc, ", "),
+		ReplyTo:     p.ReplyTo,
+		Subject:     p.Subject,
+		Message:     p.Message,
+		Text:        p.Text,
+		Attachments: p.Attachments,
+		UserID:      p.UserID,
+		Data:        p.Data,
+		CallbackURL: p.CallbackURL,
+		Template:    p.Template,
+		Locale:      p.Locale,
+		CreatedAt:   p.CreatedAt,
+	}
+}
+
+// SMSPayload is a text message sent by SMPP.
+type SMSPayload struct {
+	ID          string         `json:"id"`
+	From        string         `json:"from"`
+	To          []string       `json:"to"`
+	Message     string         `json:"message"`
+	UserID      any            `json:"user_id,omitempty"`
+	Data        map[string]any `json:"data,omitempty"`
+	CallbackURL string         `json:"callback_url,omitempty"`
+	Template    string         `json:"template,omitempty"`
+	Locale      string         `json:"locale,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// Validate checks the message has a recipient and a text or a
+// template. Addresses are checked when sent, see smpp.Manager.
+func (p SMSPayload) Validate() error {
+	if len(p.To) == 0 {
+		return errors.New("SMS has no recipient")
+	}
+	for _, to := range p.To {
+		if strings.TrimSpace(to) == "" {
+			return errors.New("SMS has an empty recipient")
+		}
+	}
+	if p.Message == "" && p.Template == "" {
+		return errors.New("SMS has no message")
+	}
+	return nil
+}
+
+// Payload returns the flat payload of the message, the recipients
+// being joined with commas.
+func (p SMSPayload) Payload() Payload {
+	return Payload{
+		ID:          p.ID,
+		Type:        Smpp,
+		From:        p.From,
+		To:          strings.Join(p.To, ","),
+		Message:     p.Message,
+		UserID:      p.UserID,
+		Data:        p.Data,
+		CallbackURL: p.CallbackURL,
+		Template:    p.Template,
+		Locale:      p.Locale,
+		CreatedAt:   p.CreatedAt,
+	}
+}
+
+// WebhookPayload is a request sent by HTTP.
+type WebhookPayload struct {
+	ID               string            `json:"id"`
+	URL              string            `json:"url"`
+	Method           string            `json:"method"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	Data             map[string]any    `json:"data,omitempty"`
+	RequestStructure string            `json:"request_structure,omitempty"`
+	UserID           any               `json:"user_id,omitempty"`
+	CallbackURL      string            `json:"callback_url,omitempty"`
+	Template         string            `json:"template,omitempty"`
+	Locale           string            `json:"locale,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+}
+
+var webhookMethods = []string{"", "GET", "POST", "PUT", "HEAD", "FORM"}
+
+// Validate checks the URL, if set, is absolute and the method is
+// supported by HTTP.
+func (p WebhookPayload) Validate() error {
+	if p.URL != "" {
+		u, err := url.Parse(p.URL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.New(fmt.Sprintf("Invalid webhook url %q", p.URL))
+		}
+	}
+	method := strings.ToUpper(p.Method)
+	for _, m := range webhookMethods {
+		if method == m {
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("Unsupported webhook method %q", p.Method))
+}
+
+// Payload returns the flat payload of the request.
+func (p WebhookPayload) Payload() Payload {
+	return Payload{
+		ID:               p.ID,
+		Type:             Http,
+		URL:              p.URL,
+		Method:           p.Method,
+		Headers:          p.Headers,
+		Data:             p.Data,
+		RequestStructure: p.RequestStructure,
+		UserID:           p.UserID,
+		CallbackURL:      p.CallbackURL,
+		Template:         p.Template,
+		Locale:           p.Locale,
+		CreatedAt:        p.CreatedAt,
+	}
+}
+
+// Email returns the email of the payload. To, Cc and Bcc may hold
+// several addresses separated by commas or semicolons.
+func (p Payload) Email() EmailPayload {
+	return EmailPayload{
+		ID:          p.ID,
+		From:        p.From,
+		FromName:    p.FromName,
+		To:          addressList(p.To),
+		Cc:          addressList(p.Cc),
+		Bcc:         addressList(p.Bcc),
+		ReplyTo:     p.ReplyTo,
+		Subject:     p.Subject,
+		Message:     p.Message,
+		Text:        p.Text,
+		Attachments: p.Attachments,
+		UserID:      p.UserID,
+		Data:        p.Data,
+		CallbackURL: p.CallbackURL,
+		Template:    p.Template,
+		Locale:      p.Locale,
+		CreatedAt:   p.CreatedAt,
+	}
+}
+
+// SMS returns the text message of the payload. To may hold several
+// numbers separated by commas or semicolons.
+func (p Payload) SMS() SMSPayload {
+	return SMSPayload{
+		ID:          p.ID,
+		From:        p.From,
+		To:          splitList(p.To),
+		Message:     p.Message,
+		UserID:      p.UserID,
+		Data:        p.Data,
+		CallbackURL: p.CallbackURL,
+		Template:    p.Template,
+		Locale:      p.Locale,
+		CreatedAt:   p.CreatedAt,
+	}
+}
+
+// Webhook returns the request of the payload.
+func (p Payload) Webhook() WebhookPayload {
+	return WebhookPayload{
+		ID:               p.ID,
+		URL:              p.URL,
+		Method:           p.Method,
+		Headers:          p.Headers,
+		Data:             p.Data,
+		RequestStructure: p.RequestStructure,
+		UserID:           p.UserID,
+		CallbackURL:      p.CallbackURL,
+		Template:         p.Template,
+		Locale:           p.Locale,
+		CreatedAt:        p.CreatedAt,
+	}
+}
+
+func validAddress(field, addr string) error {
+	_, err := address.Parse(addr)
+	return addressField(field, err)
+}
+
+// addressField sets the field of err if it is an *address.Error.
+func addressField(field string, err error) error {
+	var e *address.Error
+	if errors.As(err, &e) {
+		e.Field = field
+	}
+	return err
+}
+
+// addressList splits a list of email addresses, keeping display names
+// containing commas.
+func addressList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	if list, err := mail.ParseAddressList(strings.ReplaceAll(s, ";", ",")); err == nil {
+		addrs := make([]string, len(list))
+		for i, addr := range list {
+			addrs[i] = addr.String()
+		}
+		return addrs
+	}
+	return splitList(s)
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,141 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/oarkflow/protocol/i18n"
+)
+
+func TestPayloadEmail(t *testing.T) {
+	var p Payload
+	err := json.Unmarshal([]byte(`{
+		"from": "noreply@example.com",
+		"to": "\"Doe, Jane\" <jane@example.com>; john@example.com",
+		"cc": "ops@example.com",
+		"subject": "Hello",
+		"message": "<p>Hi</p>"
+	}`), &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := p.Email()
+	if want := []string{`"Doe, Jane" <jane@example.com>`, "<john@example.com>"}; !reflect.DeepEqual(email.To, want) {
+		t.Fatalf("want recipients %q, have %q", want, email.To)
+	}
+	if len(email.Cc) != 1 || email.Bcc != nil {
+		t.Fatalf("want one cc and no bcc, have %q and %q", email.Cc, email.Bcc)
+	}
+	if err = email.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if back := email.Payload().Email(); !reflect.DeepEqual(back.To, email.To) || back.Subject != "Hello" {
+		t.Fatalf("want email unchanged by adapter, have %+v", back)
+	}
+
+	for _, bad := range []EmailPayload{
+		{},
+		{To: []string{"jane"}},
+		{To: []string{"jane@example.com"}, Bcc: []string{"@example.com"}},
+		{To: []string{"jane@example.com"}, ReplyTo: "nobody"},
+	} {
+		if bad.Validate() == nil {
+			t.Errorf("want error for %+v", bad)
+		}
+	}
+}
+
+func TestPayloadSMSAndWebhook(t *testing.T) {
+	sms := Payload{To: "9779812345678, 9779812345679", Message: "Hi"}.SMS()
+	if len(sms.To) != 2 || sms.Validate() != nil {
+		t.Fatalf("want two valid recipients, have %+v", sms)
+	}
+	if (SMSPayload{To: []string{"1"}}).Validate() == nil {
+		t.Fatal("want error for empty message")
+	}
+
+	webhook := Payload{URL: "https://example.com/hook", Method: "post"}.Webhook()
+	if err := webhook.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if (WebhookPayload{URL: "/hook"}).Validate() == nil {
+		t.Fatal("want error for relative url")
+	}
+	if (WebhookPayload{Method: "DELETE"}).Validate() == nil {
+		t.Fatal("want error for unsupported method")
+	}
+}
+
+func TestPayloadPrepare(t *testing.T) {
+	p := Payload{
+		Data:             map[string]any{"name": `Jane "JD"`, "age": 30},
+		RequestStructure: `{"name": "{{name}}", "age": {{age}}}`,
+	}
+	if err := p.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	if p.Data["name"] != `Jane "JD"` || p.Data["age"] != float64(30) {
+		t.Fatalf("want escaped values, have %v", p.Data)
+	}
+
+	p = Payload{Data: map[string]any{"n": 2}, Message: "{{if n == 1}}1 item{{else}}{{n}} items{{end}}"}
+	if err := p.Prepare(); err != nil || p.Message != "2 items" {
+		t.Fatalf("want rendered message, have %q, %v", p.Message, err)
+	}
+}
+
+func TestPayloadLocalize(t *testing.T) {
+	catalog := i18n.NewCatalog("en")
+	catalog.Add("receipt", "en", i18n.Template{Message: "Paid {{ amount | number 2 }}"})
+	catalog.Add("receipt", "de", i18n.Template{
+		Message:          "Bezahlt {{ amount | number 2 }}",
+		RequestStructure: `{"text": "{{ note }}", "amount": {{ amount }}}`,
+	})
+
+	p := Payload{Template: "receipt", Locale: "de-CH", Data: map[string]any{"amount": 1500, "note": `"quoted"`}}
+	if _, err := p.Localize(catalog); err != nil {
+		t.Fatal(err)
+	}
+	if p.Locale != "de" || p.Message != "Bezahlt 1.500,00" {
+		t.Fatalf("want german message, have %q in %s", p.Message, p.Locale)
+	}
+	if p.Data["text"] != `"quoted"` || p.Data["amount"] != float64(1500) {
+		t.Fatalf("want data from request structure, have %v", p.Data)
+	}
+
+	p = Payload{Template: "receipt", Locale: "ja", Data: map[string]any{"amount": 2}}
+	if _, err := p.Localize(catalog); err != nil || p.Message != "Paid 2.00" {
+		t.Fatalf("want default locale, have %q, %v", p.Message, err)
+	}
+	if _, err := p.Localize(nil); !errors.Is(err, ErrNoCatalog) {
+		t.Fatalf("want ErrNoCatalog, have %v", err)
+	}
+}
+
+func TestPayloadPrepareFallback(t *testing.T) {
+	// Invalid templates have their known placeholders replaced only.
+	p := Payload{Data: map[string]any{"name": "Jane"}, Message: "Hi {{name}}, {{ unknown key }} {{"}
+	if err := p.Prepare(); err != nil || p.Message != "Hi Jane, {{ unknown key }} {{" {
+		t.Fatalf("want placeholders replaced, have %q, %v", p.Message, err)
+	}
+	// Replaced values are escaped as rendered ones.
+	p = Payload{
+		Data:             map[string]any{"name": `He said "hi"`, "first name": "Jane"},
+		RequestStructure: `{"a":"{{name}}","b":"{{ first name }}","c":"{{first name}}"}`,
+	}
+	if err := p.Prepare(); err != nil || p.Data["a"] != `He said "hi"` || p.Data["c"] != "Jane" {
+		t.Fatalf("want values JSON escaped, have %v, %v", p.Data, err)
+	}
+	// Errors of valid templates are returned.
+	p = Payload{Data: map[string]any{"n": "Jane"}, Message: "{{ n | truncate }}"}
+	if err := p.Prepare(); err == nil {
+		t.Fatalf("want truncate error, have %q", p.Message)
+	}
+	// Valid templates render missing values as nothing.
+	p = Payload{Data: map[string]any{"name": "Jane"}, Message: "Hi {{name}}{{title}}"}
+	if err := p.Prepare(); err != nil || p.Message != "Hi Jane" {
+		t.Fatalf("want missing value empty, have %q, %v", p.Message, err)
+	}
+}
@@ -22,13 +22,18 @@ const (
 
 type Payload struct {
 	ID               string            `json:"id"`
+	Type             Type              `json:"type,omitempty"`    // Used by Registry to route the payload.
+	Service          string            `json:"service,omitempty"` // Name of the Registry service to use, routed if empty.
 	From             string            `json:"from"`
 	FromName         string            `json:"from_name"`
-	To               string            `json:"to"`
+	To               string            `json:"to"` // Several recipients may be separated by commas, see Email and SMS.
 	UserID           any               `json:"user_id"`
 	Message          string            `json:"message"`
+	Text             string            `json:"text,omitempty"` // Plain text alternative of an HTML email Message, generated if empty.
 	Subject          string            `json:"subject"`
 	Cc               string            `json:"cc"`
+	Bcc              string            `json:"bcc,omitempty"`
+	ReplyTo          string            `json:"reply_to,omitempty"`
 	Query            string            `json:"query"`
 	Attachments      []smtp.Attachment `json:"attachments"`
 	CallbackURL      string            `json:"callback_url"`
@@ -36,6 +41,8 @@ type Payload struct {
 	Method           string            `json:"method"`
 	RequestStructure string            `json:"request_structure"`
 	Data             map[string]any    `json:"data"`
+	Template         string            `json:"template,omitempty"` // Name of the catalog template of the content, see Localize.
+	Locale           string            `json:"locale,omitempty"`   // Locale of the content, e.g. "pt-BR".
 	Headers          map[string]string `json:"headers"`
 	CreatedAt        time.Time         `json:"created_at"`
 	SentAt           time.Time         `json:"sent_at"`
@@ -43,6 +50,11 @@ type Payload struct {
 	FailedAt         time.Time         `json:"failed_at"`
 }
 
+// Prepare renders RequestStructure, or else Message, with Data, see
+// template.Compiled. Missing values render as nothing. Text which is
+// not a valid template, e.g. with stray braces, has its {{key}}
+// placeholders replaced as before, unknown ones being kept, see
+// template.Template.Fill.
 func (p *Payload) Prepare() (err error) {
 	if p.Data == nil && p.RequestStructure != "" {
 		err = json.Unmarshal([]byte(p.RequestStructure), &p.Data)
@@ -51,16 +63,20 @@ func (p *Payload) Prepare() (err error) {
 		}
 	} else if p.Data != nil && p.RequestStructure != "" {
 		var data map[string]any
-		tmp := template.New(p.RequestStructure, "", "")
-		p.RequestStructure = tmp.Parse(p.Data)
+		// Values are JSON escaped so quotes in them keep the JSON valid.
+		tmpl := template.New(p.RequestStructure, "", "")
+		tmpl.Escape = template.JSON
+		p.RequestStructure, err = tmpl.Fill(p.Data)
+		if err != nil {
+			return
+		}
 		err = json.Unmarshal([]byte(p.RequestStructure), &data)
 		if err != nil {
 			return
 		}
 		p.Data = data
 	} else if p.Data != nil && p.Message != "" {
-		tmp := template.New(p.Message, "", "")
-		p.Message = tmp.Parse(p.Data)
+		p.Message, err = template.New(p.Message, "", "").Fill(p.Data)
 	}
 	return
 }
@@ -71,13 +87,25 @@ type Service interface {
 	Setup() error
 	GetType() Type
 	GetServiceType() string
+	// SetService sets the service handling payloads this one fails
+	// to handle, nil removes it.
 	SetService(service Service)
 	Handle(payload Payload) (Response, error)
 	Queue(payload Payload) (Response, error)
 }
 
 func NewSMTP(config smtp.Config, engine *render.HtmlEngine, serviceType string) (*SMTP, error) {
-	return &SMTP{mailer: smtp.New(config, engine), Config: config, Service: serviceType}, nil
+	return NewSMTPProviders([]smtp.Provider{{Config: config}}, engine, serviceType)
+}
+
+// NewSMTPProviders returns an SMTP service sending over several
+// providers, see smtp.Providers. Config is the config of the first.
+func NewSMTPProviders(providers []smtp.Provider, engine *render.HtmlEngine, serviceType string) (*SMTP, error) {
+	p, err := smtp.NewProviders(providers, engine)
+	if err != nil {
+		return nil, err
+	}
+	return &SMTP{mailer: p.Mailer(), providers: p, Config: providers[0].Config, Service: serviceType}, nil
 }
 
 func NewHTTP(config *http.Options, serviceType string) (*HTTP, error) {
@@ -85,9 +113,25 @@ func NewHTTP(config *http.Options, serviceType string) (*HTTP, error) {
 }
 
 func NewSMPP(config smpp.Setting, serviceType string) (*SMPP, error) {
+	s := &SMPP{Config: config, Service: serviceType}
+	onReceived := config.OnMessageReceived
+	config.OnMessageReceived = func(manager *smpp.Manager, msg *smpp.Inbound) {
+		s.receive(msg)
+		if onReceived != nil {
+			onReceived(manager, msg)
+		}
+	}
+	onReport := config.OnMessageReport
+	config.OnMessageReport = func(manager *smpp.Manager, sms *smpp.Message, parts []*smpp.Part) {
+		s.report(sms, parts)
+		if onReport != nil {
+			onReport(manager, sms, parts)
+		}
+	}
 	manager, err := smpp.NewManager(config)
 	if err != nil {
 		return nil, err
 	}
-	return &SMPP{manager: manager, Config: config, Service: serviceType}, nil
+	s.manager = manager
+	return s, nil
 }
@@ -0,0 +1,440 @@
+package protocol
+
+import (
+	"sync"
+	"time"
+
+	"github.com/oarkflow/errors"
+	"github.com/oarkflow/log"
+
+	"github.com/oarkflow/protocol/utils/maps"
+	"github.com/oarkflow/protocol/utils/xid"
+)
+
+var (
+	ErrQueueFull   = errors.New("Queue is full")
+	ErrQueueClosed = errors.New("Queue is closed")
+)
+
+// JobStatus is the state of a queued payload.
+type JobStatus string
+
+const (
+	JobQueued     JobStatus = "queued"
+	JobProcessing JobStatus = "processing"
+	JobSent       JobStatus = "sent"
+	JobDelivered  JobStatus = "delivered"
+	JobFailed     JobStatus = "failed"
+)
+
+// Done reports whether the job is finished, i.e. it was handled even
+// if a delivery report may still update it.
+func (s JobStatus) Done() bool {
+	return s == JobSent || s == JobDelivered || s == JobFailed
+}
+
+// Job is a payload queued on a service.
+type Job struct {
+	ID        string    `json:"id"`
+	Queue     string    `json:"queue"`
+	Payload   Payload   `json:"payload"`
+	Status    JobStatus `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Response  Response  `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// QueueOptions configures the worker pool of a service.
+type QueueOptions struct {
+	// Name of the queue and of its journal file, the type and service
+	// type of the service by default. Services sharing both must set
+	// distinct names.
+	Name        string
+	Concurrency int           // Number of workers, default 4.
+	Size        int           // Maximum number of pending jobs, default 1000.
+	Retention   time.Duration // How long finished jobs can be looked up, default 1h.
+	// Journal persists the jobs, a FileJournal at JournalPath by
+	// default. Set DisableJournal to keep the jobs in memory only.
+	Journal        Journal
+	JournalPath    string // Default DefaultJournalPath of the queue name.
+	DisableJournal bool
+	// OnComplete is called when a job is finished, with its payload
+	// timestamps set, and again when a delivery report updates it.
+	OnComplete func(job Job)
+}
+
+// JobQueue runs the payloads queued on a service in a bounded worker
+// pool. Jobs are run at least once: those pending or processing when
+// the queue stops are run again when a queue with the same journal
+// starts.
+type JobQueue struct {
+	name    string
+	handle  func(payload *Payload) (Response, error)
+	opts    QueueOptions
+	journal Journal
+	jobs    *maps.Map[string, *Job]
+	refs    *maps.Map[string, string] // Payload ID to job ID.
+	// Payloads sent as several messages, see Expect, by payload ID
+	// and by message ID. Guarded by mu.
+	sent     map[string]*sentMessages
+	messages map[string]*sentMessages
+	pending  chan *Job
+	done     chan struct{}
+	wg       sync.WaitGroup
+	mu       sync.RWMutex
+	closed   bool
+}
+
+// NewJobQueue starts a queue named name running handle. handle may
+// set the timestamps of the payload, SentAt or FailedAt are set
+// otherwise.
+func NewJobQueue(name string, handle func(payload *Payload) (Response, error), opts QueueOptions) (*JobQueue, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	if opts.Size <= 0 {
+		opts.Size = 1000
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = time.Hour
+	}
+	journal := opts.Journal
+	switch {
+	case journal != nil:
+	case opts.DisableJournal:
+		journal = memoryJournal{}
+	default:
+		path := opts.JournalPath
+		if path == "" {
+			path = DefaultJournalPath(name)
+		}
+		j, err := OpenFileJournal(path)
+		if err != nil {
+			return nil, errors.NewE(err, "Unable to open queue journal", "queue:new")
+		}
+		journal = j
+	}
+	pending, err := journal.Pending()
+	if err != nil {
+		journal.Close()
+		return nil, errors.NewE(err, "Unable to read queue journal", "queue:new")
+	}
+	q := &JobQueue{
+		name:     name,
+		handle:   handle,
+		opts:     opts,
+		journal:  journal,
+		jobs:     maps.New[string, *Job](),
+		refs:     maps.New[string, string](),
+		sent:     make(map[string]*sentMessages),
+		messages: make(map[string]*sentMessages),
+		pending:  make(chan *Job, max(opts.Size, len(pending))),
+		done:     make(chan struct{}),
+	}
+	for i := range pending {
+		job := &pending[i]
+		job.Status = JobQueued
+		q.jobs.Set(job.ID, job)
+		q.refs.Set(job.Payload.ID, job.ID)
+		q.pending <- job
+	}
+	if len(pending) > 0 {
+		log.Info().Str("queue", name).Int("jobs", len(pending)).Msg("Resuming queued jobs")
+	}
+	q.wg.Add(opts.Concurrency)
+	for i := 0; i < opts.Concurrency; i++ {
+		go q.work()
+	}
+	return q, nil
+}
+
+// Enqueue queues payload and returns the job ID. The payload ID is
+// set to the job ID if empty.
+func (q *JobQueue) Enqueue(payload Payload) (string, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return "", ErrQueueClosed
+	}
+	now := time.Now()
+	job := &Job{ID: xid.New().String(), Queue: q.name, Payload: payload, Status: JobQueued, CreatedAt: now, UpdatedAt: now}
+	if job.Payload.ID == "" {
+		job.Payload.ID = job.ID
+	}
+	if job.Payload.CreatedAt.IsZero() {
+		job.Payload.CreatedAt = now
+	}
+	if len(q.pending) == cap(q.pending) {
+		return "", ErrQueueFull
+	}
+	if err := q.journal.Append(*job); err != nil {
+		return "", errors.NewE(err, "Unable to journal job", "queue:enqueue")
+	}
+	q.jobs.Set(job.ID, job)
+	q.refs.Set(job.Payload.ID, job.ID)
+	select {
+	case q.pending <- job:
+		return job.ID, nil
+	default:
+		q.jobs.Del(job.ID)
+		q.refs.Del(job.Payload.ID)
+		job.Status, job.Error = JobFailed, ErrQueueFull.Error()
+		q.journal.Append(*job)
+		return "", ErrQueueFull
+	}
+}
+
+// Get returns a copy of the job with the given ID. Finished jobs are
+// kept for the retention period of the queue.
+func (q *JobQueue) Get(id string) (Job, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	job, ok := q.jobs.Get(id)
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// sentMessages are the messages a payload was sent as.
+type sentMessages struct {
+	payloadID   string
+	ids         []string
+	undelivered int
+	failed      bool
+	reason      string
+}
+
+// Expect registers the IDs of the messages a payload is sent as, e.g.
+// one per recipient, before sending them. Their delivery reports then
+// update its job: delivered once all are, failed on the first failure.
+func (q *JobQueue) Expect(payloadID string, messageIDs ...string) {
+	m := &sentMessages{payloadID: payloadID, ids: messageIDs, undelivered: len(messageIDs)}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.sent[payloadID] = m
+	for _, id := range messageIDs {
+		q.messages[id] = m
+	}
+}
+
+// Report updates the job of the payload or message with the given ID
+// from a delivery report, status being JobDelivered or JobFailed, see
+// Expect. It reports whether the job was found.
+func (q *JobQueue) Report(payloadID string, status JobStatus, reason string) bool {
+	// The job of several messages is updated by the first failure or
+	// the last delivery.
+	skip := false
+	q.mu.Lock()
+	if m, ok := q.messages[payloadID]; ok {
+		delete(q.messages, payloadID)
+		payloadID = m.payloadID
+		switch {
+		case m.failed:
+			skip = true
+		case status == JobFailed:
+			m.failed, m.reason = true, reason
+		default:
+			m.undelivered--
+			skip = m.undelivered > 0
+		}
+	}
+	q.mu.Unlock()
+	if skip {
+		return true
+	}
+	id, ok := q.refs.Get(payloadID)
+	if !ok {
+		return false
+	}
+	job, ok := q.jobs.Get(id)
+	if !ok {
+		return false
+	}
+	q.mu.RLock()
+	processing := job.Status == JobProcessing
+	q.mu.RUnlock()
+	// Submit failures are recorded when the handler returns.
+	if processing && status == JobFailed {
+		return true
+	}
+	now := time.Now()
+	done := q.update(job, func(job *Job) {
+		job.Status = status
+		switch status {
+		case JobDelivered:
+			job.Payload.DeliveredAt = now
+		case JobFailed:
+			job.Error = reason
+			job.Payload.FailedAt = now
+		}
+	})
+	if q.opts.OnComplete != nil {
+		q.opts.OnComplete(done)
+	}
+	return true
+}
+
+// Len returns the number of jobs waiting for a worker.
+func (q *JobQueue) Len() int {
+	return len(q.pending)
+}
+
+// Close stops accepting jobs and waits for the running ones. Jobs
+// still waiting stay in the journal.
+func (q *JobQueue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	close(q.done)
+	q.mu.Unlock()
+	q.wg.Wait()
+	return q.journal.Close()
+}
+
+func (q *JobQueue) work() {
+	defer q.wg.Done()
+	for {
+		// Stopping takes precedence over pending jobs.
+		select {
+		case <-q.done:
+			return
+		default:
+		}
+		select {
+		case <-q.done:
+			return
+		case job := <-q.pending:
+			q.run(job)
+		}
+	}
+}
+
+func (q *JobQueue) run(job *Job) {
+	q.update(job, func(job *Job) {
+		job.Status = JobProcessing
+	})
+	payload := job.Payload
+	res, err := q.handle(&payload)
+	now := time.Now()
+	done := q.update(job, func(job *Job) {
+		job.Response = res
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+			if payload.FailedAt.IsZero() {
+				payload.FailedAt = now
+			}
+		} else {
+			if payload.SentAt.IsZero() {
+				payload.SentAt = now
+			}
+			// A delivery report may have been received already.
+			if m := q.sent[payload.ID]; m != nil && m.failed {
+				job.Status, job.Error = JobFailed, m.reason
+				if payload.FailedAt.IsZero() {
+					payload.FailedAt = now
+				}
+			} else if job.Status == JobProcessing {
+				job.Status = JobSent
+			} else if payload.DeliveredAt.IsZero() && payload.FailedAt.IsZero() {
+				payload.DeliveredAt, payload.FailedAt = job.Payload.DeliveredAt, job.Payload.FailedAt
+			}
+		}
+		job.Payload = payload
+	})
+	if err != nil {
+		log.Error().Err(err).Str("queue", q.name).Str("job_id", job.ID).Msg("Unable to handle queued payload")
+	}
+	time.AfterFunc(q.opts.Retention, func() {
+		q.jobs.Del(job.ID)
+		q.refs.Del(done.Payload.ID)
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if m := q.sent[done.Payload.ID]; m != nil {
+			delete(q.sent, done.Payload.ID)
+			for _, id := range m.ids {
+				delete(q.messages, id)
+			}
+		}
+	})
+	if q.opts.OnComplete != nil {
+		q.opts.OnComplete(done)
+	}
+}
+
+// update changes job under the queue lock, journals it and returns a
+// copy.
+func (q *JobQueue) update(job *Job, f func(job *Job)) Job {
+	q.mu.Lock()
+	f(job)
+	job.UpdatedAt = time.Now()
+	snapshot := *job
+	q.mu.Unlock()
+	if err := q.journal.Append(snapshot); err != nil {
+		log.Error().Err(err).Str("queue", q.name).Str("job_id", job.ID).Msg("Unable to journal job")
+	}
+	return snapshot
+}
+
+// serviceQueue is the JobQueue of a service, started on first use.
+type serviceQueue struct {
+	mu   sync.Mutex
+	jobs *JobQueue
+}
+
+func (q *serviceQueue) get(s Service, handle func(payload *Payload) (Response, error), opts QueueOptions) (*JobQueue, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.jobs != nil {
+		return q.jobs, nil
+	}
+	if opts.Name == "" {
+		opts.Name = string(s.GetType())
+		if st := s.GetServiceType(); st != "" {
+			opts.Name += "-" + st
+		}
+	}
+	jobs, err := NewJobQueue(opts.Name, handle, opts)
+	if err != nil {
+		return nil, err
+	}
+	q.jobs = jobs
+	return jobs, nil
+}
+
+// started returns the queue if it was started.
+func (q *serviceQueue) started() *JobQueue {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.jobs
+}
+
+// close closes the queue if it was started.
+func (q *serviceQueue) close() error {
+	if jobs := q.started(); jobs != nil {
+		return jobs.Close()
+	}
+	return nil
+}
+
+func (q *serviceQueue) enqueue(s Service, handle func(payload *Payload) (Response, error), opts QueueOptions, callbacks *CallbackDispatcher, payload Payload) (Response, error) {
+	jobs, err := q.get(s, handle, opts)
+	if err != nil {
+		return nil, err
+	}
+	id, err := jobs.Enqueue(payload)
+	if err != nil {
+		return nil, err
+	}
+	if job, ok := jobs.Get(id); ok && payload.CallbackURL != "" {
+		cb := newCallback(s, job.Payload, EventQueued)
+		cb.JobID = id
+		callbacks.Dispatch(payload.CallbackURL, cb)
+	}
+	return id, nil
+}
@@ -0,0 +1,185 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJobQueue(t *testing.T) {
+	completed := make(chan Job, 4)
+	q, err := NewJobQueue("test", func(p *Payload) (Response, error) {
+		if p.To == "fail" {
+			return nil, errors.New("rejected")
+		}
+		return "ok", nil
+	}, QueueOptions{DisableJournal: true, OnComplete: func(job Job) { completed <- job }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	id, err := q.Enqueue(Payload{ID: "p1", To: "jane"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := <-completed
+	if job.ID != id || job.Status != JobSent || job.Payload.SentAt.IsZero() || job.Response != "ok" {
+		t.Fatalf("want sent job, have %+v", job)
+	}
+	if !q.Report("p1", JobDelivered, "") {
+		t.Fatal("want job found by payload ID")
+	}
+	if job = <-completed; job.Status != JobDelivered || job.Payload.DeliveredAt.IsZero() {
+		t.Fatalf("want delivered job, have %+v", job)
+	}
+
+	q.Enqueue(Payload{To: "fail"})
+	if job = <-completed; job.Status != JobFailed || job.Error != "rejected" || job.Payload.FailedAt.IsZero() {
+		t.Fatalf("want failed job, have %+v", job)
+	}
+	if job, ok := q.Get(job.ID); !ok || job.Status != JobFailed {
+		t.Fatalf("want failed job tracked, have %+v", job)
+	}
+}
+
+func TestJobQueueJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.journal")
+	block := make(chan struct{})
+	q, err := NewJobQueue("test", func(p *Payload) (Response, error) {
+		<-block
+		return nil, nil
+	}, QueueOptions{Concurrency: 1, Size: 2, JournalPath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := q.Enqueue(Payload{To: "1"})
+	waitStatus(t, q, first, JobProcessing)
+	second, _ := q.Enqueue(Payload{To: "2"})
+	if _, err = q.Enqueue(Payload{To: "3"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = q.Enqueue(Payload{To: "4"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("want ErrQueueFull, have %v", err)
+	}
+	// The second job runs while closing, the third stays pending.
+	block <- struct{}{}
+	waitStatus(t, q, second, JobProcessing)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(block)
+	}()
+	q.Close()
+
+	j, err := OpenFileJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err := j.Pending()
+	j.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Payload.To != "3" {
+		t.Fatalf("want job 3 pending, have %+v", pending)
+	}
+
+	done := make(chan Job, 1)
+	q, err = NewJobQueue("test", func(p *Payload) (Response, error) {
+		return nil, nil
+	}, QueueOptions{JournalPath: path, OnComplete: func(job Job) { done <- job }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if job := <-done; job.Payload.To != "3" || job.ID == second {
+		t.Fatalf("want job 3 resumed, have %+v", job)
+	}
+}
+
+func waitStatus(t *testing.T, q *JobQueue, id string, status JobStatus) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		if job, _ := q.Get(id); job.Status == status {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job %s did not reach %s", id, status)
+}
+
+func TestJobQueueMessages(t *testing.T) {
+	completed := make(chan Job, 4)
+	var q *JobQueue
+	q, err := NewJobQueue("test", func(p *Payload) (Response, error) {
+		// Sent as one message per recipient, as by the SMPP service.
+		q.Expect(p.ID, p.ID+"-1", p.ID+"-2")
+		return "ok", nil
+	}, QueueOptions{DisableJournal: true, OnComplete: func(job Job) { completed <- job }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	id, _ := q.Enqueue(Payload{ID: "p1", To: "jane, john"})
+	if job := <-completed; job.Status != JobSent {
+		t.Fatalf("want sent job, have %+v", job)
+	}
+	if !q.Report("p1-1", JobDelivered, "") {
+		t.Fatal("want job found by message ID")
+	}
+	if job, _ := q.Get(id); job.Status != JobSent {
+		t.Fatalf("want job sent until both messages are delivered, have %s", job.Status)
+	}
+	q.Report("p1-2", JobDelivered, "")
+	if job := <-completed; job.Status != JobDelivered || job.Payload.ID != "p1" {
+		t.Fatalf("want delivered job, have %+v", job)
+	}
+
+	q.Enqueue(Payload{ID: "p2", To: "jane, john"})
+	<-completed
+	q.Report("p2-2", JobFailed, "expired")
+	if job := <-completed; job.Status != JobFailed || job.Error != "expired" {
+		t.Fatalf("want failed job, have %+v", job)
+	}
+	q.Report("p2-1", JobDelivered, "")
+	select {
+	case job := <-completed:
+		t.Fatalf("want failed job kept, have %+v", job)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestFileJournalCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue", "test.journal")
+	j, err := OpenFileJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Fatalf("want journal readable by its owner only, have %v, %v", fi.Mode(), err)
+	}
+	if err = j.Append(Job{ID: "pending", Status: JobQueued}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < JournalCompactAfter; i++ {
+		id := fmt.Sprint(i)
+		j.Append(Job{ID: id, Status: JobQueued})
+		j.Append(Job{ID: id, Status: JobSent})
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(b, []byte("\n")); n != 1 {
+		t.Fatalf("want journal compacted to the pending job, have %d lines", n)
+	}
+	if pending, _ := j.Pending(); len(pending) != 1 || pending[0].ID != "pending" {
+		t.Fatalf("want pending job kept, have %+v", pending)
+	}
+}
@@ -0,0 +1,282 @@
+package protocol
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/oarkflow/errors"
+
+	"github.com/oarkflow/protocol/suppression"
+)
+
+var (
+	ErrNoRoute         = errors.New("No service found for payload")
+	ErrServiceNotFound = errors.New("Service not found")
+)
+
+// Rule routes the payloads it matches to the service registered as
+// Service. Type, ServiceType and Match are optional and all the set
+// ones must match.
+type Rule struct {
+	Name        string
+	Type        Type
+	ServiceType string
+	Match       func(payload Payload) bool
+	Service     string
+}
+
+func (r Rule) matches(payload Payload, service Service) bool {
+	if r.Type != "" && r.Type != payload.Type {
+		return false
+	}
+	if r.ServiceType != "" && r.ServiceType != service.GetServiceType() {
+		return false
+	}
+	return r.Match == nil || r.Match(payload)
+}
+
+// Registry holds named services and routes payloads to them.
+//
+// A payload is routed to the service named by its Service field if
+// set, otherwise to the service of the first matching rule, otherwise
+// to the default service of its Type, otherwise to the first service
+// registered with its Type.
+type Registry struct {
+	// Idempotency, if set, returns the original response of payloads
+	// handled or queued again with the same ID.
+	Idempotency *Idempotency
+	mu          sync.RWMutex
+	services    map[string]Service
+	names       []string
+	rules       []Rule
+	defaults    map[Type]string
+	next        map[string]string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		services: make(map[string]Service),
+		defaults: make(map[Type]string),
+		next:     make(map[string]string),
+	}
+}
+
+// Register adds service under name, which must be unique.
+func (r *Registry) Register(name string, service Service) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.services[name]; ok {
+		return fmt.Errorf("service %s already registered", name)
+	}
+	r.services[name] = service
+	r.names = append(r.names, name)
+	return nil
+}
+
+// Unregister removes the service registered as name, the fallback
+// links from and to it and the defaults naming it. Rules naming it
+// are kept but skipped until a service is registered again as name.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.services[name]; !ok {
+		return
+	}
+	delete(r.services, name)
+	for i, n := range r.names {
+		if n == name {
+			r.names = append(r.names[:i], r.names[i+1:]...)
+			break
+		}
+	}
+	delete(r.next, name)
+	for from, to := range r.next {
+		if to == name {
+			r.services[from].SetService(nil)
+			delete(r.next, from)
+		}
+	}
+	for t, n := range r.defaults {
+		if n == name {
+			delete(r.defaults, t)
+		}
+	}
+}
+
+// Get returns the service registered as name.
+func (r *Registry) Get(name string) (Service, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.services[name]
+	return s, ok
+}
+
+// Names returns the names of the registered services in registration
+// order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.names...)
+}
+
+// ByType returns the services of type t in registration order.
+func (r *Registry) ByType(t Type) []Service {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var services []Service
+	for _, name := range r.names {
+		if s := r.services[name]; s.GetType() == t {
+			services = append(services, s)
+		}
+	}
+	return services
+}
+
+// ByServiceType returns the services of the given service type in
+// registration order.
+func (r *Registry) ByServiceType(serviceType string) []Service {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var services []Service
+	for _, name := range r.names {
+		if s := r.services[name]; s.GetServiceType() == serviceType {
+			services = append(services, s)
+		}
+	}
+	return services
+}
+
+// AddRule appends a routing rule, rules are evaluated in order.
+func (r *Registry) AddRule(rule Rule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = append(r.rules, rule)
+}
+
+// SetDefault sets the service payloads of type t are routed to when
+// no rule matches.
+func (r *Registry) SetDefault(t Type, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.services[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrServiceNotFound, name)
+	}
+	r.defaults[t] = name
+	return nil
+}
+
+// Chain sets the fallback chain of the service registered as name:
+// when it fails to handle a payload, the first of fallbacks handles
+// it, then the second and so on. It replaces the previous chain of
+// name and links the services with SetService.
+func (r *Registry) Chain(name string, fallbacks ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	chain := append([]string{name}, fallbacks...)
+	seen := make(map[string]bool, len(chain))
+	for _, n := range chain {
+		if _, ok := r.services[n]; !ok {
+			return fmt.Errorf("%w: %s", ErrServiceNotFound, n)
+		}
+		if seen[n] {
+			return fmt.Errorf("service %s appears twice in fallback chain", n)
+		}
+		seen[n] = true
+	}
+	// The chain must not loop through links set by other chains.
+	for n, ok := r.next[chain[len(chain)-1]]; ok; n, ok = r.next[n] {
+		if seen[n] {
+			return fmt.Errorf("fallback chain of %s loops through %s", name, n)
+		}
+	}
+	for i, n := range chain[:len(chain)-1] {
+		r.services[n].SetService(r.services[chain[i+1]])
+		r.next[n] = chain[i+1]
+	}
+	if len(fallbacks) == 0 {
+		r.services[name].SetService(nil)
+		delete(r.next, name)
+	}
+	return nil
+}
+
+// Route returns the service payload is routed to.
+func (r *Registry) Route(payload Payload) (Service, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if payload.Service != "" {
+		s, ok := r.services[payload.Service]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrServiceNotFound, payload.Service)
+		}
+		return s, nil
+	}
+	for _, rule := range r.rules {
+		s, ok := r.services[rule.Service]
+		if ok && rule.matches(payload, s) {
+			return s, nil
+		}
+	}
+	if name, ok := r.defaults[payload.Type]; ok {
+		s, ok := r.services[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrServiceNotFound, name)
+		}
+		return s, nil
+	}
+	for _, name := range r.names {
+		if s := r.services[name]; s.GetType() == payload.Type {
+			return s, nil
+		}
+	}
+	return nil, ErrNoRoute
+}
+
+// Handle routes payload and handles it with the service found and
+// its fallback chain.
+func (r *Registry) Handle(payload Payload) (Response, error) {
+	return r.Idempotency.Handle(payload, func(payload Payload) (Response, error) {
+		s, err := r.Route(payload)
+		if err != nil {
+			return nil, err
+		}
+		return s.Handle(payload)
+	})
+}
+
+// Queue routes payload and queues it on the service found. The job ID
+// is returned again for a payload queued with the same ID.
+func (r *Registry) Queue(payload Payload) (Response, error) {
+	return r.Idempotency.Handle(payload, func(payload Payload) (Response, error) {
+		s, err := r.Route(payload)
+		if err != nil {
+			return nil, err
+		}
+		return s.Queue(payload)
+	})
+}
+
+// fallback calls handle and, if it fails, the next service set with
+// SetService. Suppressed recipients are not retried. failed, if not
+// nil, is called with the response and error of handle when no next
+// service is tried. The next services report their own failure, so a
+// payload is reported failed once, and not while a fallback service
+// may still send it.
+func fallback(next Service, payload Payload, handle func(Payload) (Response, error), failed func(Response, error)) (Response, error) {
+	res, err := handle(payload)
+	if err == nil {
+		return res, nil
+	}
+	var suppressed *suppression.ErrSuppressed
+	if next == nil || errors.As(err, &suppressed) {
+		if failed != nil {
+			failed(res, err)
+		}
+		return nil, err
+	}
+	res, nextErr := next.Handle(payload)
+	if nextErr != nil {
+		return nil, fmt.Errorf("%w; fallback: %w", err, nextErr)
+	}
+	return res, nil
+}
@@ -0,0 +1,117 @@
+package protocol
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type fakeService struct {
+	name string
+	typ  Type
+	fail bool
+	next Service
+	hits int
+}
+
+func (s *fakeService) Setup() error               { return nil }
+func (s *fakeService) GetType() Type              { return s.typ }
+func (s *fakeService) GetServiceType() string     { return "test" }
+func (s *fakeService) SetService(service Service) { s.next = service }
+func (s *fakeService) Queue(payload Payload) (Response, error) {
+	return s.Handle(payload)
+}
+
+func (s *fakeService) Handle(payload Payload) (Response, error) {
+	return fallback(s.next, payload, func(Payload) (Response, error) {
+		s.hits++
+		if s.fail {
+			return nil, errors.New(s.name + " failed")
+		}
+		return s.name, nil
+	}, nil)
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	mailA := &fakeService{name: "mail-a", typ: Smtp}
+	mailB := &fakeService{name: "mail-b", typ: Smtp}
+	carrier := &fakeService{name: "carrier", typ: Smpp}
+	for _, s := range []*fakeService{mailA, mailB, carrier} {
+		if err := r.Register(s.name, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Register("mail-a", mailA); err == nil {
+		t.Fatal("want error for duplicate name")
+	}
+
+	route := func(p Payload) Response {
+		t.Helper()
+		res, err := r.Handle(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	if res := route(Payload{Type: Smtp}); res != "mail-a" {
+		t.Fatalf("want first smtp service, have %v", res)
+	}
+	if res := route(Payload{Type: Smpp}); res != "carrier" {
+		t.Fatalf("want smpp service, have %v", res)
+	}
+	r.SetDefault(Smtp, "mail-b")
+	if res := route(Payload{Type: Smtp}); res != "mail-b" {
+		t.Fatalf("want default smtp service, have %v", res)
+	}
+	r.AddRule(Rule{Type: Smtp, Service: "mail-a", Match: func(p Payload) bool {
+		return strings.HasSuffix(p.To, "@example.com")
+	}})
+	if res := route(Payload{Type: Smtp, To: "jane@example.com"}); res != "mail-a" {
+		t.Fatalf("want rule service, have %v", res)
+	}
+	if res := route(Payload{Type: Smtp, Service: "carrier"}); res != "carrier" {
+		t.Fatalf("want named service, have %v", res)
+	}
+	if _, err := r.Handle(Payload{Type: Http}); !errors.Is(err, ErrNoRoute) {
+		t.Fatalf("want ErrNoRoute, have %v", err)
+	}
+
+	mailA.fail = true
+	if err := r.Chain("mail-a", "mail-b"); err != nil {
+		t.Fatal(err)
+	}
+	if res := route(Payload{Service: "mail-a"}); res != "mail-b" {
+		t.Fatalf("want fallback, have %v", res)
+	}
+	if err := r.Chain("mail-b", "mail-a"); err == nil {
+		t.Fatal("want error for looping chain")
+	}
+	mailB.fail = true
+	if _, err := r.Handle(Payload{Service: "mail-a"}); err == nil || !strings.Contains(err.Error(), "mail-b failed") {
+		t.Fatalf("want both errors, have %v", err)
+	}
+	r.Unregister("mail-b")
+	if mailA.next != nil {
+		t.Fatal("want link to unregistered service removed")
+	}
+}
+
+func TestRegistryUnregisterDefault(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register("mail", &fakeService{name: "mail", typ: Smtp}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetDefault(Smtp, "mail"); err != nil {
+		t.Fatal(err)
+	}
+	r.Unregister("mail")
+	if _, err := r.Handle(Payload{Type: Smtp}); !errors.Is(err, ErrNoRoute) {
+		t.Fatalf("want ErrNoRoute, have %v", err)
+	}
+	// A default left naming a missing service is not found.
+	r.defaults[Smtp] = "mail"
+	if _, err := r.Handle(Payload{Type: Smtp}); !errors.Is(err, ErrServiceNotFound) {
+		t.Fatalf("want ErrServiceNotFound, have %v", err)
+	}
+}
@@ -1,23 +1,61 @@
 package protocol
 
-import "github.com/oarkflow/protocol/smpp"
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/oarkflow/log"
+
+	"github.com/oarkflow/protocol/i18n"
+	"github.com/oarkflow/protocol/smpp"
+	"github.com/oarkflow/protocol/suppression"
+	"github.com/oarkflow/protocol/utils/xid"
+)
 
 type SMPP struct {
-	manager *smpp.Manager
-	Config  smpp.Setting
-	Service string
+	manager      *smpp.Manager
+	Config       smpp.Setting
+	Service      string
+	Suppression  *suppression.List   // Recipients not to message, updated from stop keywords received.
+	QueueOptions QueueOptions        // Worker pool used by Queue.
+	Callbacks    *CallbackDispatcher // Posts the lifecycle events of payloads to their CallbackURL.
+	Catalog      *i18n.Catalog       // Templates of the payloads naming one, see Payload.Localize.
+	next         Service
+	queue        serviceQueue
+	mu           sync.Mutex
+	tracked      map[string]*tracked // Payload ID to the callback of its messages.
+}
+
+// tracked is the callback of a sent message, its last event being
+// dispatched once.
+type tracked struct {
+	url     string
+	event   CallbackEvent
+	sending bool      // Until the payload is sent, see settle.
+	failed  *Callback // Submission failure held while sending.
 }
 
 func (s *SMPP) Setup() error {
 	return s.manager.Start()
 }
 
+// Close stops the worker pool of the service, if started, and closes
+// its binds, see smpp.Manager.Close.
+func (s *SMPP) Close() error {
+	err := s.queue.close()
+	if e := s.manager.Close(); e != nil {
+		return e
+	}
+	return err
+}
+
 func (s *SMPP) GetType() Type {
 	return Smpp
 }
 
 func (s *SMPP) SetService(service Service) {
-
+	s.next = service
 }
 
 func (s *SMPP) GetServiceType() string {
@@ -25,12 +63,87 @@ func (s *SMPP) GetServiceType() string {
 }
 
 func (s *SMPP) Handle(payload Payload) (Response, error) {
+	return fallback(s.next, payload, s.handle, func(res Response, err error) {
+		s.Callbacks.notify(s, payload, EventSent, res, err)
+	})
+}
+
+// SendSMS sends sms, falling back to the next service on failure.
+func (s *SMPP) SendSMS(sms SMSPayload) (Response, error) {
+	return s.Handle(sms.Payload())
+}
+
+// handle sends the payload to each of its recipients. The message of
+// a single recipient has the payload ID, those of several recipients
+// have the payload ID suffixed with their position, e.g. "id-2", and
+// the response is then a list of messages. It fails only if no message
+// could be sent, the other failures being reported.
+func (s *SMPP) handle(payload Payload) (Response, error) {
+	if payload.Template != "" {
+		if _, err := payload.Localize(s.Catalog); err != nil {
+			return nil, err
+		}
+	}
+	sms := payload.SMS()
+	if err := sms.Validate(); err != nil {
+		return nil, err
+	}
+	if len(sms.To) == 1 {
+		id := payload.ID
+		if id == "" {
+			id = xid.New().String()
+		}
+		res, err := s.send(payload, sms.To[0], id)
+		s.settle(err == nil, id)
+		return res, err
+	}
+	id := payload.ID
+	if id == "" {
+		id = xid.New().String()
+	}
+	var sent []Response
+	var first error
+	ids := make([]string, len(sms.To))
+	for i := range ids {
+		ids[i] = fmt.Sprintf("%s-%d", id, i+1)
+	}
+	if jobs := s.queue.started(); jobs != nil && payload.ID != "" {
+		jobs.Expect(payload.ID, ids...)
+	}
+	// Failed recipients are reported unless no message was sent.
+	defer func() { s.settle(len(sent) > 0, ids...) }()
+	for i, to := range sms.To {
+		res, err := s.send(payload, to, ids[i])
+		if err != nil {
+			log.Error().Err(err).Str("to", to).Msg("Unable to send SMS")
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		sent = append(sent, res)
+	}
+	if len(sent) == 0 {
+		return nil, first
+	}
+	return sent, nil
+}
+
+func (s *SMPP) send(payload Payload, to, id string) (Response, error) {
+	if s.Suppression != nil {
+		if err := s.Suppression.Check(suppression.SMS, to); err != nil {
+			return nil, err
+		}
+	}
+	if s.Callbacks != nil && payload.CallbackURL != "" {
+		s.track(id, payload.CallbackURL)
+	}
 	return s.manager.Send(smpp.Message{
 		From:        payload.From,
-		To:          payload.To,
+		To:          to,
 		Message:     payload.Message,
 		UserID:      payload.UserID,
-		ID:          payload.ID,
+		ID:          id,
 		CreatedAt:   payload.CreatedAt,
 		SentAt:      payload.SentAt,
 		FailedAt:    payload.FailedAt,
@@ -38,6 +151,127 @@ func (s *SMPP) Handle(payload Payload) (Response, error) {
 	})
 }
 
+// Queue queues payload on the worker pool of the service and returns
+// the job ID.
 func (s *SMPP) Queue(payload Payload) (Response, error) {
-	return s.Handle(payload)
+	return s.queue.enqueue(s, s.queued, s.QueueOptions, s.Callbacks, payload)
+}
+
+// Jobs returns the worker pool of the service, started with
+// QueueOptions on first use.
+func (s *SMPP) Jobs() (*JobQueue, error) {
+	return s.queue.get(s, s.queued, s.QueueOptions)
+}
+
+// queued handles a queued payload. Its delivery is reported later,
+// see report.
+func (s *SMPP) queued(payload *Payload) (Response, error) {
+	res, err := s.Handle(*payload)
+	if sms, ok := res.(*smpp.Message); ok {
+		payload.SentAt = sms.SentAt
+	}
+	return res, err
+}
+
+// track dispatches the events of the message with the given ID to
+// url, see report. Messages are tracked for a day at most.
+func (s *SMPP) track(id, url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tracked == nil {
+		s.tracked = make(map[string]*tracked)
+	}
+	t := &tracked{url: url, sending: true}
+	s.tracked[id] = t
+	time.AfterFunc(24*time.Hour, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.tracked[id] == t {
+			delete(s.tracked, id)
+		}
+	})
+}
+
+// report updates the queued job and dispatches the callback of sms
+// from its submission or delivery report. The messages of several
+// recipients update their job as registered by handle.
+func (s *SMPP) report(sms *smpp.Message, parts []*smpp.Part) {
+	s.dispatch(sms, parts)
+	jobs := s.queue.started()
+	if jobs == nil {
+		return
+	}
+	switch sms.MessageStatus {
+	case smpp.DELIVERED:
+		jobs.Report(sms.ID, JobDelivered, "")
+	case smpp.FAILED:
+		jobs.Report(sms.ID, JobFailed, sms.Error)
+	}
+}
+
+// dispatch posts the callback of sms if its status changed. A failed
+// submission may be retried, so only delivered messages stop being
+// tracked.
+func (s *SMPP) dispatch(sms *smpp.Message, parts []*smpp.Part) {
+	cb, ok := smsCallback(s, sms, parts)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	t, ok := s.tracked[sms.ID]
+	if !ok || t.event == cb.Event {
+		s.mu.Unlock()
+		return
+	}
+	if t.sending && cb.Event == EventFailed {
+		// The payload may yet be sent by a fallback service.
+		t.failed = &cb
+		s.mu.Unlock()
+		return
+	}
+	t.event = cb.Event
+	if cb.Event == EventDelivered {
+		delete(s.tracked, sms.ID)
+	}
+	s.mu.Unlock()
+	s.Callbacks.Dispatch(t.url, cb)
+}
+
+// settle ends the sending of the messages with the given IDs. Their
+// submission failures are dispatched if report, or else dropped, the
+// failure of the payload being reported at the end of its fallback
+// chain.
+func (s *SMPP) settle(report bool, ids ...string) {
+	type dispatch struct {
+		url string
+		cb  Callback
+	}
+	var failed []dispatch
+	s.mu.Lock()
+	for _, id := range ids {
+		t, ok := s.tracked[id]
+		if !ok || !t.sending {
+			continue
+		}
+		t.sending = false
+		if t.failed != nil && report && t.event != EventFailed {
+			t.event = EventFailed
+			failed = append(failed, dispatch{url: t.url, cb: *t.failed})
+		}
+		t.failed = nil
+	}
+	s.mu.Unlock()
+	for _, d := range failed {
+		s.Callbacks.Dispatch(d.url, d.cb)
+	}
+}
+
+// receive updates the suppression list from stop and start keywords.
+func (s *SMPP) receive(msg *smpp.Inbound) {
+	if s.Suppression == nil {
+		return
+	}
+	if err := s.Suppression.Inbound(suppression.SMS, msg.From, msg.Message); err != nil {
+		log.Error().Err(err).Str("from", msg.From).Msg("Unable to update suppression list")
+	}
 }
@@ -1,5 +1,59 @@
 package balancer
 
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/oarkflow/errors"
+)
+
 type Balancer interface {
 	Pick(ids []string) (string, error)
 }
+
+// ErrUnknownBalancer is returned by New for names not registered.
+var ErrUnknownBalancer = errors.New("unknown balancer")
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]func() Balancer{
+		"round_robin": func() Balancer { return &RoundRobin{} },
+		"random":      func() Balancer { return &Random{} },
+	}
+)
+
+// Register makes a balancer available by name to New, replacing any
+// balancer of the same name.
+func Register(name string, factory func() Balancer) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[name] = factory
+}
+
+// New returns a new balancer of the given name, "round_robin" if
+// empty.
+func New(name string) (Balancer, error) {
+	if name == "" {
+		name = "round_robin"
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	factory, ok := factories[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownBalancer, name)
+	}
+	return factory(), nil
+}
+
+// Names returns the registered balancer names, sorted.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
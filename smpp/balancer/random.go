@@ -0,0 +1,15 @@
+package balancer
+
+import (
+	"math/rand/v2"
+)
+
+// Random picks a uniformly random item.
+type Random struct{}
+
+func (r *Random) Pick(ids []string) (string, error) {
+	if len(ids) == 0 {
+		return "", ErrNoAvailableItem
+	}
+	return ids[rand.IntN(len(ids))], nil
+}
@@ -0,0 +1,227 @@
+// Package capture records SMPP PDUs as they cross the wire and
+// replays recorded sessions.
+//
+// A capture file is a sequence of length-prefixed binary records:
+//
+//	uint32 record length (excluding these 4 octets)
+//	int64  timestamp in unix nanoseconds
+//	uint8  direction (1 inbound, 2 outbound)
+//	uint8  connection ID length, followed by the connection ID
+//	[]byte raw PDU, header included
+//
+// All integers are big endian. FileWriter also writes a JSON decode
+// of every record to a sidecar file, one JSON object per line.
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/oarkflow/protocol/smpp/pdu"
+	"github.com/oarkflow/protocol/smpp/pdu/pdufield"
+)
+
+// Direction of a captured PDU, seen from the local side.
+type Direction uint8
+
+// Supported directions.
+const (
+	Inbound Direction = iota + 1
+	Outbound
+)
+
+var directionText = map[Direction]string{
+	Inbound:  "in",
+	Outbound: "out",
+}
+
+// String implements the Stringer interface.
+func (d Direction) String() string {
+	return directionText[d]
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (d Direction) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (d *Direction) UnmarshalText(b []byte) error {
+	for k, v := range directionText {
+		if v == string(b) {
+			*d = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown direction: %q", b)
+}
+
+// Record is a single captured PDU.
+type Record struct {
+	Time      time.Time
+	Direction Direction
+	ConnID    string
+	Data      []byte // Raw PDU, header included.
+}
+
+// PDU decodes the raw PDU of the record.
+func (r Record) PDU() (pdu.Body, error) {
+	return pdu.Decode(bytes.NewReader(r.Data))
+}
+
+// Capturer receives a copy of every PDU read from or written to
+// a connection. Implementations must be safe for concurrent use.
+type Capturer interface {
+	Capture(r Record) error
+}
+
+// CapturerFunc is an adapter to allow the use of ordinary functions
+// as a Capturer.
+type CapturerFunc func(r Record) error
+
+// Capture implements the Capturer interface.
+func (f CapturerFunc) Capture(r Record) error {
+	return f(r)
+}
+
+// maxConnIDLen is the longest connection ID that fits the record header.
+const maxConnIDLen = 255
+
+// Encode writes the binary representation of the record to w.
+func Encode(w io.Writer, r Record) error {
+	id := r.ConnID
+	if len(id) > maxConnIDLen {
+		id = id[:maxConnIDLen]
+	}
+	b := make([]byte, 4+8+1+1+len(id)+len(r.Data))
+	binary.BigEndian.PutUint32(b[0:4], uint32(len(b)-4))
+	binary.BigEndian.PutUint64(b[4:12], uint64(r.Time.UnixNano()))
+	b[12] = uint8(r.Direction)
+	b[13] = uint8(len(id))
+	copy(b[14:], id)
+	copy(b[14+len(id):], r.Data)
+	_, err := w.Write(b)
+	return err
+}
+
+// Reader reads records from a binary capture.
+type Reader struct {
+	r io.Reader
+}
+
+// NewReader returns a Reader that reads records from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// Next returns the next record, or io.EOF when there are no more.
+func (cr *Reader) Next() (Record, error) {
+	var l [4]byte
+	if _, err := io.ReadFull(cr.r, l[:]); err != nil {
+		return Record{}, err
+	}
+	n := binary.BigEndian.Uint32(l[:])
+	if n < 10 || n > pdu.MaxSize+10+maxConnIDLen {
+		return Record{}, fmt.Errorf("invalid capture record length: %d", n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(cr.r, b); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return Record{}, err
+	}
+	idLen := int(b[9])
+	if 10+idLen > len(b) {
+		return Record{}, fmt.Errorf("invalid capture connection id length: %d", idLen)
+	}
+	return Record{
+		Time:      time.Unix(0, int64(binary.BigEndian.Uint64(b[0:8]))),
+		Direction: Direction(b[8]),
+		ConnID:    string(b[10 : 10+idLen]),
+		Data:      b[10+idLen:],
+	}, nil
+}
+
+// ReadAll reads all records of a binary capture.
+func ReadAll(r io.Reader) ([]Record, error) {
+	cr := NewReader(r)
+	var records []Record
+	for {
+		rec, err := cr.Next()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, rec)
+	}
+}
+
+// Entry is the JSON friendly decode of a Record.
+type Entry struct {
+	Time      time.Time         `json:"time"`
+	Direction Direction         `json:"direction"`
+	ConnID    string            `json:"conn_id"`
+	Command   string            `json:"command"`
+	Status    string            `json:"status"`
+	Seq       uint32            `json:"seq"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	TLVs      map[string]any    `json:"tlvs,omitempty"`
+	Raw       string            `json:"raw"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// Decode returns the JSON friendly decode of the record. Records
+// holding PDUs that cannot be decoded are returned with Error set.
+func Decode(r Record) Entry {
+	e := Entry{
+		Time:      r.Time,
+		Direction: r.Direction,
+		ConnID:    r.ConnID,
+		Raw:       hex.EncodeToString(r.Data),
+	}
+	p, err := r.PDU()
+	if err != nil {
+		e.Error = err.Error()
+		return e
+	}
+	h := p.Header()
+	e.Command = h.ID.String()
+	e.Status = h.Status.Error()
+	e.Seq = h.Seq
+	if len(p.Fields()) > 0 {
+		e.Fields = make(map[string]string, len(p.Fields()))
+		for k, v := range p.Fields() {
+			if v == nil {
+				continue
+			}
+			switch k {
+			case pdufield.GSMUserData, pdufield.DestinationList:
+				e.Fields[string(k)] = hex.EncodeToString(v.Bytes())
+			default:
+				e.Fields[string(k)] = v.String()
+			}
+		}
+	}
+	if len(p.TLVFields()) > 0 {
+		e.TLVs = make(map[string]any, len(p.TLVFields()))
+		for t := range p.TLVFields() {
+			v, err := p.TLVFields().Get(t)
+			if err != nil {
+				v = hex.EncodeToString(p.TLVFields()[t].Bytes())
+			}
+			if b, ok := v.([]byte); ok {
+				v = hex.EncodeToString(b)
+			}
+			e.TLVs[t.Name()] = v
+		}
+	}
+	return e
+}
@@ -0,0 +1,53 @@
+package capture
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/oarkflow/protocol/smpp/pdu"
+	"github.com/oarkflow/protocol/smpp/pdu/pdufield"
+)
+
+func TestEncodeReplay(t *testing.T) {
+	var buf bytes.Buffer
+	sm := pdu.NewSubmitSM(nil, nil)
+	sm.Fields().Set(pdufield.SourceAddr, "root")
+	sm.Fields().Set(pdufield.DestinationAddr, "9779800000000")
+	sm.Fields().Set(pdufield.ShortMessage, "hello")
+	for i, p := range []pdu.Body{sm, pdu.NewEnquireLink(nil)} {
+		var raw bytes.Buffer
+		if err := p.SerializeTo(&raw); err != nil {
+			t.Fatal(err)
+		}
+		dir := Outbound
+		if i == 1 {
+			dir = Inbound
+		}
+		err := Encode(&buf, Record{Time: time.Now(), Direction: dir, ConnID: "c1", Data: raw.Bytes()})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	records, err := ReadAll(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("want 2 records, have %d", len(records))
+	}
+	e := Decode(records[0])
+	if e.Command != "SubmitSM" || e.ConnID != "c1" || e.Fields["destination_addr"] != "9779800000000" {
+		t.Fatalf("unexpected entry: %#v", e)
+	}
+	var got []pdu.ID
+	err = Replay(bytes.NewReader(buf.Bytes()), func(p pdu.Body) {
+		got = append(got, p.Header().ID)
+	}, Options{Direction: Inbound})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != pdu.EnquireLinkID {
+		t.Fatalf("unexpected replay: %v", got)
+	}
+}
@@ -0,0 +1,78 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// FileWriter is a Capturer writing records to a binary capture file
+// and their JSON decode to a sidecar file with the ".jsonl" suffix.
+type FileWriter struct {
+	mu   sync.Mutex
+	bin  *os.File
+	js   *os.File
+	bw   *bufio.Writer
+	jw   *bufio.Writer
+	enc  *json.Encoder
+	path string
+}
+
+// NewFileWriter creates (or appends to) the capture file at path
+// and its JSON sidecar.
+func NewFileWriter(path string) (*FileWriter, error) {
+	bin, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	js, err := os.OpenFile(path+".jsonl", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		bin.Close()
+		return nil, err
+	}
+	w := &FileWriter{
+		bin:  bin,
+		js:   js,
+		bw:   bufio.NewWriter(bin),
+		jw:   bufio.NewWriter(js),
+		path: path,
+	}
+	w.enc = json.NewEncoder(w.jw)
+	return w, nil
+}
+
+// Path returns the path of the binary capture file.
+func (w *FileWriter) Path() string {
+	return w.path
+}
+
+// Capture implements the Capturer interface. Records are flushed
+// to disk immediately so captures survive a crash.
+func (w *FileWriter) Capture(r Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := Encode(w.bw, r); err != nil {
+		return err
+	}
+	if err := w.enc.Encode(Decode(r)); err != nil {
+		return err
+	}
+	if err := w.bw.Flush(); err != nil {
+		return err
+	}
+	return w.jw.Flush()
+}
+
+// Close flushes and closes both files.
+func (w *FileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.bw.Flush()
+	w.jw.Flush()
+	err := w.bin.Close()
+	if jerr := w.js.Close(); err == nil {
+		err = jerr
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package capture
+
+import (
+	"io"
+	"time"
+
+	"github.com/oarkflow/protocol/smpp/pdu"
+)
+
+// Options configures which records are replayed and how.
+type Options struct {
+	// Direction only replays records in the given direction.
+	// Zero replays both directions.
+	Direction Direction
+
+	// ConnID only replays records of the given connection.
+	// Empty replays all connections.
+	ConnID string
+
+	// Realtime preserves the original delay between records.
+	Realtime bool
+}
+
+func (o Options) match(r Record) bool {
+	if o.Direction != 0 && r.Direction != o.Direction {
+		return false
+	}
+	if o.ConnID != "" && r.ConnID != o.ConnID {
+		return false
+	}
+	return true
+}
+
+// PDUWriter is the interface that wraps the basic Write method of
+// an SMPP connection.
+type PDUWriter interface {
+	Write(p pdu.Body) error
+}
+
+// Replay decodes the records of the capture read from r and calls h
+// for every PDU matching opt, in capture order. It is meant to feed
+// a receiver HandlerFunc in regression tests.
+func Replay(r io.Reader, h func(p pdu.Body), opt Options) error {
+	return replay(r, opt, func(p pdu.Body) error {
+		h(p)
+		return nil
+	})
+}
+
+// ReplayTo writes the PDUs of the capture read from r matching opt
+// to w, e.g. a connection dialed to an SMSC simulator. Sequence
+// numbers are preserved.
+func ReplayTo(r io.Reader, w PDUWriter, opt Options) error {
+	return replay(r, opt, w.Write)
+}
+
+func replay(r io.Reader, opt Options, f func(p pdu.Body) error) error {
+	cr := NewReader(r)
+	var last time.Time
+	for {
+		rec, err := cr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !opt.match(rec) {
+			continue
+		}
+		if opt.Realtime && !last.IsZero() {
+			if d := rec.Time.Sub(last); d > 0 {
+				time.Sleep(d)
+			}
+		}
+		last = rec.Time
+		p, err := rec.PDU()
+		if err != nil {
+			return err
+		}
+		if err = f(p); err != nil {
+			return err
+		}
+	}
+}
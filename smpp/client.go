@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/oarkflow/protocol/interfaces"
+	"github.com/oarkflow/protocol/smpp/capture"
 	"github.com/oarkflow/protocol/smpp/pdu"
 	"github.com/oarkflow/protocol/smpp/pdu/pdufield"
 )
@@ -83,6 +84,7 @@ type RateLimiter interface {
 
 // client provides a persistent client connection.
 type client struct {
+	ID                 string
 	Addr               string
 	TLS                *tls.Config
 	Status             chan ConnStatus
@@ -94,6 +96,7 @@ type client struct {
 	WindowSize         uint
 	RateLimiter        RateLimiter
 	ObserveEnquireLink func(float64)
+	Capture            capture.Capturer
 
 	// internal stuff.
 	inbox chan pdu.Body
@@ -105,6 +108,7 @@ type client struct {
 	eliTime time.Time
 	eliMtx  sync.RWMutex
 	manager interfaces.IManager
+	seq     pdu.SequenceGenerator
 }
 
 func (c *client) init() {
@@ -127,7 +131,7 @@ func (c *client) Bind() {
 	for !c.closed() {
 		eli := make(chan struct{})
 		c.inbox = make(chan pdu.Body)
-		conn, err := Dial(c.Addr, c.TLS)
+		conn, err := DialCapture(c.Addr, c.TLS, c.ID, c.Capture)
 		if err != nil {
 			c.notify(&connStatus{
 				s:   ConnectionFailed,
@@ -154,7 +158,7 @@ func (c *client) Bind() {
 			}
 			switch p.Header().ID {
 			case pdu.EnquireLinkID:
-				pResp := pdu.NewEnquireLinkRespSeq(p.Header().Seq, c.manager)
+				pResp := pdu.NewEnquireLinkRespSeq(p.Header().Seq, c.seq)
 				err := c.conn.Write(pResp)
 				if err != nil {
 					break
@@ -188,14 +192,14 @@ func (c *client) enquireLink(stop chan struct{}) {
 			// check the time of the last received EnquireLinkResp
 			c.eliMtx.RLock()
 			if time.Since(c.eliTime) >= c.EnquireLinkTimeout {
-				c.conn.Write(pdu.NewUnbind(c.manager))
+				c.conn.Write(pdu.NewUnbind(c.seq))
 				c.conn.Close()
 				c.eliMtx.RUnlock()
 				return
 			}
 			c.eliMtx.RUnlock()
 			// send the EnquireLink
-			err := c.conn.Write(pdu.NewEnquireLink(c.manager))
+			err := c.conn.Write(pdu.NewEnquireLink(c.seq))
 			if err != nil {
 				return
 			}
@@ -237,8 +241,12 @@ func (c *client) Read() (pdu.Body, error) {
 	}
 }
 
-// Write serializes the given PDU and writes to the connection.
+// Write serializes the given PDU and writes to the connection. The
+// PDU is given the manager of the session, as read ones are.
 func (c *client) Write(w pdu.Body) error {
+	if c.manager != nil {
+		w.SetManager(c.manager)
+	}
 	if c.RateLimiter != nil {
 		c.RateLimiter.Wait(c.lmctx)
 	}
@@ -249,7 +257,7 @@ func (c *client) Write(w pdu.Body) error {
 func (c *client) Close() error {
 	c.once.Do(func() {
 		close(c.stop)
-		if err := c.conn.Write(pdu.NewUnbind(c.manager)); err == nil {
+		if err := c.conn.Write(pdu.NewUnbind(c.seq)); err == nil {
 			select {
 			case <-c.inbox: // TODO: validate UnbindResp
 			case <-time.After(time.Second):
@@ -12,7 +12,11 @@ import (
 	"io"
 	"net"
 	"sync"
+	"time"
 
+	"github.com/oarkflow/log"
+
+	"github.com/oarkflow/protocol/smpp/capture"
 	"github.com/oarkflow/protocol/smpp/pdu"
 )
 
@@ -56,6 +60,13 @@ type Closer interface {
 // Dial dials to the SMPP server and returns a Conn, or error.
 // TLS is only used if provided.
 func Dial(addr string, TLS *tls.Config) (Conn, error) {
+	return DialCapture(addr, TLS, "", nil)
+}
+
+// DialCapture is like Dial, but sends a copy of every PDU read from
+// or written to the connection to cp, tagged with the given
+// connection ID. Capture is disabled if cp is nil.
+func DialCapture(addr string, TLS *tls.Config, id string, cp capture.Capturer) (Conn, error) {
 	if addr == "" {
 		addr = "localhost:2775"
 	}
@@ -67,9 +78,11 @@ func Dial(addr string, TLS *tls.Config) (Conn, error) {
 		fd = tls.Client(fd, TLS)
 	}
 	c := &conn{
-		rwc: fd,
-		r:   bufio.NewReader(fd),
-		w:   bufio.NewWriter(fd),
+		rwc:     fd,
+		r:       bufio.NewReader(fd),
+		w:       bufio.NewWriter(fd),
+		id:      id,
+		capture: cp,
 	}
 	return c, nil
 }
@@ -77,14 +90,37 @@ func Dial(addr string, TLS *tls.Config) (Conn, error) {
 // conn provides the basics of a single client connection and
 // implements the Conn interface.
 type conn struct {
-	rwc net.Conn
-	r   *bufio.Reader
-	w   *bufio.Writer
+	rwc     net.Conn
+	r       *bufio.Reader
+	w       *bufio.Writer
+	id      string
+	capture capture.Capturer
 }
 
 // Read implements the Conn interface.
 func (c *conn) Read() (pdu.Body, error) {
-	return pdu.Decode(c.r)
+	if c.capture == nil {
+		return pdu.Decode(c.r)
+	}
+	var b bytes.Buffer
+	p, err := pdu.Decode(io.TeeReader(c.r, &b))
+	if err == nil {
+		c.record(capture.Inbound, b.Bytes())
+	}
+	return p, err
+}
+
+// record hands a copy of the raw PDU to the capture hook.
+func (c *conn) record(dir capture.Direction, data []byte) {
+	err := c.capture.Capture(capture.Record{
+		Time:      time.Now(),
+		Direction: dir,
+		ConnID:    c.id,
+		Data:      append([]byte(nil), data...),
+	})
+	if err != nil {
+		log.Error().Err(err).Str("conn_id", c.id).Msg("Unable to capture PDU")
+	}
 }
 
 // Write implements the Conn interface.
@@ -94,11 +130,19 @@ func (c *conn) Write(w pdu.Body) error {
 	if err != nil {
 		return err
 	}
+	data := b.Bytes()
 	_, err = io.Copy(c.w, &b)
 	if err != nil {
 		return err
 	}
-	return c.w.Flush()
+	if err = c.w.Flush(); err != nil {
+		return err
+	}
+	// Only PDUs actually sent are recorded.
+	if c.capture != nil {
+		c.record(capture.Outbound, data)
+	}
+	return nil
 }
 
 // Close implements the Conn interface.
@@ -3,6 +3,7 @@ package smpp
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"regexp"
@@ -11,17 +12,18 @@ import (
 	"sync/atomic"
 	"syscall"
 	"time"
-	"unicode"
-	"unicode/utf8"
 
 	"github.com/oarkflow/errors"
 	"github.com/oarkflow/log"
 	"golang.org/x/time/rate"
 
 	"github.com/oarkflow/protocol/smpp/balancer"
+	"github.com/oarkflow/protocol/smpp/capture"
+	"github.com/oarkflow/protocol/smpp/number"
 	"github.com/oarkflow/protocol/smpp/pdu"
 	"github.com/oarkflow/protocol/smpp/pdu/pdufield"
 	"github.com/oarkflow/protocol/smpp/pdu/pdutext"
+	"github.com/oarkflow/protocol/smpp/pdu/pdutlv"
 	"github.com/oarkflow/protocol/utils/maps"
 	"github.com/oarkflow/protocol/utils/str"
 	"github.com/oarkflow/protocol/utils/xid"
@@ -33,6 +35,26 @@ type Auth struct {
 	SystemType string
 }
 
+// BindMode selects the kind of binds maintained by a Manager.
+type BindMode string
+
+const (
+	BindTransceiver BindMode = "transceiver" // MaxConnection transceiver binds, the default.
+	BindTransmitter BindMode = "transmitter" // MaxConnection transmitter binds, no MO or receipts.
+	BindReceiver    BindMode = "receiver"    // MaxReceiver receiver binds, sending is disabled.
+	BindSplit       BindMode = "split"       // MaxConnection transmitter and MaxReceiver receiver binds.
+)
+
+// Connection is a bind able to submit messages, i.e. a *Transceiver
+// or a *Transmitter, as returned by Manager.GetConnection.
+type Connection interface {
+	Submit(sm *ShortMessage) (*ShortMessage, error)
+	SubmitLongMsg(sm *ShortMessage) ([]ShortMessage, error)
+	QuerySM(src, msgid string, srcTON, srcNPI uint8) (*QueryResp, error)
+	CancelSM(src, dst, msgid string, srcTON, srcNPI uint8) error
+	Close() error
+}
+
 type Setting struct {
 	Name                 string                   `json:"name,omitempty"`
 	Slug                 string                   `json:"slug,omitempty"`
@@ -43,7 +65,10 @@ type Setting struct {
 	WriteTimeout         time.Duration            `json:"write_timeout,omitempty"`
 	EnquiryInterval      time.Duration            `json:"enquiry_interval,omitempty"`
 	EnquiryTimeout       time.Duration            `json:"enquiry_timeout,omitempty"`
+	BindMode             BindMode                 `json:"bind_mode,omitempty"`
 	MaxConnection        int                      `json:"max_connection,omitempty"`
+	MaxReceiver          int                      `json:"max_receiver,omitempty"`
+	MergeInterval        time.Duration            `json:"merge_interval,omitempty"`
 	Balancer             balancer.Balancer        `json:"balancer,omitempty"`
 	Throttle             int                      `json:"throttle,omitempty"`
 	UseAllConnection     bool                     `json:"use_all_connection,omitempty"`
@@ -55,10 +80,17 @@ type Setting struct {
 	ProtocolID           uint8                    `json:"protocol_id,omitempty"`
 	PriorityFlag         uint8                    `json:"priority_flag,omitempty"`
 	ScheduleDeliveryTime string                   `json:"schedule_delivery_time,omitempty"`
+	DefaultCountry       string                   `json:"default_country,omitempty"` // ISO 3166-1 alpha-2 code used to normalize national numbers.
+	RelativeTime         bool                     `json:"relative_time,omitempty"`   // Send Message schedule and validity in relative format.
+	LocalSchedule        bool                     `json:"local_schedule,omitempty"`  // Hold scheduled messages in memory until due instead of using schedule_delivery_time.
 	ReplaceIfPresentFlag uint8                    `json:"replace_if_present_flag,omitempty"`
+	TLS                  *TLSSetting              `json:"tls,omitempty"`
+	CaptureFile          string                   `json:"capture_file,omitempty"`
+	Capture              capture.Capturer         `json:"-"`
 	HandlePDU            func(p pdu.Body)
 	OnPartReport         func(manager *Manager, parts []*Part)
 	OnMessageReport      func(manager *Manager, sms *Message, parts []*Part)
+	OnMessageReceived    func(manager *Manager, msg *Inbound)
 }
 
 type Manager struct {
@@ -67,9 +99,11 @@ type Manager struct {
 	ID                     string
 	ctx                    context.Context
 	setting                Setting
-	connections            map[string]*Transceiver
-	messagesToRetry        map[any]*Transceiver
+	connections            map[string]Connection
+	receivers              map[string]*Receiver
+	messagesToRetry        map[any]string
 	balancer               balancer.Balancer
+	tls                    *TLSConfig
 	connIDs                []string
 	mu                     sync.RWMutex
 	messages               *maps.Map[string, *Message]
@@ -78,31 +112,51 @@ type Manager struct {
 	smsParts               *maps.Map[string, []string]
 	lastMessageTS          time.Time
 	lastDeliveredMessageTS time.Time
+	seq                    pdu.Sequence
+	scheduler              *scheduler
 }
 
 type Message struct {
-	From           string    `json:"from,omitempty"`
-	To             string    `json:"to,omitempty"`
-	ID             string    `json:"id"`
-	UserID         any       `json:"user_id"`
-	Message        string    `json:"message,omitempty"`
-	MessageID      string    `json:"message_id,omitempty"`
-	MessageStatus  string    `json:"message_status,omitempty"`
-	Error          string    `json:"error,omitempty"`
-	TotalParts     int32     `json:"total_parts"`
-	SentParts      int32     `json:"sent_parts"`
-	FailedParts    int32     `json:"failed_parts"`
-	DeliveredParts int32     `json:"delivered_parts"`
-	CreatedAt      time.Time `json:"created_at"`
-	SentAt         time.Time `json:"sent_at"`
-	DeliveredAt    time.Time `json:"delivered_at"`
-	FailedAt       time.Time `json:"failed_at"`
+	From           string        `json:"from,omitempty"`
+	To             string        `json:"to,omitempty"`
+	ID             string        `json:"id"`
+	UserID         any           `json:"user_id"`
+	Message        string        `json:"message,omitempty"`
+	Coding         string        `json:"coding,omitempty"` // Data coding name, detected from Message if empty.
+	TLVs           pdutlv.Fields `json:"tlvs,omitempty"`
+	ScheduleAt     time.Time     `json:"schedule_at"`          // Delivery time, immediate if zero or past.
+	ValidUntil     time.Time     `json:"valid_until"`          // Validity deadline, the setting's Validity if zero.
+	Country        string        `json:"country,omitempty"`    // Country of national numbers, the setting's DefaultCountry if empty.
+	SourceTON      *uint8        `json:"source_ton,omitempty"` // Overrides the TON detected for From, which is then sent as is.
+	SourceNPI      *uint8        `json:"source_npi,omitempty"`
+	DestTON        *uint8        `json:"dest_ton,omitempty"` // Overrides the TON detected for To, which is then sent as is.
+	DestNPI        *uint8        `json:"dest_npi,omitempty"`
+	MessageID      string        `json:"message_id,omitempty"`
+	MessageStatus  string        `json:"message_status,omitempty"`
+	Error          string        `json:"error,omitempty"`
+	TotalParts     int32         `json:"total_parts"`
+	SentParts      int32         `json:"sent_parts"`
+	FailedParts    int32         `json:"failed_parts"`
+	DeliveredParts int32         `json:"delivered_parts"`
+	CreatedAt      time.Time     `json:"created_at"`
+	SentAt         time.Time     `json:"sent_at"`
+	DeliveredAt    time.Time     `json:"delivered_at"`
+	FailedAt       time.Time     `json:"failed_at"`
 	totalParts     atomic.Int32
 	sentParts      atomic.Int32
 	failedParts    atomic.Int32
 	deliveredParts atomic.Int32
 }
 
+// Inbound is a mobile originated message received in a deliver_sm
+// that is not a delivery receipt.
+type Inbound struct {
+	From       string    `json:"from"` // International numbers are prefixed with "+".
+	To         string    `json:"to"`
+	Message    string    `json:"message"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
 type Part struct {
 	ID            string    `json:"id"`
 	SmsMessageID  string    `json:"sms_message_id"`
@@ -117,8 +171,11 @@ type Part struct {
 }
 
 const (
+	SENT      string = "SENT"
 	DELIVERED string = "DELIVERED"
 	FAILED    string = "FAILED"
+	SCHEDULED string = "SCHEDULED"
+	CANCELLED string = "CANCELLED"
 )
 
 func NewManager(setting Setting) (*Manager, error) {
@@ -127,6 +184,16 @@ func NewManager(setting Setting) (*Manager, error) {
 		if setting.MaxConnection == 0 {
 			setting.MaxConnection = 1
 		}
+		if setting.MaxReceiver == 0 {
+			setting.MaxReceiver = 1
+		}
+		switch setting.BindMode {
+		case "":
+			setting.BindMode = BindTransceiver
+		case BindTransceiver, BindTransmitter, BindReceiver, BindSplit:
+		default:
+			return nil, errors.New("Unsupported bind mode: " + string(setting.BindMode))
+		}
 		if setting.ReadTimeout == 0 {
 			setting.ReadTimeout = 10 * time.Second
 		}
@@ -150,28 +217,53 @@ func NewManager(setting Setting) (*Manager, error) {
 		Slug:            setting.Slug,
 		ID:              id,
 		ctx:             context.Background(),
-		messagesToRetry: make(map[any]*Transceiver),
-		connections:     make(map[string]*Transceiver),
+		messagesToRetry: make(map[any]string),
+		connections:     make(map[string]Connection),
+		receivers:       make(map[string]*Receiver),
 		messages:        maps.New[string, *Message](10000),
 		parts:           maps.New[string, *Part](10000),
 		messageParts:    maps.New[string, string](10000),
 		smsParts:        maps.New[string, []string](10000),
+		scheduler:       newScheduler(),
 	}
 
+	if setting.TLS != nil {
+		tc, err := NewTLSConfig(*setting.TLS, setting.URL)
+		if err != nil {
+			return nil, errors.NewE(err, "Unable to load TLS configuration", "manager:new")
+		}
+		manager.tls = tc
+	}
+	// Opened last so that it is not left open by the errors above.
+	if setting.Capture == nil && setting.CaptureFile != "" {
+		w, err := capture.NewFileWriter(setting.CaptureFile)
+		if err != nil {
+			return nil, errors.NewE(err, "Unable to open capture file", "manager:new")
+		}
+		setting.Capture = w
+	}
 	if setting.HandlePDU == nil {
 		setting.HandlePDU = manager.DefaultPDUHandler
 	}
-	if setting.Balancer == nil {
+	manager.balancer = setting.Balancer
+	if manager.balancer == nil {
 		manager.balancer = &balancer.RoundRobin{}
 	}
 	manager.setting = setting
 	return manager, nil
 }
 
+// NextSeq implements the pdu.SequenceGenerator interface so PDUs can
+// still be built with the manager. Binds use their own sequence.
+func (m *Manager) NextSeq() uint32 {
+	return m.seq.NextSeq()
+}
+
 func (m *Manager) DefaultPDUHandler(p pdu.Body) {
 	if msgStatus, ok := p.Fields()[pdufield.ShortMessage]; ok {
 		response := Unmarshal(msgStatus.String())
 		if response == nil {
+			m.receive(p)
 			return
 		}
 		id := response["id"]
@@ -224,7 +316,42 @@ func (m *Manager) DefaultPDUHandler(p pdu.Body) {
 	}
 }
 
+// receive reports p, a deliver_sm that is not a delivery receipt, to
+// OnMessageReceived.
+func (m *Manager) receive(p pdu.Body) {
+	if m.setting.OnMessageReceived == nil || p.Header().ID != pdu.DeliverSMID {
+		return
+	}
+	f := p.Fields()
+	msg := &Inbound{ReceivedAt: time.Now()}
+	if v := f[pdufield.SourceAddr]; v != nil {
+		msg.From = v.String()
+	}
+	if v := f[pdufield.SourceAddrTON]; v != nil && v.Bytes()[0] == number.TONInternational && !strings.HasPrefix(msg.From, "+") {
+		msg.From = "+" + msg.From
+	}
+	if v := f[pdufield.DestinationAddr]; v != nil {
+		msg.To = v.String()
+	}
+	var coding pdutext.DataCoding
+	if v := f[pdufield.DataCoding]; v != nil {
+		coding = pdutext.DataCoding(v.Bytes()[0])
+	}
+	if v := f[pdufield.ShortMessage]; v != nil {
+		msg.Message = string(coding.Decode(v.Bytes()))
+	}
+	m.setting.OnMessageReceived(m, msg)
+}
+
 func (m *Manager) Start() error {
+	switch m.setting.BindMode {
+	case BindReceiver:
+		return m.startReceivers()
+	case BindSplit:
+		if err := m.startReceivers(); err != nil {
+			return err
+		}
+	}
 	if m.setting.UseAllConnection {
 		for i := 0; i < m.setting.MaxConnection; i++ {
 			err := m.SetupConnection()
@@ -234,7 +361,7 @@ func (m *Manager) Start() error {
 		}
 		return nil
 	}
-	if len(m.connIDs) == 0 {
+	if m.connCount() == 0 {
 		err := m.SetupConnection()
 		if err != nil {
 			return errors.NewE(err, "Unable to make SMPP connection", "manager:start")
@@ -243,6 +370,21 @@ func (m *Manager) Start() error {
 	return nil
 }
 
+func (m *Manager) startReceivers() error {
+	m.mu.RLock()
+	bound := len(m.receivers)
+	m.mu.RUnlock()
+	for i := bound; i < m.setting.MaxReceiver; i++ {
+		err := m.SetupReceiver()
+		if err != nil {
+			return errors.NewE(err, "Unable to make SMPP receiver connection", "manager:start")
+		}
+	}
+	return nil
+}
+
+// Report passes sms with its parts to OnMessageReport once it has a
+// status: SENT when submitted, then DELIVERED or FAILED.
 func (m *Manager) Report(sms *Message) {
 	if sms.MessageStatus != "" && m.setting.OnMessageReport != nil {
 		smsParts, pExists := m.smsParts.Get(sms.ID)
@@ -270,10 +412,11 @@ func (m *Manager) AddConnection(noOfConnection ...int) error {
 	if con > m.setting.MaxConnection {
 		return errors.New("Can't create more than allowed no of connections.")
 	}
-	if (len(m.connIDs) + con) > m.setting.MaxConnection {
+	active := m.connCount()
+	if (active + con) > m.setting.MaxConnection {
 		return errors.New("There are active sessions. Can't create more than allowed no of sessions.")
 	}
-	connLeft := m.setting.MaxConnection - len(m.connIDs)
+	connLeft := m.setting.MaxConnection - active
 	n := 0
 	if connLeft >= con {
 		n = con
@@ -290,40 +433,94 @@ func (m *Manager) AddConnection(noOfConnection ...int) error {
 }
 
 func (m *Manager) RemoveConnection(conID ...string) error {
+	// Connections are detached under the lock, then closed, sends
+	// picking the others meanwhile.
+	var closers []io.Closer
+	m.mu.Lock()
 	if len(conID) > 0 {
 		for _, id := range conID {
 			if con, ok := m.connections[id]; ok {
-				err := con.Close()
-				if err != nil {
-					return err
-				}
+				closers = append(closers, con)
 				m.connIDs = remove(m.connIDs, id)
 				delete(m.connections, id)
 			}
+			if rx, ok := m.receivers[id]; ok {
+				closers = append(closers, rx)
+				delete(m.receivers, id)
+			}
 		}
 	} else {
 		for id, con := range m.connections {
-			err := con.Close()
-			if err != nil {
-				return err
-			}
+			closers = append(closers, con)
 			m.connIDs = remove(m.connIDs, id)
 			delete(m.connections, id)
 		}
 	}
+	m.mu.Unlock()
+	for _, c := range closers {
+		if err := c.Close(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// Rebind closes all connections and binds them again. Unlike Close,
+// it keeps the messages held by the local scheduler and the capture
+// file.
 func (m *Manager) Rebind() error {
-	err := m.Close()
+	err := m.closeConnections()
 	if err != nil {
 		return err
 	}
-	m.connections = make(map[string]*Transceiver)
+	m.mu.Lock()
+	m.connections = make(map[string]Connection)
+	m.receivers = make(map[string]*Receiver)
 	m.connIDs = []string{}
+	m.mu.Unlock()
 	return m.Start()
 }
 
+// ReloadTLS reloads the CA bundle and client certificate of the TLS
+// setting, then replaces the bound connections one at a time so that
+// the remaining ones keep serving traffic. If a new bind fails the
+// error is returned: the connections already replaced keep their new
+// bind and the others their old one, so ReloadTLS may be called again.
+func (m *Manager) ReloadTLS() error {
+	if m.tls == nil {
+		return errors.New("TLS is not configured")
+	}
+	if err := m.tls.Reload(); err != nil {
+		return errors.NewE(err, "Unable to reload TLS configuration", "manager:reload_tls")
+	}
+	m.mu.RLock()
+	old := append([]string(nil), m.connIDs...)
+	var rxs []string
+	for id := range m.receivers {
+		rxs = append(rxs, id)
+	}
+	m.mu.RUnlock()
+	for _, id := range old {
+		if err := m.SetupConnection(); err != nil {
+			return errors.NewE(err, "Unable to rebind SMPP connection", "manager:reload_tls")
+		}
+		if err := m.RemoveConnection(id); err != nil {
+			return err
+		}
+		log.Info().Str("conn_id", id).Msg("SMPP Connection replaced after TLS reload")
+	}
+	for _, id := range rxs {
+		if err := m.SetupReceiver(); err != nil {
+			return errors.NewE(err, "Unable to rebind SMPP receiver connection", "manager:reload_tls")
+		}
+		if err := m.RemoveConnection(id); err != nil {
+			return err
+		}
+		log.Info().Str("conn_id", id).Msg("SMPP Receiver connection replaced after TLS reload")
+	}
+	return nil
+}
+
 func (m *Manager) GetPart(key string) (any, bool) {
 	return m.parts.Get(key)
 }
@@ -360,48 +557,76 @@ func (m *Manager) GetMessages() (messages []any) {
 	return
 }
 
+// SetupConnection binds a new connection able to submit messages, a
+// Transceiver or a Transmitter depending on the bind mode. In
+// receiver-only mode it binds a Receiver instead.
 func (m *Manager) SetupConnection() error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	// make persistent connection
-	tx := &Transceiver{
-		ID:                 xid.New().String(),
-		Addr:               m.setting.URL,
-		User:               m.setting.Auth.SystemID,
-		Passwd:             m.setting.Auth.Password,
-		Handler:            m.setting.HandlePDU,
-		SystemType:         m.setting.Auth.SystemType,
-		EnquireLink:        m.setting.EnquiryInterval,
-		EnquireLinkTimeout: m.setting.EnquiryTimeout,
-		RespTimeout:        15 * time.Minute,
-		BindInterval:       10 * time.Second,
-		manager:            m,
+	if m.setting.BindMode == BindReceiver {
+		return m.SetupReceiver()
 	}
-
+	var rateLimiter RateLimiter
 	if m.setting.Throttle != 0 {
-		rateLimiter := rate.NewLimiter(rate.Limit(m.setting.Throttle), 1)
-		tx.RateLimiter = rateLimiter
+		rateLimiter = rate.NewLimiter(rate.Limit(m.setting.Throttle), 1)
+	} else {
+		rateLimiter = rate.NewLimiter(rate.Limit(100), 1)
+	}
+	// make persistent connection
+	id := xid.New().String()
+	var tx Connection
+	var conn <-chan ConnStatus
+	if m.setting.BindMode == BindTransmitter || m.setting.BindMode == BindSplit {
+		t := &Transmitter{
+			ID:                 id,
+			Addr:               m.setting.URL,
+			User:               m.setting.Auth.SystemID,
+			Passwd:             m.setting.Auth.Password,
+			SystemType:         m.setting.Auth.SystemType,
+			EnquireLink:        m.setting.EnquiryInterval,
+			EnquireLinkTimeout: m.setting.EnquiryTimeout,
+			RespTimeout:        15 * time.Minute,
+			BindInterval:       10 * time.Second,
+			TLS:                m.tls.Config(),
+			RateLimiter:        rateLimiter,
+			Capture:            m.setting.Capture,
+			manager:            m,
+		}
+		tx, conn = t, t.Bind()
 	} else {
-		rateLimiter := rate.NewLimiter(rate.Limit(100), 1)
-		tx.RateLimiter = rateLimiter
+		t := &Transceiver{
+			ID:                 id,
+			Addr:               m.setting.URL,
+			User:               m.setting.Auth.SystemID,
+			Passwd:             m.setting.Auth.Password,
+			Handler:            m.setting.HandlePDU,
+			SystemType:         m.setting.Auth.SystemType,
+			EnquireLink:        m.setting.EnquiryInterval,
+			EnquireLinkTimeout: m.setting.EnquiryTimeout,
+			RespTimeout:        15 * time.Minute,
+			BindInterval:       10 * time.Second,
+			TLS:                m.tls.Config(),
+			RateLimiter:        rateLimiter,
+			Capture:            m.setting.Capture,
+			manager:            m,
+		}
+		tx, conn = t, t.Bind()
 	}
-	conn := tx.Bind()
 	// check initial connection status
 	var status ConnStatus
 	if status = <-conn; status.Error() != nil {
+		tx.Close()
 		return status.Error()
 	}
 	go func(m *Manager) {
 		for c := range conn {
 			if c.Status() == Connected && len(m.messagesToRetry) > 0 {
-				for payload, t := range m.messagesToRetry {
+				for payload, connID := range m.messagesToRetry {
 					switch payload := payload.(type) {
 					case Message:
 						log.Warn().Bool("resend", true).Str("message_id", payload.ID).Msg("Resending message")
 					case *Message:
 						log.Warn().Bool("resend", true).Str("message_id", payload.ID).Msg("Resending message")
 					}
-					_, err := m.Send(payload, t.ID)
+					_, err := m.Send(payload, connID)
 					if err == nil {
 						m.mu.Lock()
 						delete(m.messagesToRetry, payload)
@@ -411,86 +636,184 @@ func (m *Manager) SetupConnection() error {
 			}
 		}
 	}(m)
-	m.connIDs = append(m.connIDs, tx.ID)
-	m.connections[tx.ID] = tx
+	// Sends keep picking the other connections while binding.
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.connIDs = append(m.connIDs, id)
+	m.connections[id] = tx
 	return nil
 }
 
+// SetupReceiver binds a new Receiver. Incoming messages and delivery
+// receipts are passed to the HandlePDU of the setting, so receipts
+// update the state of messages sent through the transmitter binds.
+func (m *Manager) SetupReceiver() error {
+	rx := &Receiver{
+		ID:                 xid.New().String(),
+		Addr:               m.setting.URL,
+		User:               m.setting.Auth.SystemID,
+		Passwd:             m.setting.Auth.Password,
+		SystemType:         m.setting.Auth.SystemType,
+		EnquireLink:        m.setting.EnquiryInterval,
+		EnquireLinkTimeout: m.setting.EnquiryTimeout,
+		BindInterval:       10 * time.Second,
+		MergeInterval:      m.setting.MergeInterval,
+		TLS:                m.tls.Config(),
+		Handler:            m.setting.HandlePDU,
+		Capture:            m.setting.Capture,
+		manager:            m,
+	}
+	conn := rx.Bind()
+	if status := <-conn; status.Error() != nil {
+		rx.Close()
+		return status.Error()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.receivers[rx.ID] = rx
+	return nil
+}
+
+// GetConnection picks one of the given connections, or one of all
+// managed connections, using the balancer. The returned value is a
+// Connection.
 func (m *Manager) GetConnection(conIds ...string) (any, error) {
+	_, con, err := m.pick(conIds...)
+	if err != nil {
+		return nil, err
+	}
+	return con, nil
+}
+
+// connCount returns the number of sending connections.
+func (m *Manager) connCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.connIDs)
+}
+
+func (m *Manager) pick(conIds ...string) (string, Connection, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	var err error
 	var pickedID string
 	if len(conIds) > 0 { // pick among custom
 		pickedID, err = m.balancer.Pick(conIds)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 		if con, ok := m.connections[pickedID]; ok {
-			return con, nil
+			return pickedID, con, nil
 		}
 	}
 
 	// pick among managing session
 	pickedID, err = m.balancer.Pick(m.connIDs)
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 	if con, ok := m.connections[pickedID]; ok {
-		return con, nil
+		return pickedID, con, nil
 	}
-	return nil, errors.New("no connection")
+	return "", nil, errors.New("no connection")
 }
 
 func (m *Manager) Send(payload any, connectionId ...string) (any, error) {
-	if len(m.connIDs) == 0 {
-		err := m.Start()
-		if err != nil {
-			return nil, err
-		}
-	}
-	t, err := m.GetConnection(connectionId...)
-	if err != nil {
-		return nil, err
+	if m.setting.BindMode == BindReceiver {
+		return nil, errors.New("Unable to send with receiver-only bind mode")
 	}
-	tx := t.(*Transceiver)
 	var sms *Message
 	switch payload := payload.(type) {
 	case Message:
 		sms = &payload
 	case *Message:
 		sms = payload
+	default:
+		return nil, errors.New("Unsupported payload")
 	}
 	if sms.ID == "" {
 		sms.ID = xid.New().String()
 	}
+	now := time.Now()
+	if !sms.ValidUntil.IsZero() && !sms.ValidUntil.After(now) {
+		return nil, m.reject(sms, errors.New("Message validity expired before sending"))
+	}
+	country := sms.Country
+	if country == "" {
+		country = m.setting.DefaultCountry
+	}
+	src, srcTon, srcNpi, err := resolveAddr(sms.From, country, sms.SourceTON, sms.SourceNPI)
+	if err != nil {
+		return nil, m.reject(sms, errors.NewE(err, "Invalid source address", "manager:send"))
+	}
+	if sms.To == "" {
+		return nil, m.reject(sms, errors.New("Destination address is required"))
+	}
+	dst, destTon, destNpi, err := resolveAddr(sms.To, country, sms.DestTON, sms.DestNPI)
+	if err == nil && destTon == number.TONAlphanumeric && sms.DestTON == nil {
+		err = number.ErrInvalid
+	}
+	if err != nil {
+		return nil, m.reject(sms, errors.NewE(err, "Invalid destination address", "manager:send"))
+	}
+	// A held message is due when its timer fires, it is not held again.
+	if m.setting.LocalSchedule && sms.MessageStatus != SCHEDULED && sms.ScheduleAt.After(now) {
+		return m.hold(sms, connectionId...), nil
+	}
+	if m.connCount() == 0 {
+		err := m.Start()
+		if err != nil {
+			return nil, err
+		}
+	}
+	txID, tx, err := m.pick(connectionId...)
+	if err != nil {
+		return nil, err
+	}
 	encodedText, isLongMsg := pdutext.FindCoding([]byte(sms.Message))
-	srcTon, srcNpi := parseSrcPhone(sms.From)
-	destTon, destNpi := parseDestPhone(sms.To)
+	if sms.Coding != "" {
+		coding, err := pdutext.ParseCoding(sms.Coding)
+		if err != nil {
+			return nil, err
+		}
+		encodedText, isLongMsg = coding.Encode([]byte(sms.Message))
+	}
+	scheduleDeliveryTime := m.setting.ScheduleDeliveryTime
+	if !m.setting.LocalSchedule && sms.ScheduleAt.After(now) {
+		scheduleDeliveryTime = m.smppTime(sms.ScheduleAt)
+	}
+	var validityPeriod string
+	if !sms.ValidUntil.IsZero() {
+		validityPeriod = m.smppTime(sms.ValidUntil)
+	}
 	shortMessage := &ShortMessage{
-		Src:           sms.From,
+		Src:           src,
 		SourceAddrTON: srcTon,
 		SourceAddrNPI: srcNpi,
 
-		Dst:         sms.To,
+		Dst:         dst,
 		DestAddrTON: destTon,
 		DestAddrNPI: destNpi,
 
-		Text: encodedText,
+		Text:      encodedText,
+		TLVFields: sms.TLVs,
 
-		Validity: m.setting.Validity,
-		Register: m.setting.Register,
+		Validity:       m.setting.Validity,
+		ValidityPeriod: validityPeriod,
+		Register:       m.setting.Register,
 
 		ServiceType:          m.setting.ServiceType,
 		ESMClass:             m.setting.ESMClass,
 		ProtocolID:           m.setting.ProtocolID,
 		PriorityFlag:         m.setting.PriorityFlag,
-		ScheduleDeliveryTime: m.setting.ScheduleDeliveryTime,
+		ScheduleDeliveryTime: scheduleDeliveryTime,
 		ReplaceIfPresentFlag: m.setting.ReplaceIfPresentFlag,
 	}
 	if isLongMsg {
 		sm, err := tx.SubmitLongMsg(shortMessage)
 		if err != nil {
 			m.mu.Lock()
-			m.messagesToRetry[sms] = tx
+			m.messagesToRetry[sms] = txID
 			m.mu.Unlock()
 			sms.MessageStatus = FAILED
 			sms.FailedAt = time.Now()
@@ -509,11 +832,11 @@ func (m *Manager) Send(payload any, connectionId ...string) (any, error) {
 				MessageID:    s.RespID(),
 			}
 			if s.Resp().Header().Status == pdu.ESME_ROK {
-				part.MessageStatus = "SENT"
+				part.MessageStatus = SENT
 				part.SentAt = time.Now()
 				sms.sentParts.Add(1)
 			} else {
-				part.MessageStatus = "FAILED"
+				part.MessageStatus = FAILED
 				part.FailedAt = time.Now()
 				part.Error = s.Resp().Header().Status.Error()
 				sms.failedParts.Add(1)
@@ -528,13 +851,15 @@ func (m *Manager) Send(payload any, connectionId ...string) (any, error) {
 			}
 		}
 		sms.Error = ""
+		sms.MessageStatus = SENT
+		sms.SentAt = time.Now()
 		m.messages.Set(sms.ID, sms)
 		m.Report(sms)
 	} else {
 		s, err := tx.Submit(shortMessage)
 		if err != nil {
 			m.mu.Lock()
-			m.messagesToRetry[sms] = tx
+			m.messagesToRetry[sms] = txID
 			m.mu.Unlock()
 			sms.MessageStatus = FAILED
 			sms.FailedAt = time.Now()
@@ -551,17 +876,17 @@ func (m *Manager) Send(payload any, connectionId ...string) (any, error) {
 			MessageID:    s.RespID(),
 		}
 		if s.Resp().Header().Status == pdu.ESME_ROK {
-			part.MessageStatus = "SENT"
+			part.MessageStatus = SENT
 			part.SentAt = time.Now()
 			sms.sentParts.Add(1)
 		} else {
-			part.MessageStatus = "FAILED"
+			part.MessageStatus = FAILED
 			part.FailedAt = time.Now()
 			part.Error = s.Resp().Header().Status.Error()
 			sms.failedParts.Add(1)
 		}
 		sms.Error = ""
-		sms.MessageStatus = "SENT"
+		sms.MessageStatus = SENT
 		sms.SentAt = time.Now()
 		m.messageParts.Set(s.RespID(), sms.ID)
 		m.parts.Set(part.MessageID, part)
@@ -573,6 +898,57 @@ func (m *Manager) Send(payload any, connectionId ...string) (any, error) {
 	return curSms, nil
 }
 
+// reject marks sms as failed before it is submitted.
+func (m *Manager) reject(sms *Message, err error) error {
+	sms.MessageStatus = FAILED
+	sms.FailedAt = time.Now()
+	sms.Error = err.Error()
+	m.messages.Set(sms.ID, sms)
+	m.Report(sms)
+	return err
+}
+
+// hold keeps sms until its ScheduleAt and sends it then. It is used
+// when LocalSchedule is set.
+func (m *Manager) hold(sms *Message, connectionId ...string) *Message {
+	sms.MessageStatus = SCHEDULED
+	m.messages.Set(sms.ID, sms)
+	m.Report(sms)
+	m.scheduler.schedule(sms.ID, sms.ScheduleAt, func() {
+		if _, err := m.Send(sms, connectionId...); err != nil {
+			log.Error().Err(err).Str("message_id", sms.ID).Msg("Unable to send scheduled message")
+		}
+	})
+	return sms
+}
+
+// CancelScheduled cancels a message held by the local scheduler. It
+// reports whether the message was pending.
+func (m *Manager) CancelScheduled(id string) bool {
+	if !m.scheduler.cancel(id) {
+		return false
+	}
+	if sms, ok := m.messages.Get(id); ok {
+		sms.MessageStatus = CANCELLED
+		m.messages.Del(id)
+		m.Report(sms)
+	}
+	return true
+}
+
+// Scheduled returns the number of messages held by the local scheduler.
+func (m *Manager) Scheduled() int {
+	return m.scheduler.len()
+}
+
+// smppTime formats t for schedule_delivery_time and validity_period.
+func (m *Manager) smppTime(t time.Time) string {
+	if m.setting.RelativeTime {
+		return pdufield.RelativeDate(time.Until(t))
+	}
+	return pdufield.AbsoluteDate(t)
+}
+
 func (m *Manager) Wait() {
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
@@ -588,68 +964,82 @@ func (m *Manager) Wait() {
 	fmt.Println("Exiting SMPP Manager")
 }
 
+// Close closes the given connection, or all of them, stops the local
+// scheduler and closes the capture file: messages held until due are
+// lost, and are not sent again when the manager is restarted.
 func (m *Manager) Close(connectionId ...string) error {
 	if len(connectionId) > 0 {
-		if con, ok := m.connections[connectionId[0]]; ok {
-			err := con.Close()
-			if err != nil {
-				return err
-			}
-			log.Info().Str("conn_id", con.ID).Msg("SMPP Connection Closing")
-		}
-	} else {
-		for _, conn := range m.connections {
-			err := conn.Close()
-			if err != nil {
-				return err
-			}
-			log.Info().Str("conn_id", conn.ID).Msg("SMPP Connection Closing")
+		return m.closeConnections(connectionId[0])
+	}
+	m.scheduler.stop()
+	err := m.closeConnections()
+	if c, ok := m.setting.Capture.(io.Closer); ok {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
 		}
 	}
-
-	return nil
+	return err
 }
 
-func parseSrcPhone(phone string) (ton uint8, npi uint8) {
-	if strings.HasPrefix(phone, "+") {
-		ton = 1
-		npi = 1
-		return
+// closeConnections closes the given connection, or all of them.
+func (m *Manager) closeConnections(connectionId ...string) error {
+	type closing struct {
+		id string
+		c  io.Closer
+		rx bool
 	}
-
-	if utf8.RuneCountInString(phone) <= 5 {
-		ton = 3
-		npi = 0
-		return
+	var closers []closing
+	m.mu.RLock()
+	if len(connectionId) > 0 {
+		if con, ok := m.connections[connectionId[0]]; ok {
+			closers = append(closers, closing{id: connectionId[0], c: con})
+		}
+		if rx, ok := m.receivers[connectionId[0]]; ok {
+			closers = append(closers, closing{id: rx.ID, c: rx, rx: true})
+		}
+	} else {
+		for id, con := range m.connections {
+			closers = append(closers, closing{id: id, c: con})
+		}
+		for _, rx := range m.receivers {
+			closers = append(closers, closing{id: rx.ID, c: rx, rx: true})
+		}
 	}
-	if isLetter(phone) {
-		ton = 5
-		npi = 0
-		return
+	m.mu.RUnlock()
+	for _, c := range closers {
+		if err := c.c.Close(); err != nil {
+			return err
+		}
+		if c.rx {
+			log.Info().Str("conn_id", c.id).Msg("SMPP Receiver Connection Closing")
+		} else {
+			log.Info().Str("conn_id", c.id).Msg("SMPP Connection Closing")
+		}
 	}
-	ton = 1
-	npi = 1
-	return
+	return nil
 }
 
-func parseDestPhone(phone string) (ton uint8, npi uint8) {
-	if strings.HasPrefix(phone, "+") {
-		ton = 1
-		npi = 1
-		return
+// resolveAddr normalizes addr and detects its TON and NPI, see
+// number.Parse. When ton or npi is set, addr is sent as is and they
+// override the detected values. An empty addr is left to the SMSC.
+func resolveAddr(addr, country string, ton, npi *uint8) (string, uint8, uint8, error) {
+	if addr == "" {
+		return "", number.TONUnknown, number.NPIUnknown, nil
 	}
-	ton = 0
-	npi = 1
-	return
-}
-
-func isLetter(s string) bool {
-	for _, r := range s {
-		if !unicode.IsLetter(r) {
-			return false
+	n, err := number.Parse(addr, country)
+	if ton == nil && npi == nil {
+		if err != nil {
+			return "", 0, 0, err
 		}
+		return n.Address(), n.TON, n.NPI, nil
 	}
-	return true
+	if ton != nil {
+		n.TON = *ton
+	}
+	if npi != nil {
+		n.NPI = *npi
+	}
+	return addr, n.TON, n.NPI, nil
 }
 
 func remove(s []string, r string) []string {
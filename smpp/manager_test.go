@@ -0,0 +1,153 @@
+package smpp
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oarkflow/protocol/smpp/balancer"
+	"github.com/oarkflow/protocol/smpp/capture"
+	"github.com/oarkflow/protocol/smpp/pdu"
+)
+
+type fakeConn struct {
+	Connection
+	closed bool
+}
+
+func (c *fakeConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestManagerRemoveConnectionWhilePicking(t *testing.T) {
+	m := &Manager{
+		connections: make(map[string]Connection),
+		receivers:   make(map[string]*Receiver),
+		balancer:    &balancer.RoundRobin{},
+	}
+	conns := make(map[string]*fakeConn)
+	for i := 0; i < 50; i++ {
+		id := fmt.Sprint(i)
+		conns[id] = &fakeConn{}
+		m.connIDs = append(m.connIDs, id)
+		m.connections[id] = conns[id]
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			m.GetConnection()
+		}
+	}()
+	for id := range conns {
+		if err := m.RemoveConnection(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+	for id, c := range conns {
+		if !c.closed {
+			t.Fatalf("want connection %s closed", id)
+		}
+	}
+	if _, err := m.GetConnection(); err == nil {
+		t.Fatal("want error without connection")
+	}
+}
+
+func TestManagerCloseStopsScheduler(t *testing.T) {
+	m := &Manager{
+		connections: make(map[string]Connection),
+		receivers:   make(map[string]*Receiver),
+		scheduler:   newScheduler(),
+	}
+	fired := make(chan struct{})
+	m.scheduler.schedule("1", time.Now().Add(50*time.Millisecond), func() { close(fired) })
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := m.Scheduled(); n != 0 {
+		t.Fatalf("want no held message after Close, have %d", n)
+	}
+	select {
+	case <-fired:
+		t.Fatal("want held message dropped after Close")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestNewManagerCaptureFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "smpp.cap")
+	_, err := NewManager(Setting{
+		URL:         "localhost:2775",
+		TLS:         &TLSSetting{CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		CaptureFile: path,
+	})
+	if err == nil {
+		t.Fatal("want error for missing CA file")
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("want capture file not opened on error, have %v", err)
+	}
+}
+
+func TestConnWriteCapture(t *testing.T) {
+	var records []capture.Record
+	client, server := net.Pipe()
+	c := &conn{
+		rwc: client,
+		w:   bufio.NewWriter(client),
+		capture: capture.CapturerFunc(func(r capture.Record) error {
+			records = append(records, r)
+			return nil
+		}),
+	}
+	go func() {
+		b := make([]byte, 1024)
+		server.Read(b)
+		server.Close()
+	}()
+	if err := c.Write(pdu.NewEnquireLink(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Direction != capture.Outbound {
+		t.Fatalf("want sent PDU captured, have %v", records)
+	}
+	if err := c.Write(pdu.NewEnquireLink(nil)); err == nil {
+		t.Fatal("want error writing to closed connection")
+	}
+	if len(records) != 1 {
+		t.Fatalf("want unsent PDU not captured, have %d records", len(records))
+	}
+}
+
+type fakeWriter struct {
+	Conn
+	written []pdu.Body
+}
+
+func (c *fakeWriter) Write(p pdu.Body) error {
+	c.written = append(c.written, p)
+	return nil
+}
+
+func TestClientWriteManager(t *testing.T) {
+	m := &Manager{}
+	conn := &fakeWriter{}
+	c := &client{conn: &connSwitch{}, manager: m}
+	c.conn.Set(conn)
+	var seq pdu.Sequence
+	if err := c.Write(pdu.NewQuerySM(&seq)); err != nil {
+		t.Fatal(err)
+	}
+	if p := conn.written[0]; p.Manager() != m {
+		t.Fatalf("want PDU written with the manager, have %v", p.Manager())
+	}
+}
@@ -0,0 +1,116 @@
+package number
+
+import "strings"
+
+// Country is the numbering plan of a country used to normalize its
+// national numbers.
+type Country struct {
+	Region      string // ISO 3166-1 alpha-2 code.
+	CallingCode string // E.164 country code.
+	Trunk       string // National prefix stripped from national numbers.
+	IDD         string // International prefix, "00" if empty.
+	MinLen      int    // Minimum length of the national significant number.
+	MaxLen      int    // Maximum length of the national significant number.
+}
+
+func (c Country) idd() string {
+	if c.IDD == "" {
+		return "00"
+	}
+	return c.IDD
+}
+
+// countries is ordered by calling code, the first region of a shared
+// calling code is the one used to validate its numbers.
+var countries = []Country{
+	{Region: "US", CallingCode: "1", Trunk: "1", IDD: "011", MinLen: 10, MaxLen: 10},
+	{Region: "CA", CallingCode: "1", Trunk: "1", IDD: "011", MinLen: 10, MaxLen: 10},
+	{Region: "RU", CallingCode: "7", Trunk: "8", IDD: "810", MinLen: 10, MaxLen: 10},
+	{Region: "KZ", CallingCode: "7", Trunk: "8", IDD: "810", MinLen: 10, MaxLen: 10},
+	{Region: "EG", CallingCode: "20", Trunk: "0", MinLen: 8, MaxLen: 10},
+	{Region: "ZA", CallingCode: "27", Trunk: "0", MinLen: 9, MaxLen: 9},
+	{Region: "GR", CallingCode: "30", MinLen: 10, MaxLen: 10},
+	{Region: "NL", CallingCode: "31", Trunk: "0", MinLen: 9, MaxLen: 9},
+	{Region: "BE", CallingCode: "32", Trunk: "0", MinLen: 8, MaxLen: 9},
+	{Region: "FR", CallingCode: "33", Trunk: "0", MinLen: 9, MaxLen: 9},
+	{Region: "ES", CallingCode: "34", MinLen: 9, MaxLen: 9},
+	{Region: "HU", CallingCode: "36", Trunk: "06", MinLen: 8, MaxLen: 9},
+	{Region: "IT", CallingCode: "39", MinLen: 6, MaxLen: 11},
+	{Region: "RO", CallingCode: "40", Trunk: "0", MinLen: 9, MaxLen: 9},
+	{Region: "CH", CallingCode: "41", Trunk: "0", MinLen: 9, MaxLen: 9},
+	{Region: "AT", CallingCode: "43", Trunk: "0", MinLen: 4, MaxLen: 13},
+	{Region: "GB", CallingCode: "44", Trunk: "0", MinLen: 9, MaxLen: 10},
+	{Region: "DK", CallingCode: "45", MinLen: 8, MaxLen: 8},
+	{Region: "SE", CallingCode: "46", Trunk: "0", MinLen: 7, MaxLen: 9},
+	{Region: "NO", CallingCode: "47", MinLen: 8, MaxLen: 8},
+	{Region: "PL", CallingCode: "48", MinLen: 9, MaxLen: 9},
+	{Region: "DE", CallingCode: "49", Trunk: "0", MinLen: 6, MaxLen: 13},
+	{Region: "PE", CallingCode: "51", Trunk: "0", MinLen: 8, MaxLen: 9},
+	{Region: "MX", CallingCode: "52", MinLen: 10, MaxLen: 10},
+	{Region: "AR", CallingCode: "54", Trunk: "0", MinLen: 10, MaxLen: 11},
+	{Region: "BR", CallingCode: "55", Trunk: "0", MinLen: 10, MaxLen: 11},
+	{Region: "CL", CallingCode: "56", MinLen: 9, MaxLen: 9},
+	{Region: "CO", CallingCode: "57", MinLen: 10, MaxLen: 10},
+	{Region: "MY", CallingCode: "60", Trunk: "0", MinLen: 8, MaxLen: 10},
+	{Region: "AU", CallingCode: "61", Trunk: "0", IDD: "0011", MinLen: 9, MaxLen: 9},
+	{Region: "ID", CallingCode: "62", Trunk: "0", MinLen: 8, MaxLen: 12},
+	{Region: "PH", CallingCode: "63", Trunk: "0", MinLen: 8, MaxLen: 10},
+	{Region: "NZ", CallingCode: "64", Trunk: "0", MinLen: 8, MaxLen: 10},
+	{Region: "SG", CallingCode: "65", MinLen: 8, MaxLen: 8},
+	{Region: "TH", CallingCode: "66", Trunk: "0", MinLen: 8, MaxLen: 9},
+	{Region: "JP", CallingCode: "81", Trunk: "0", IDD: "010", MinLen: 9, MaxLen: 10},
+	{Region: "KR", CallingCode: "82", Trunk: "0", IDD: "001", MinLen: 8, MaxLen: 10},
+	{Region: "VN", CallingCode: "84", Trunk: "0", MinLen: 9, MaxLen: 10},
+	{Region: "CN", CallingCode: "86", Trunk: "0", MinLen: 9, MaxLen: 11},
+	{Region: "TR", CallingCode: "90", Trunk: "0", MinLen: 10, MaxLen: 10},
+	{Region: "IN", CallingCode: "91", Trunk: "0", MinLen: 10, MaxLen: 10},
+	{Region: "PK", CallingCode: "92", Trunk: "0", MinLen: 9, MaxLen: 10},
+	{Region: "AF", CallingCode: "93", Trunk: "0", MinLen: 9, MaxLen: 9},
+	{Region: "LK", CallingCode: "94", Trunk: "0", MinLen: 9, MaxLen: 9},
+	{Region: "MM", CallingCode: "95", Trunk: "0", MinLen: 7, MaxLen: 10},
+	{Region: "IR", CallingCode: "98", Trunk: "0", MinLen: 10, MaxLen: 10},
+	{Region: "MA", CallingCode: "212", Trunk: "0", MinLen: 9, MaxLen: 9},
+	{Region: "GH", CallingCode: "233", Trunk: "0", MinLen: 9, MaxLen: 9},
+	{Region: "NG", CallingCode: "234", Trunk: "0", MinLen: 8, MaxLen: 10},
+	{Region: "ET", CallingCode: "251", Trunk: "0", MinLen: 9, MaxLen: 9},
+	{Region: "KE", CallingCode: "254", Trunk: "0", MinLen: 9, MaxLen: 9},
+	{Region: "TZ", CallingCode: "255", Trunk: "0", MinLen: 9, MaxLen: 9},
+	{Region: "UG", CallingCode: "256", Trunk: "0", MinLen: 9, MaxLen: 9},
+	{Region: "PT", CallingCode: "351", MinLen: 9, MaxLen: 9},
+	{Region: "IE", CallingCode: "353", Trunk: "0", MinLen: 7, MaxLen: 9},
+	{Region: "FI", CallingCode: "358", Trunk: "0", MinLen: 5, MaxLen: 12},
+	{Region: "UA", CallingCode: "380", Trunk: "0", MinLen: 9, MaxLen: 9},
+	{Region: "HK", CallingCode: "852", IDD: "001", MinLen: 8, MaxLen: 8},
+	{Region: "BD", CallingCode: "880", Trunk: "0", MinLen: 8, MaxLen: 10},
+	{Region: "TW", CallingCode: "886", Trunk: "0", IDD: "002", MinLen: 8, MaxLen: 9},
+	{Region: "JO", CallingCode: "962", Trunk: "0", MinLen: 8, MaxLen: 9},
+	{Region: "KW", CallingCode: "965", MinLen: 8, MaxLen: 8},
+	{Region: "SA", CallingCode: "966", Trunk: "0", MinLen: 8, MaxLen: 9},
+	{Region: "OM", CallingCode: "968", MinLen: 8, MaxLen: 8},
+	{Region: "AE", CallingCode: "971", Trunk: "0", MinLen: 8, MaxLen: 9},
+	{Region: "IL", CallingCode: "972", Trunk: "0", MinLen: 8, MaxLen: 9},
+	{Region: "BH", CallingCode: "973", MinLen: 8, MaxLen: 8},
+	{Region: "QA", CallingCode: "974", MinLen: 8, MaxLen: 8},
+	{Region: "NP", CallingCode: "977", Trunk: "0", MinLen: 8, MaxLen: 10},
+}
+
+var (
+	byRegion      = make(map[string]Country, len(countries))
+	byCallingCode = make(map[string]Country, len(countries))
+)
+
+func init() {
+	for _, c := range countries {
+		byRegion[c.Region] = c
+		if _, ok := byCallingCode[c.CallingCode]; !ok {
+			byCallingCode[c.CallingCode] = c
+		}
+	}
+}
+
+// Lookup returns the numbering plan of the country with the given
+// ISO 3166-1 alpha-2 code.
+func Lookup(region string) (Country, bool) {
+	c, ok := byRegion[strings.ToUpper(strings.TrimSpace(region))]
+	return c, ok
+}
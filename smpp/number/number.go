@@ -0,0 +1,212 @@
+// Package number normalizes SMPP source and destination addresses and
+// detects their type of number (TON) and numbering plan (NPI).
+//
+// Phone numbers are normalized to E.164 using the numbering plan of a
+// default country for numbers in national format. Digit strings too
+// short to be phone numbers are short codes, anything else is an
+// alphanumeric sender ID.
+package number
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/oarkflow/protocol/smpp/encoding"
+)
+
+// Kind is the kind of an address.
+type Kind uint8
+
+// Supported kinds of address.
+const (
+	Unknown       Kind = iota // Digits of an unknown numbering plan, sent as is.
+	International             // E.164 number.
+	ShortCode                 // Network specific short code.
+	Alphanumeric              // Alphanumeric sender ID.
+)
+
+var kindName = map[Kind]string{
+	Unknown:       "unknown",
+	International: "international",
+	ShortCode:     "short_code",
+	Alphanumeric:  "alphanumeric",
+}
+
+func (k Kind) String() string {
+	return kindName[k]
+}
+
+// TON values, see SMPP 3.4 section 5.2.5.
+const (
+	TONUnknown         uint8 = 0
+	TONInternational   uint8 = 1
+	TONNational        uint8 = 2
+	TONNetworkSpecific uint8 = 3
+	TONSubscriber      uint8 = 4
+	TONAlphanumeric    uint8 = 5
+	TONAbbreviated     uint8 = 6
+)
+
+// NPI values, see SMPP 3.4 section 5.2.6.
+const (
+	NPIUnknown uint8 = 0
+	NPIISDN    uint8 = 1 // E.163/E.164
+)
+
+const (
+	// MaxShortCode is the maximum length of a short code.
+	MaxShortCode = 8
+	// MaxAlphanumeric is the maximum length, in GSM 7-bit septets,
+	// of an alphanumeric sender ID.
+	MaxAlphanumeric = 11
+	// maxE164 is the maximum length of an E.164 number without "+".
+	maxE164 = 15
+)
+
+var (
+	ErrEmpty        = errors.New("empty address")
+	ErrInvalid      = errors.New("invalid number")
+	ErrLength       = errors.New("invalid number length")
+	ErrAlphanumeric = errors.New("invalid alphanumeric sender")
+)
+
+// Number is a parsed address.
+type Number struct {
+	Kind   Kind
+	Value  string // E.164 with "+" if International, as given otherwise.
+	Region string // ISO 3166-1 alpha-2 code of International numbers, if known.
+	TON    uint8
+	NPI    uint8
+}
+
+// String returns the normalized address.
+func (n Number) String() string {
+	return n.Value
+}
+
+// Address returns the address as sent in source_addr and
+// destination_addr, i.e. international numbers without "+".
+func (n Number) Address() string {
+	return strings.TrimPrefix(n.Value, "+")
+}
+
+// Parse parses addr. Numbers in national format, or with the
+// international prefix of the country, are normalized using the
+// numbering plan of region, an ISO 3166-1 alpha-2 code. Without a
+// known region they are of Unknown kind, except for "00" prefixed
+// numbers which are international.
+func Parse(addr, region string) (Number, error) {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return Number{}, ErrEmpty
+	}
+	d := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, addr)
+	if strings.HasPrefix(d, "+") {
+		if !isDigits(d[1:]) {
+			return Number{}, fmt.Errorf("%w: %q", ErrInvalid, addr)
+		}
+		return international(d[1:], addr)
+	}
+	if !isDigits(d) {
+		return alphanumeric(addr)
+	}
+
+	c, ok := Lookup(region)
+	if !ok {
+		switch {
+		case strings.HasPrefix(d, "00"):
+			return international(d[2:], addr)
+		case len(d) <= MaxShortCode:
+			return shortCode(d), nil
+		}
+		return Number{Kind: Unknown, Value: d, TON: TONUnknown, NPI: NPIISDN}, nil
+	}
+	if strings.HasPrefix(d, c.idd()) {
+		return international(d[len(c.idd()):], addr)
+	}
+	// The national significant number is tried with the trunk prefix
+	// stripped, then with the calling code stripped for numbers given
+	// without "+", then as is for numbers dialed without trunk prefix.
+	for _, prefix := range []string{c.Trunk, c.CallingCode, ""} {
+		if nsn, ok := strings.CutPrefix(d, prefix); ok && c.valid(nsn) {
+			return e164(c, nsn), nil
+		}
+	}
+	if len(d) <= MaxShortCode {
+		return shortCode(d), nil
+	}
+	return Number{}, fmt.Errorf("%w: %q is not a valid %s number", ErrLength, addr, c.Region)
+}
+
+// international parses the digits following "+" or an international
+// prefix.
+func international(d, addr string) (Number, error) {
+	if d == "" || d[0] == '0' {
+		return Number{}, fmt.Errorf("%w: %q", ErrInvalid, addr)
+	}
+	for i := 1; i <= 3 && i < len(d); i++ {
+		c, ok := byCallingCode[d[:i]]
+		if !ok {
+			continue
+		}
+		if !c.valid(d[i:]) {
+			return Number{}, fmt.Errorf("%w: %q is not a valid %s number", ErrLength, addr, c.Region)
+		}
+		return e164(c, d[i:]), nil
+	}
+	if len(d) < 8 || len(d) > maxE164 {
+		return Number{}, fmt.Errorf("%w: %q", ErrLength, addr)
+	}
+	return Number{Kind: International, Value: "+" + d, TON: TONInternational, NPI: NPIISDN}, nil
+}
+
+func alphanumeric(addr string) (Number, error) {
+	if invalid := encoding.ValidateGSM7String(addr); len(invalid) > 0 {
+		return Number{}, fmt.Errorf("%w: %q has characters outside of the GSM 7-bit alphabet", ErrAlphanumeric, addr)
+	}
+	b, err := encoding.GSM7(false).NewEncoder().String(addr)
+	if err != nil {
+		return Number{}, fmt.Errorf("%w: %q: %v", ErrAlphanumeric, addr, err)
+	}
+	if len(b) > MaxAlphanumeric {
+		return Number{}, fmt.Errorf("%w: %q is longer than %d characters", ErrAlphanumeric, addr, MaxAlphanumeric)
+	}
+	return Number{Kind: Alphanumeric, Value: addr, TON: TONAlphanumeric, NPI: NPIUnknown}, nil
+}
+
+func shortCode(d string) Number {
+	return Number{Kind: ShortCode, Value: d, TON: TONNetworkSpecific, NPI: NPIUnknown}
+}
+
+func e164(c Country, nsn string) Number {
+	return Number{
+		Kind:   International,
+		Value:  "+" + c.CallingCode + nsn,
+		Region: c.Region,
+		TON:    TONInternational,
+		NPI:    NPIISDN,
+	}
+}
+
+func (c Country) valid(nsn string) bool {
+	return len(nsn) >= c.MinLen && len(nsn) <= c.MaxLen && len(c.CallingCode)+len(nsn) <= maxE164
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package number
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		addr, region string
+		kind         Kind
+		value        string
+		ton, npi     uint8
+	}{
+		{"+977 981-234-5678", "", International, "+9779812345678", 1, 1},
+		{"009779812345678", "", International, "+9779812345678", 1, 1},
+		{"9812345678", "NP", International, "+9779812345678", 1, 1},
+		{"014412345", "NP", International, "+97714412345", 1, 1},
+		{"9779812345678", "np", International, "+9779812345678", 1, 1},
+		{"(415) 555-2671", "US", International, "+14155552671", 1, 1},
+		{"1 415 555 2671", "US", International, "+14155552671", 1, 1},
+		{"011 44 20 7946 0958", "US", International, "+442079460958", 1, 1},
+		{"020 7946 0958", "GB", International, "+442079460958", 1, 1},
+		{"+888 1234 5678", "", International, "+88812345678", 1, 1},
+		{"9812345678", "", Unknown, "9812345678", 0, 1},
+		{"32665", "", ShortCode, "32665", 3, 0},
+		{"32665", "NP", ShortCode, "32665", 3, 0},
+		{"Shop24", "NP", Alphanumeric, "Shop24", 5, 0},
+		{"My Shop", "", Alphanumeric, "My Shop", 5, 0},
+	}
+	for _, tt := range tests {
+		n, err := Parse(tt.addr, tt.region)
+		if err != nil {
+			t.Errorf("Parse(%q, %q): %v", tt.addr, tt.region, err)
+			continue
+		}
+		if n.Kind != tt.kind || n.Value != tt.value || n.TON != tt.ton || n.NPI != tt.npi {
+			t.Errorf("Parse(%q, %q): want %s %s %d/%d, have %s %s %d/%d", tt.addr, tt.region,
+				tt.kind, tt.value, tt.ton, tt.npi, n.Kind, n.Value, n.TON, n.NPI)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		addr, region string
+		err          error
+	}{
+		{"", "", ErrEmpty},
+		{"+44 20 7946", "", ErrLength},
+		{"+0123456789", "", ErrInvalid},
+		{"98123456789012", "NP", ErrLength},
+		{"VeryLongSender", "", ErrAlphanumeric},
+		{"Shop{}[]", "", ErrAlphanumeric},
+		{"Магазин", "", ErrAlphanumeric},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.addr, tt.region); !errors.Is(err, tt.err) {
+			t.Errorf("Parse(%q, %q): want %v, have %v", tt.addr, tt.region, tt.err, err)
+		}
+	}
+}
@@ -41,7 +41,11 @@ type Body interface {
 	// the header and all fields.
 	SerializeTo(w io.Writer) error
 
+	// Manager returns the manager of the session the PDU was read
+	// from or written to, if any. It plays no part in building PDUs,
+	// which only need a SequenceGenerator.
 	Manager() interfaces.IManager
 
+	// SetManager sets the manager returned by Manager, once.
 	SetManager(manager interfaces.IManager)
 }
@@ -8,15 +8,12 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"sync/atomic"
 
 	"github.com/oarkflow/protocol/interfaces"
 	"github.com/oarkflow/protocol/smpp/pdu/pdufield"
 	"github.com/oarkflow/protocol/smpp/pdu/pdutlv"
 )
 
-var nextSeq uint32
-
 // codec is the base type of all PDUs.
 // It implements the PDU interface and provides a generic encoder.
 type codec struct {
@@ -28,15 +25,18 @@ type codec struct {
 }
 
 // init initializes the codec's list and maps and sets the header
-// sequence number.
-func (pdu *codec) init() {
+// sequence number from seq, or from a package wide sequence if nil.
+func (pdu *codec) init(seq SequenceGenerator) {
 	if pdu.l == nil {
 		pdu.l = pdufield.List{}
 	}
 	pdu.f = make(pdufield.Map)
 	pdu.t = make(pdutlv.Map)
 	if pdu.h.Seq == 0 { // If Seq not set
-		pdu.h.Seq = atomic.AddUint32(&nextSeq, 1)
+		if seq == nil {
+			seq = &defaultSeq
+		}
+		pdu.h.Seq = seq.NextSeq()
 	}
 }
 
@@ -95,6 +95,11 @@ func (pdu *codec) SerializeTo(w io.Writer) error {
 	for _, k := range pdu.FieldList() {
 		f, ok := pdu.f[k]
 		if !ok {
+			if k == pdufield.UDHLength || k == pdufield.GSMUserData {
+				// Only present on the wire when the UDH was set
+				// as separate fields, not within short_message.
+				continue
+			}
 			pdu.f.Set(k, nil)
 			f = pdu.f[k]
 		}
@@ -0,0 +1,32 @@
+package pdu
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/oarkflow/protocol/smpp/pdu/pdufield"
+)
+
+func TestSerializeSubmitSMWithoutUDH(t *testing.T) {
+	var seq Sequence
+	p := NewSubmitSM(nil, &seq)
+	f := p.Fields()
+	f.Set(pdufield.DestinationAddr, "9779800000000")
+	f.Set(pdufield.ShortMessage, "hi")
+	var b bytes.Buffer
+	if err := p.SerializeTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	// sm_length is followed by short_message, without the 0x00 of an
+	// unset UDH length.
+	if body := b.Bytes(); !bytes.HasSuffix(body, []byte{2, 'h', 'i'}) {
+		t.Fatalf("want sm_length and short_message last, have % x", body)
+	}
+	decoded, err := Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sm := decoded.Fields()[pdufield.ShortMessage]; sm == nil || sm.String() != "hi" {
+		t.Fatalf("want short_message decoded, have %v", sm)
+	}
+}
@@ -0,0 +1,40 @@
+package pdufield
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAbsoluteDate(t *testing.T) {
+	loc := time.FixedZone("", -(5*3600 + 45*60))
+	at := time.Date(2024, 3, 9, 17, 4, 5, 700e6, loc)
+	s := AbsoluteDate(at)
+	if s != "240309170405723-" {
+		t.Fatalf("unexpected absolute date %q", s)
+	}
+	dt := &Date{Variable: Variable{Data: []byte(s)}}
+	if err := dt.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	if !dt.Absolute.Equal(at) {
+		t.Fatalf("want %s, have %s", at, dt.Absolute)
+	}
+	if s = AbsoluteDate(time.Date(2024, 1, 1, 0, 0, 0, 0, time.FixedZone("", 20*60))); s != "231231234000000+" {
+		t.Fatalf("want UTC fallback, have %q", s)
+	}
+}
+
+func TestRelativeDate(t *testing.T) {
+	d := 400*24*time.Hour + 3*time.Hour + 2*time.Minute + time.Second
+	s := RelativeDate(d)
+	if s != "010105030201000R" {
+		t.Fatalf("unexpected relative date %q", s)
+	}
+	dt := &Date{Variable: Variable{Data: []byte(s)}}
+	if err := dt.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	if dt.Relative != d {
+		t.Fatalf("want %s, have %s", d, dt.Relative)
+	}
+}
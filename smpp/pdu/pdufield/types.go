@@ -490,6 +490,46 @@ func (dt *Date) Parse() error {
 	return nil
 }
 
+// AbsoluteDate formats t in the SMPP absolute time format
+// YYMMDDhhmmsstnnp, where t is the tenths of second, nn the offset
+// from UTC in quarter hours and p its sign, see SMPP 3.4 spec 7.1.1.
+// Times in zones whose offset is not a whole number of quarter hours
+// are formatted in UTC.
+func AbsoluteDate(t time.Time) string {
+	_, off := t.Zone()
+	if off%900 != 0 {
+		t = t.UTC()
+		off = 0
+	}
+	sign := byte('+')
+	if off < 0 {
+		sign = '-'
+		off = -off
+	}
+	return fmt.Sprintf("%s%d%02d%c", t.Format("060102150405"), t.Nanosecond()/1e8, off/900, sign)
+}
+
+// RelativeDate formats d in the SMPP relative time format
+// YYMMDDhhmmss000R. Years count 365 days and months 30 days, as in
+// Date.Parse. Durations beyond 99 years are capped.
+func RelativeDate(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	secs := int64(d / time.Second)
+	s := secs % 60
+	i := secs / 60 % 60
+	h := secs / 3600 % 24
+	days := secs / 86400
+	y := days / 365
+	m := days % 365 / 30
+	days = days % 365 % 30
+	if y > 99 {
+		y, m, days, h, i, s = 99, 11, 29, 23, 59, 59
+	}
+	return fmt.Sprintf("%02d%02d%02d%02d%02d%02d000R", y, m, days, h, i, s)
+}
+
 type Flag struct {
 	Data bool
 }
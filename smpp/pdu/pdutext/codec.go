@@ -5,6 +5,8 @@
 package pdutext
 
 import (
+	"fmt"
+	"strings"
 	"unicode"
 )
 
@@ -28,6 +30,37 @@ const (
 	//	KSC5601Type   DataCoding = 0x0E // KS C 5601
 )
 
+var codingName = map[DataCoding]string{
+	DefaultType:  "gsm7",
+	Latin1Type:   "latin1",
+	Binary2Type:  "binary",
+	ISO88595Type: "iso88595",
+	UCS2Type:     "ucs2",
+}
+
+// String returns the name of the data coding, e.g. "ucs2".
+func (c DataCoding) String() string {
+	if n, ok := codingName[c]; ok {
+		return n
+	}
+	return fmt.Sprintf("%#02x", uint8(c))
+}
+
+// ParseCoding returns the DataCoding with the given name, as
+// returned by DataCoding.String. "default" is an alias of "gsm7".
+func ParseCoding(name string) (DataCoding, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "default" {
+		return DefaultType, nil
+	}
+	for c, n := range codingName {
+		if n == name {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported data coding: %q", name)
+}
+
 // Codec defines a text codec.
 type Codec interface {
 	// Type returns the value for the data_coding PDU.
@@ -55,8 +88,9 @@ func (c DataCoding) Validate(input []byte) bool {
 }
 
 const (
-	GSM_SINGLE_MAX_LEN  = 160
-	UCS2_SINGLE_MAX_LEN = 70
+	GSM_SINGLE_MAX_LEN    = 160
+	UCS2_SINGLE_MAX_LEN   = 70
+	BINARY_SINGLE_MAX_LEN = 140
 )
 
 func (c DataCoding) Encode(input []byte) (Codec, bool) {
@@ -72,6 +106,18 @@ func (c DataCoding) Encode(input []byte) (Codec, bool) {
 			return code, true
 		}
 		return code, false
+	} else if c == ISO88595Type {
+		code := ISO88595(input)
+		if len(code.Encode()) > BINARY_SINGLE_MAX_LEN {
+			return code, true
+		}
+		return code, false
+	} else if c == Binary2Type {
+		code := Binary2(input)
+		if len(input) > BINARY_SINGLE_MAX_LEN {
+			return code, true
+		}
+		return code, false
 	}
 	code := UCS2(input)
 	if len(input) > UCS2_SINGLE_MAX_LEN {
@@ -80,6 +126,22 @@ func (c DataCoding) Encode(input []byte) (Codec, bool) {
 	return code, false
 }
 
+// Decode returns the UTF-8 text of b, a short_message received with
+// data coding c. Binary and unknown codings are returned as is.
+func (c DataCoding) Decode(b []byte) []byte {
+	switch c {
+	case UCS2Type:
+		return UCS2(b).Decode()
+	case Latin1Type:
+		return Latin1(b).Decode()
+	case ISO88595Type:
+		return ISO88595(b).Decode()
+	case DefaultType:
+		return GSM7(b).Decode()
+	}
+	return b
+}
+
 func FindCoding(input []byte) (Codec, bool) {
 	codings := []DataCoding{DefaultType, Latin1Type}
 	for _, coding := range codings {
@@ -5,7 +5,9 @@
 package pdutlv
 
 import (
+	"encoding/binary"
 	"fmt"
+	"math"
 )
 
 // Map is a collection of PDU TLV field data indexed by tag.
@@ -15,6 +17,8 @@ type Map map[Tag]Body
 // returns error if the value cannot be converted to type Data.
 //
 // This is a shortcut for m[t] = NewTLV(t, v) converting v properly.
+// Integers are encoded with the width registered for the tag, if any,
+// and strings of C-Octet string tags are null-terminated.
 func (m Map) Set(t Tag, v any) error {
 	switch v.(type) {
 	case nil:
@@ -22,8 +26,34 @@ func (m Map) Set(t Tag, v any) error {
 	case uint8:
 		m[t] = NewTLV(t, []byte{v.(uint8)})
 	case int:
+		if d, ok := Lookup(t); ok && (d.Kind == KindUint8 || d.Kind == KindUint16 || d.Kind == KindUint32) {
+			b, err := d.Encode(v)
+			if err != nil {
+				return err
+			}
+			m[t] = NewTLV(t, b)
+			break
+		}
+		if n := v.(int); n < 0 || n > math.MaxUint8 {
+			return fmt.Errorf("tlv %s: value %d does not fit in 1 octet", t.Hex(), n)
+		}
 		m[t] = NewTLV(t, []byte{uint8(v.(int))})
+	case uint16:
+		b := make([]byte, 2)
+		binary.BigEndian.PutUint16(b, v.(uint16))
+		m[t] = NewTLV(t, b)
+	case uint32:
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, v.(uint32))
+		m[t] = NewTLV(t, b)
+	case MessageState:
+		m[t] = NewTLV(t, []byte{uint8(v.(MessageState))})
+	case NetworkErrorCode:
+		m[t] = NewTLV(t, v.(NetworkErrorCode).Bytes())
 	case string:
+		if d, ok := Lookup(t); ok && d.Kind == KindCString {
+			return m.Set(t, CString(v.(string)))
+		}
 		m[t] = NewTLV(t, []byte(v.(string)))
 	case String:
 		m[t] = NewTLV(t, []byte(v.(String)))
@@ -0,0 +1,381 @@
+// Copyright 2015 go-smpp authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package pdutlv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Kind describes how the value of a TLV is represented on the wire.
+type Kind uint8
+
+// Supported TLV value kinds.
+const (
+	KindOctets  Kind = iota // Octet string, no terminator.
+	KindCString             // Null-terminated octet string.
+	KindUint8               // 1 octet integer.
+	KindUint16              // 2 octets integer, big endian.
+	KindUint32              // 4 octets integer, big endian.
+)
+
+var kindString = map[Kind]string{
+	KindOctets:  "octets",
+	KindCString: "c-octet-string",
+	KindUint8:   "uint8",
+	KindUint16:  "uint16",
+	KindUint32:  "uint32",
+}
+
+// String implements the Stringer interface.
+func (k Kind) String() string {
+	return kindString[k]
+}
+
+// Definition describes a TLV tag: its name, wire representation,
+// allowed value length and, for enumerated integers, the set of
+// valid values.
+type Definition struct {
+	Tag    Tag
+	Name   string
+	Kind   Kind
+	MinLen int // Minimum value length in octets.
+	MaxLen int // Maximum value length in octets, 0 means unbounded.
+	// Values holds the valid values of enumerated integer TLVs,
+	// indexed by value. Empty means any value is accepted.
+	Values map[uint32]string
+	Vendor bool
+}
+
+// Validate checks the given raw TLV value against the definition.
+func (d Definition) Validate(b []byte) error {
+	if len(b) < d.MinLen {
+		return fmt.Errorf("tlv %s (%s): length %d below minimum %d", d.Name, d.Tag.Hex(), len(b), d.MinLen)
+	}
+	if d.MaxLen > 0 && len(b) > d.MaxLen {
+		return fmt.Errorf("tlv %s (%s): length %d above maximum %d", d.Name, d.Tag.Hex(), len(b), d.MaxLen)
+	}
+	if d.Kind == KindCString && len(b) > 0 && b[len(b)-1] != 0x00 {
+		return fmt.Errorf("tlv %s (%s): value is not null-terminated", d.Name, d.Tag.Hex())
+	}
+	if len(d.Values) == 0 || len(b) == 0 {
+		return nil
+	}
+	v, err := d.uint(b)
+	if err != nil {
+		return err
+	}
+	if _, ok := d.Values[v]; !ok {
+		return fmt.Errorf("tlv %s (%s): invalid value %d", d.Name, d.Tag.Hex(), v)
+	}
+	return nil
+}
+
+// Decode converts the raw TLV value to its Go representation:
+// uint8, uint16, uint32, string (for C-Octet strings) or []byte.
+func (d Definition) Decode(b []byte) (any, error) {
+	if err := d.Validate(b); err != nil {
+		return nil, err
+	}
+	switch d.Kind {
+	case KindUint8, KindUint16, KindUint32:
+		if len(b) == 0 {
+			return nil, nil
+		}
+		v, err := d.uint(b)
+		if err != nil {
+			return nil, err
+		}
+		switch d.Kind {
+		case KindUint8:
+			return uint8(v), nil
+		case KindUint16:
+			return uint16(v), nil
+		}
+		return v, nil
+	case KindCString:
+		if l := len(b); l > 0 && b[l-1] == 0x00 {
+			return string(b[:l-1]), nil
+		}
+		return string(b), nil
+	}
+	return b, nil
+}
+
+// Encode converts v to the raw TLV value described by the definition.
+func (d Definition) Encode(v any) ([]byte, error) {
+	var b []byte
+	switch d.Kind {
+	case KindUint8, KindUint16, KindUint32:
+		n, ok := toUint(v)
+		if !ok {
+			if raw, isRaw := v.([]byte); isRaw {
+				b = raw
+				break
+			}
+			return nil, fmt.Errorf("tlv %s (%s): unsupported value %#v", d.Name, d.Tag.Hex(), v)
+		}
+		if (d.Kind == KindUint8 && n > math.MaxUint8) || (d.Kind == KindUint16 && n > math.MaxUint16) {
+			return nil, fmt.Errorf("tlv %s (%s): value %d out of range", d.Name, d.Tag.Hex(), n)
+		}
+		switch d.Kind {
+		case KindUint8:
+			b = []byte{uint8(n)}
+		case KindUint16:
+			b = make([]byte, 2)
+			binary.BigEndian.PutUint16(b, uint16(n))
+		default:
+			b = make([]byte, 4)
+			binary.BigEndian.PutUint32(b, n)
+		}
+	case KindCString:
+		switch v := v.(type) {
+		case string:
+			b = append([]byte(v), 0x00)
+		case CString:
+			b = append([]byte(v), 0x00)
+		case []byte:
+			b = v
+			if len(b) == 0 || b[len(b)-1] != 0x00 {
+				b = append(b, 0x00)
+			}
+		default:
+			return nil, fmt.Errorf("tlv %s (%s): unsupported value %#v", d.Name, d.Tag.Hex(), v)
+		}
+	default:
+		switch v := v.(type) {
+		case string:
+			b = []byte(v)
+		case String:
+			b = []byte(v)
+		case []byte:
+			b = v
+		case interface{ Bytes() []byte }:
+			b = v.Bytes()
+		default:
+			return nil, fmt.Errorf("tlv %s (%s): unsupported value %#v", d.Name, d.Tag.Hex(), v)
+		}
+	}
+	return b, d.Validate(b)
+}
+
+func (d Definition) uint(b []byte) (uint32, error) {
+	switch len(b) {
+	case 1:
+		return uint32(b[0]), nil
+	case 2:
+		return uint32(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return binary.BigEndian.Uint32(b), nil
+	}
+	return 0, fmt.Errorf("tlv %s (%s): cannot decode %d octets as integer", d.Name, d.Tag.Hex(), len(b))
+}
+
+func toUint(v any) (uint32, bool) {
+	switch v := v.(type) {
+	case uint8:
+		return uint32(v), true
+	case uint16:
+		return uint32(v), true
+	case uint32:
+		return v, true
+	case int:
+		return uint32(v), v >= 0 && v <= math.MaxUint32
+	case MessageState:
+		return uint32(v), true
+	}
+	return 0, false
+}
+
+// registry holds the known TLV definitions indexed by tag.
+var registry = struct {
+	sync.RWMutex
+	defs   map[Tag]Definition
+	byName map[string]Tag
+}{
+	defs:   make(map[Tag]Definition),
+	byName: make(map[string]Tag),
+}
+
+// Vendor specific TLVs must use tags in this range, see SMPP 5.0 spec 4.8.1.
+const (
+	VendorTagMin Tag = 0x1400
+	VendorTagMax Tag = 0x3FFF
+)
+
+// Register adds a vendor specific TLV definition to the registry.
+// It returns an error if the tag is outside the vendor range, or
+// if the tag or name is already registered.
+func Register(d Definition) error {
+	if d.Tag < VendorTagMin || d.Tag > VendorTagMax {
+		return fmt.Errorf("tlv %s (%s): tag outside vendor range %s-%s", d.Name, d.Tag.Hex(), VendorTagMin.Hex(), VendorTagMax.Hex())
+	}
+	d.Vendor = true
+	return register(d)
+}
+
+func register(d Definition) error {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.defs[d.Tag]; ok {
+		return fmt.Errorf("tlv %s (%s): tag already registered", d.Name, d.Tag.Hex())
+	}
+	if _, ok := registry.byName[d.Name]; ok {
+		return fmt.Errorf("tlv %s (%s): name already registered", d.Name, d.Tag.Hex())
+	}
+	registry.defs[d.Tag] = d
+	registry.byName[d.Name] = d.Tag
+	return nil
+}
+
+// Lookup returns the definition of the given tag.
+func Lookup(t Tag) (Definition, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	d, ok := registry.defs[t]
+	return d, ok
+}
+
+// LookupName returns the definition registered under the given name,
+// e.g. "receipted_message_id".
+func LookupName(name string) (Definition, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	t, ok := registry.byName[name]
+	if !ok {
+		return Definition{}, false
+	}
+	return registry.defs[t], true
+}
+
+// Definitions returns all registered definitions ordered by tag.
+func Definitions() []Definition {
+	registry.RLock()
+	defs := make([]Definition, 0, len(registry.defs))
+	for _, d := range registry.defs {
+		defs = append(defs, d)
+	}
+	registry.RUnlock()
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Tag < defs[j].Tag })
+	return defs
+}
+
+// Name returns the registered name of the tag, or its hex
+// representation if the tag is unknown.
+func (t Tag) Name() string {
+	if d, ok := Lookup(t); ok {
+		return d.Name
+	}
+	return t.Hex()
+}
+
+// Validate checks every TLV of the map against the registry.
+// Unknown tags are accepted as they are.
+func (m Map) Validate() error {
+	for t, f := range m {
+		d, ok := Lookup(t)
+		if !ok {
+			continue
+		}
+		if err := d.Validate(f.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func enum(names ...string) map[uint32]string {
+	m := make(map[uint32]string, len(names))
+	for i, n := range names {
+		m[uint32(i)] = n
+	}
+	return m
+}
+
+func init() {
+	networkType := enum("unknown", "gsm", "ansi-136/tdma", "is-95/cdma", "pdc", "phs", "iden", "amps", "paging_network")
+	bearerType := enum("unknown", "sms", "csd", "packet_data", "ussd", "cdpd", "datatac", "flex/reflex", "cell_broadcast")
+	addrSubunit := enum("unknown", "ms_display", "mobile_equipment", "smart_card_1", "external_unit_1")
+	defs := []Definition{
+		{Tag: TagDestAddrSubunit, Name: "dest_addr_subunit", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: addrSubunit},
+		{Tag: TagDestNetworkType, Name: "dest_network_type", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: networkType},
+		{Tag: TagDestBearerType, Name: "dest_bearer_type", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: bearerType},
+		{Tag: TagDestTelematicsID, Name: "dest_telematics_id", Kind: KindUint16, MinLen: 2, MaxLen: 2},
+		{Tag: TagSourceAddrSubunit, Name: "source_addr_subunit", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: addrSubunit},
+		{Tag: TagSourceNetworkType, Name: "source_network_type", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: networkType},
+		{Tag: TagSourceBearerType, Name: "source_bearer_type", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: bearerType},
+		{Tag: TagSourceTelematicsID, Name: "source_telematics_id", Kind: KindUint8, MinLen: 1, MaxLen: 1},
+		{Tag: TagQosTimeToLive, Name: "qos_time_to_live", Kind: KindUint32, MinLen: 4, MaxLen: 4},
+		{Tag: TagPayloadType, Name: "payload_type", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: enum("default", "wcmp")},
+		{Tag: TagAdditionalStatusInfoText, Name: "additional_status_info_text", Kind: KindCString, MinLen: 1, MaxLen: 256},
+		{Tag: TagReceiptedMessageID, Name: "receipted_message_id", Kind: KindCString, MinLen: 1, MaxLen: 65},
+		{Tag: TagMsMsgWaitFacilities, Name: "ms_msg_wait_facilities", Kind: KindUint8, MinLen: 1, MaxLen: 1},
+		{Tag: TagPrivacyIndicator, Name: "privacy_indicator", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: enum("not_restricted", "restricted", "confidential", "secret")},
+		{Tag: TagSourceSubaddress, Name: "source_subaddress", Kind: KindOctets, MinLen: 2, MaxLen: 23},
+		{Tag: TagDestSubaddress, Name: "dest_subaddress", Kind: KindOctets, MinLen: 2, MaxLen: 23},
+		{Tag: TagUserMessageReference, Name: "user_message_reference", Kind: KindUint16, MinLen: 2, MaxLen: 2},
+		{Tag: TagUserResponseCode, Name: "user_response_code", Kind: KindUint8, MinLen: 1, MaxLen: 1},
+		{Tag: TagSourcePort, Name: "source_port", Kind: KindUint16, MinLen: 2, MaxLen: 2},
+		{Tag: TagDestinationPort, Name: "destination_port", Kind: KindUint16, MinLen: 2, MaxLen: 2},
+		{Tag: TagSarMsgRefNum, Name: "sar_msg_ref_num", Kind: KindUint16, MinLen: 2, MaxLen: 2},
+		{Tag: TagLanguageIndicator, Name: "language_indicator", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: enum("unspecified", "english", "french", "spanish", "german", "portuguese")},
+		{Tag: TagSarTotalSegments, Name: "sar_total_segments", Kind: KindUint8, MinLen: 1, MaxLen: 1},
+		{Tag: TagSarSegmentSeqnum, Name: "sar_segment_seqnum", Kind: KindUint8, MinLen: 1, MaxLen: 1},
+		{Tag: TagScInterfaceVersion, Name: "sc_interface_version", Kind: KindUint8, MinLen: 1, MaxLen: 1},
+		{Tag: TagCallbackNumPresInd, Name: "callback_num_pres_ind", Kind: KindUint8, MinLen: 1, MaxLen: 1},
+		{Tag: TagCallbackNumAtag, Name: "callback_num_atag", Kind: KindOctets, MaxLen: 65},
+		{Tag: TagNumberOfMessages, Name: "number_of_messages", Kind: KindUint8, MinLen: 1, MaxLen: 1},
+		{Tag: TagCallbackNum, Name: "callback_num", Kind: KindOctets, MinLen: 4, MaxLen: 19},
+		{Tag: TagDpfResult, Name: "dpf_result", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: enum("dpf_not_set", "dpf_set")},
+		{Tag: TagSetDpf, Name: "set_dpf", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: enum("not_requested", "requested")},
+		{Tag: TagMsAvailabilityStatus, Name: "ms_availability_status", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: enum("available", "denied", "unavailable")},
+		{Tag: TagNetworkErrorCode, Name: "network_error_code", Kind: KindOctets, MinLen: 3, MaxLen: 3},
+		{Tag: TagMessagePayload, Name: "message_payload", Kind: KindOctets},
+		{Tag: TagDeliveryFailureReason, Name: "delivery_failure_reason", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: enum("destination_unavailable", "destination_address_invalid", "permanent_network_error", "temporary_network_error")},
+		{Tag: TagMoreMessagesToSend, Name: "more_messages_to_send", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: enum("no_more_messages", "more_messages")},
+		{Tag: TagMessageStateOption, Name: "message_state", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: messageStateValues()},
+		{Tag: TagCongestionState, Name: "congestion_state", Kind: KindUint8, MinLen: 1, MaxLen: 1},
+		{Tag: TagUssdServiceOp, Name: "ussd_service_op", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: map[uint32]string{
+			0: "pssd_indication", 1: "pssr_indication", 2: "ussr_request", 3: "ussn_request",
+			16: "pssd_response", 17: "pssr_response", 18: "ussr_confirm", 19: "ussn_confirm",
+		}},
+		{Tag: TagBroadcastChannelIndicator, Name: "broadcast_channel_indicator", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: enum("basic", "extended")},
+		{Tag: TagBroadcastContentType, Name: "broadcast_content_type", Kind: KindOctets, MinLen: 3, MaxLen: 3},
+		{Tag: TagBroadcastContentTypeInfo, Name: "broadcast_content_type_info", Kind: KindOctets, MaxLen: 255},
+		{Tag: TagBroadcastMessageClass, Name: "broadcast_message_class", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: enum("no_class", "class_1", "class_2", "class_3")},
+		{Tag: TagBroadcastRepNum, Name: "broadcast_rep_num", Kind: KindUint16, MinLen: 2, MaxLen: 2},
+		{Tag: TagBroadcastFrequencyInterval, Name: "broadcast_frequency_interval", Kind: KindOctets, MinLen: 3, MaxLen: 3},
+		{Tag: TagBroadcastAreaIdentifier, Name: "broadcast_area_identifier", Kind: KindOctets, MinLen: 1, MaxLen: 100},
+		{Tag: TagBroadcastErrorStatus, Name: "broadcast_error_status", Kind: KindUint32, MinLen: 4, MaxLen: 4},
+		{Tag: TagBroadcastAreaSuccess, Name: "broadcast_area_success", Kind: KindUint8, MinLen: 1, MaxLen: 1},
+		{Tag: TagBroadcastEndTime, Name: "broadcast_end_time", Kind: KindCString, MinLen: 17, MaxLen: 17},
+		{Tag: TagBroadcastServiceGroup, Name: "broadcast_service_group", Kind: KindOctets, MaxLen: 255},
+		{Tag: TagBillingIdentification, Name: "billing_identification", Kind: KindOctets, MaxLen: 1024},
+		{Tag: TagSourceNetworkID, Name: "source_network_id", Kind: KindCString, MinLen: 1, MaxLen: 65},
+		{Tag: TagDestNetworkID, Name: "dest_network_id", Kind: KindCString, MinLen: 1, MaxLen: 65},
+		{Tag: TagSourceNodeID, Name: "source_node_id", Kind: KindOctets, MinLen: 6, MaxLen: 6},
+		{Tag: TagDestNodeID, Name: "dest_node_id", Kind: KindOctets, MinLen: 6, MaxLen: 6},
+		{Tag: TagDestAddrNpResolution, Name: "dest_addr_np_resolution", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: enum("query_not_performed", "query_performed_not_ported", "query_performed_ported")},
+		{Tag: TagDestAddrNpInformation, Name: "dest_addr_np_information", Kind: KindOctets, MinLen: 10, MaxLen: 10},
+		{Tag: TagDestAddrNpCountry, Name: "dest_addr_np_country", Kind: KindOctets, MinLen: 1, MaxLen: 5},
+		{Tag: TagDisplayTime, Name: "display_time", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: enum("temporary", "default", "invoke")},
+		{Tag: TagSmsSignal, Name: "sms_signal", Kind: KindUint16, MinLen: 2, MaxLen: 2},
+		{Tag: TagMsValidity, Name: "ms_validity", Kind: KindOctets, MinLen: 1, MaxLen: 4},
+		{Tag: TagAlertOnMessageDelivery, Name: "alert_on_message_delivery", Kind: KindUint8, MaxLen: 1},
+		{Tag: TagItsReplyType, Name: "its_reply_type", Kind: KindUint8, MinLen: 1, MaxLen: 1, Values: enum("digit", "number", "telephone_no", "password", "character_line", "menu", "date", "time", "continue")},
+		{Tag: TagItsSessionInfo, Name: "its_session_info", Kind: KindOctets, MinLen: 2, MaxLen: 2},
+		{Tag: TagDltEntityId, Name: "dlt_entity_id", Kind: KindOctets, Vendor: true},
+		{Tag: TagDltTemplateId, Name: "dlt_template_id", Kind: KindOctets, Vendor: true},
+		{Tag: TagTmId, Name: "tm_id", Kind: KindOctets, Vendor: true},
+	}
+	for _, d := range defs {
+		if err := register(d); err != nil {
+			panic(err)
+		}
+	}
+}
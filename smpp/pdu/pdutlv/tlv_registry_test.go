@@ -0,0 +1,89 @@
+package pdutlv
+
+import (
+	"testing"
+)
+
+func TestMapTypedValues(t *testing.T) {
+	m := make(Map)
+	if err := m.SetMessageState(MessageStateDelivered); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetNetworkErrorCode(NetworkErrorCode{NetworkType: 3, ErrorCode: 0x0101}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetReceiptedMessageID("abc123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set(TagAdditionalStatusInfoText, "info"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set(TagUserMessageReference, 513); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := m.MessageState(); err != nil || s != MessageStateDelivered {
+		t.Fatalf("unexpected message state %v: %v", s, err)
+	}
+	if c, err := m.NetworkErrorCode(); err != nil || c.NetworkType != 3 || c.ErrorCode != 0x0101 {
+		t.Fatalf("unexpected network error code %v: %v", c, err)
+	}
+	if id, err := m.ReceiptedMessageID(); err != nil || id != "abc123" {
+		t.Fatalf("unexpected receipted message id %q: %v", id, err)
+	}
+	if s, err := m.CString(TagAdditionalStatusInfoText); err != nil || s != "info" {
+		t.Fatalf("unexpected additional status info text %q: %v", s, err)
+	}
+	if v, err := m.Uint16(TagUserMessageReference); err != nil || v != 513 {
+		t.Fatalf("unexpected user message reference %d: %v", v, err)
+	}
+	if _, err := m.DeliveryFailureReason(); err != ErrNotPresent {
+		t.Fatalf("want ErrNotPresent, have %v", err)
+	}
+}
+
+func TestFieldsValidate(t *testing.T) {
+	tests := []struct {
+		fields Fields
+		ok     bool
+	}{
+		{Fields{TagPayloadType: uint8(1)}, true},
+		{Fields{TagPayloadType: uint8(7)}, false},
+		{Fields{TagSourcePort: uint16(9200)}, true},
+		{Fields{TagSourcePort: []byte{1}}, false},
+		{Fields{TagReceiptedMessageID: CString("id")}, true},
+		{Fields{TagReceiptedMessageID: "id"}, true},
+		{Fields{TagPayloadType: 256}, false},
+		{Fields{TagSourcePort: 65536}, false},
+		{Fields{Tag(0x1499): 256}, false},
+		{Fields{Tag(0x1499): []byte{1, 2, 3}}, true},
+	}
+	for i, tc := range tests {
+		err := tc.fields.Validate()
+		if tc.ok && err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("test %d: expected error", i)
+		}
+	}
+}
+
+func TestRegisterVendor(t *testing.T) {
+	d := Definition{Tag: 0x1500, Name: "vendor_test", Kind: KindUint8, MinLen: 1, MaxLen: 1}
+	if err := Register(d); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register(d); err == nil {
+		t.Fatal("expected duplicate registration to fail")
+	}
+	if err := Register(Definition{Tag: TagPayloadType, Name: "payload"}); err == nil {
+		t.Fatal("expected non-vendor tag to fail")
+	}
+	got, ok := LookupName("vendor_test")
+	if !ok || !got.Vendor || got.Tag != 0x1500 {
+		t.Fatalf("unexpected definition %#v", got)
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2015 go-smpp authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package pdutlv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrNotPresent is returned by typed getters when the TLV is not set.
+var ErrNotPresent = errors.New("tlv not present")
+
+// MessageState is the value of the message_state TLV.
+type MessageState uint8
+
+// Message states, see SMPP 5.0 spec 4.7.15.
+const (
+	MessageStateScheduled     MessageState = 0
+	MessageStateEnroute       MessageState = 1
+	MessageStateDelivered     MessageState = 2
+	MessageStateExpired       MessageState = 3
+	MessageStateDeleted       MessageState = 4
+	MessageStateUndeliverable MessageState = 5
+	MessageStateAccepted      MessageState = 6
+	MessageStateUnknown       MessageState = 7
+	MessageStateRejected      MessageState = 8
+	MessageStateSkipped       MessageState = 9
+)
+
+var messageStateString = map[MessageState]string{
+	MessageStateScheduled:     "SCHEDULED",
+	MessageStateEnroute:       "ENROUTE",
+	MessageStateDelivered:     "DELIVERED",
+	MessageStateExpired:       "EXPIRED",
+	MessageStateDeleted:       "DELETED",
+	MessageStateUndeliverable: "UNDELIVERABLE",
+	MessageStateAccepted:      "ACCEPTED",
+	MessageStateUnknown:       "UNKNOWN",
+	MessageStateRejected:      "REJECTED",
+	MessageStateSkipped:       "SKIPPED",
+}
+
+func messageStateValues() map[uint32]string {
+	m := make(map[uint32]string, len(messageStateString))
+	for k, v := range messageStateString {
+		m[uint32(k)] = v
+	}
+	return m
+}
+
+// String implements the Stringer interface.
+func (s MessageState) String() string {
+	if v, ok := messageStateString[s]; ok {
+		return v
+	}
+	return fmt.Sprintf("UNKNOWN (%d)", uint8(s))
+}
+
+// Final reports whether the state is final, i.e. no further
+// delivery attempts will be made.
+func (s MessageState) Final() bool {
+	switch s {
+	case MessageStateDelivered, MessageStateExpired, MessageStateDeleted,
+		MessageStateUndeliverable, MessageStateRejected, MessageStateSkipped:
+		return true
+	}
+	return false
+}
+
+// NetworkErrorCode is the value of the network_error_code TLV.
+type NetworkErrorCode struct {
+	NetworkType uint8 // 1 ANSI-136, 2 IS-95, 3 GSM, 4 reserved, 5 ANSI-41...
+	ErrorCode   uint16
+}
+
+// Bytes returns the 3 octets wire representation.
+func (c NetworkErrorCode) Bytes() []byte {
+	b := make([]byte, 3)
+	b[0] = c.NetworkType
+	binary.BigEndian.PutUint16(b[1:], c.ErrorCode)
+	return b
+}
+
+// String implements the Stringer interface.
+func (c NetworkErrorCode) String() string {
+	return fmt.Sprintf("%d:%d", c.NetworkType, c.ErrorCode)
+}
+
+// value returns the raw value of the tag after validating it
+// against the registry.
+func (m Map) value(t Tag) ([]byte, error) {
+	f, ok := m[t]
+	if !ok || f == nil {
+		return nil, ErrNotPresent
+	}
+	b := f.Bytes()
+	if d, ok := Lookup(t); ok {
+		if err := d.Validate(b); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Uint8 returns the value of a 1 octet integer TLV.
+func (m Map) Uint8(t Tag) (uint8, error) {
+	b, err := m.value(t)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) != 1 {
+		return 0, fmt.Errorf("tlv %s: want 1 octet, have %d", t.Name(), len(b))
+	}
+	return b[0], nil
+}
+
+// Uint16 returns the value of a 2 octets integer TLV.
+func (m Map) Uint16(t Tag) (uint16, error) {
+	b, err := m.value(t)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) != 2 {
+		return 0, fmt.Errorf("tlv %s: want 2 octets, have %d", t.Name(), len(b))
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+// Uint32 returns the value of a 4 octets integer TLV.
+func (m Map) Uint32(t Tag) (uint32, error) {
+	b, err := m.value(t)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) != 4 {
+		return 0, fmt.Errorf("tlv %s: want 4 octets, have %d", t.Name(), len(b))
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+// CString returns the value of a C-Octet string TLV without
+// the null terminator.
+func (m Map) CString(t Tag) (string, error) {
+	b, err := m.value(t)
+	if err != nil {
+		return "", err
+	}
+	if l := len(b); l > 0 && b[l-1] == 0x00 {
+		b = b[:l-1]
+	}
+	return string(b), nil
+}
+
+// MessageState returns the message_state TLV.
+func (m Map) MessageState() (MessageState, error) {
+	v, err := m.Uint8(TagMessageStateOption)
+	return MessageState(v), err
+}
+
+// SetMessageState sets the message_state TLV.
+func (m Map) SetMessageState(s MessageState) error {
+	return m.Set(TagMessageStateOption, s)
+}
+
+// NetworkErrorCode returns the network_error_code TLV.
+func (m Map) NetworkErrorCode() (NetworkErrorCode, error) {
+	b, err := m.value(TagNetworkErrorCode)
+	if err != nil {
+		return NetworkErrorCode{}, err
+	}
+	return NetworkErrorCode{NetworkType: b[0], ErrorCode: binary.BigEndian.Uint16(b[1:3])}, nil
+}
+
+// SetNetworkErrorCode sets the network_error_code TLV.
+func (m Map) SetNetworkErrorCode(c NetworkErrorCode) error {
+	return m.Set(TagNetworkErrorCode, c)
+}
+
+// ReceiptedMessageID returns the receipted_message_id TLV.
+func (m Map) ReceiptedMessageID() (string, error) {
+	return m.CString(TagReceiptedMessageID)
+}
+
+// SetReceiptedMessageID sets the receipted_message_id TLV.
+func (m Map) SetReceiptedMessageID(id string) error {
+	return m.Set(TagReceiptedMessageID, CString(id))
+}
+
+// DeliveryFailureReason returns the delivery_failure_reason TLV.
+func (m Map) DeliveryFailureReason() (uint8, error) {
+	return m.Uint8(TagDeliveryFailureReason)
+}
+
+// SarInfo returns the sar_msg_ref_num, sar_total_segments and
+// sar_segment_seqnum TLVs used for concatenated messages.
+func (m Map) SarInfo() (ref uint16, total, seq uint8, err error) {
+	if ref, err = m.Uint16(TagSarMsgRefNum); err != nil {
+		return
+	}
+	if total, err = m.Uint8(TagSarTotalSegments); err != nil {
+		return
+	}
+	seq, err = m.Uint8(TagSarSegmentSeqnum)
+	return
+}
+
+// Get returns the decoded value of the TLV, using the registry
+// to pick its Go representation. Unknown tags are returned as []byte.
+func (m Map) Get(t Tag) (any, error) {
+	f, ok := m[t]
+	if !ok || f == nil {
+		return nil, ErrNotPresent
+	}
+	if d, ok := Lookup(t); ok {
+		return d.Decode(f.Bytes())
+	}
+	return f.Bytes(), nil
+}
+
+// Validate checks every value of the fields against the registry,
+// converting them the same way Map.Set does.
+func (f Fields) Validate() error {
+	m := make(Map, len(f))
+	for t, v := range f {
+		if err := m.Set(t, v); err != nil {
+			return err
+		}
+	}
+	return m.Validate()
+}
@@ -0,0 +1,38 @@
+package pdu
+
+import "sync/atomic"
+
+// MaxSeq is the highest sequence number. SMPP reserves the range
+// 0x80000000-0xFFFFFFFF, so sequences wrap around to 1 after MaxSeq.
+const MaxSeq = 0x7FFFFFFF
+
+// SequenceGenerator is the interface that provides the sequence
+// numbers of new PDUs. Implementations must be safe for concurrent
+// use and return values in the range 1..MaxSeq.
+type SequenceGenerator interface {
+	NextSeq() uint32
+}
+
+// Sequence is a SequenceGenerator counting from 1 to MaxSeq and
+// wrapping around. The zero value is ready to use; a session should
+// own its Sequence so numbers are not shared between binds.
+type Sequence struct {
+	n atomic.Uint32
+}
+
+// NextSeq implements the SequenceGenerator interface.
+func (s *Sequence) NextSeq() uint32 {
+	for {
+		cur := s.n.Load()
+		next := cur + 1
+		if next > MaxSeq {
+			next = 1
+		}
+		if s.n.CompareAndSwap(cur, next) {
+			return next
+		}
+	}
+}
+
+// defaultSeq is used by PDUs built without a SequenceGenerator.
+var defaultSeq Sequence
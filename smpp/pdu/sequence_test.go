@@ -0,0 +1,34 @@
+package pdu
+
+import "testing"
+
+func TestSequenceWrap(t *testing.T) {
+	var s Sequence
+	if n := s.NextSeq(); n != 1 {
+		t.Fatalf("want 1, have %d", n)
+	}
+	s.n.Store(MaxSeq - 1)
+	if n := s.NextSeq(); n != MaxSeq {
+		t.Fatalf("want %#x, have %#x", MaxSeq, n)
+	}
+	if n := s.NextSeq(); n != 1 {
+		t.Fatalf("want wrap to 1, have %#x", n)
+	}
+}
+
+func TestSequenceGenerator(t *testing.T) {
+	var a, b Sequence
+	a.NextSeq()
+	if seq := NewSubmitSM(nil, &a).Header().Seq; seq != 2 {
+		t.Fatalf("want 2, have %d", seq)
+	}
+	if seq := NewEnquireLink(&b).Header().Seq; seq != 1 {
+		t.Fatalf("want 1 from an independent session, have %d", seq)
+	}
+	if seq := NewDeliverSMRespSeq(42, &b).Header().Seq; seq != 42 {
+		t.Fatalf("want explicit seq 42, have %d", seq)
+	}
+	if seq := NewUnbind(nil).Header().Seq; seq == 0 {
+		t.Fatal("want a sequence without generator")
+	}
+}
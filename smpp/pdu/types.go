@@ -5,7 +5,6 @@
 package pdu
 
 import (
-	"github.com/oarkflow/protocol/interfaces"
 	"github.com/oarkflow/protocol/smpp/pdu/pdufield"
 	"github.com/oarkflow/protocol/smpp/pdu/pdutlv"
 )
@@ -49,59 +48,58 @@ func newGenericNACK(hdr *Header) *codec {
 }
 
 // NewGenericNACK creates and initializes a GenericNACK PDU.
-func NewGenericNACK(manager interfaces.IManager) Body {
+func NewGenericNACK(seq SequenceGenerator) Body {
 	b := newGenericNACK(&Header{ID: GenericNACKID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
 // NewGenericNACKSeq creates and initializes a new GenericNACK PDU for a specific seq.
 func NewGenericNACKSeq(seq uint32) Body {
 	b := newGenericNACK(&Header{ID: GenericNACKID, Seq: seq})
-	b.init()
+	b.init(nil)
 	return b
 }
 
 // NewBindReceiverRespSeq creates and initializes a new BindResp PDU for a specific seq.
 func NewBindReceiverRespSeq(seq uint32) Body {
 	b := newBindResp(&Header{ID: BindReceiverRespID, Seq: seq})
-	b.init()
+	b.init(nil)
 	return b
 }
 
 // NewBindTransceiverRespSeq creates and initializes a new BindResp PDU for a specific seq.
 func NewBindTransceiverRespSeq(seq uint32) Body {
 	b := newBindResp(&Header{ID: BindTransceiverRespID, Seq: seq})
-	b.init()
+	b.init(nil)
 	return b
 }
 
 // NewBindTransmitterRespSeq creates and initializes a new BindResp PDU for a specific seq.
 func NewBindTransmitterRespSeq(seq uint32) Body {
 	b := newBindResp(&Header{ID: BindTransmitterRespID, Seq: seq})
-	b.init()
+	b.init(nil)
 	return b
 }
 
 // NewQuerySMRespSeq creates and initializes a new QuerySMResp PDU for a specific seq.
 func NewQuerySMRespSeq(seq uint32) Body {
 	b := newQuerySMResp(&Header{ID: QuerySMRespID, Seq: seq})
-	b.init()
+	b.init(nil)
 	return b
 }
 
 // NewSubmitSMRespSeq creates and initializes a new SubmitSMResp PDU for a specific seq.
 func NewSubmitSMRespSeq(seq uint32) Body {
 	b := newSubmitSMResp(&Header{ID: SubmitSMRespID, Seq: seq})
-	b.init()
+	b.init(nil)
 	return b
 }
 
 // NewSubmitMultiRespSeq creates and initializes a new SubmitMultiResp PDU for a specific seq.
 func NewSubmitMultiRespSeq(seq uint32) Body {
 	b := newSubmitMultiResp(&Header{ID: SubmitMultiRespID, Seq: seq})
-	b.init()
+	b.init(nil)
 	return b
 }
 
@@ -123,26 +121,23 @@ func newBind(hdr *Header) *codec {
 }
 
 // NewBindReceiver creates a new Bind PDU.
-func NewBindReceiver(manager interfaces.IManager) Body {
+func NewBindReceiver(seq SequenceGenerator) Body {
 	b := newBind(&Header{ID: BindReceiverID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
 // NewBindTransceiver creates a new Bind PDU.
-func NewBindTransceiver(manager interfaces.IManager) Body {
+func NewBindTransceiver(seq SequenceGenerator) Body {
 	b := newBind(&Header{ID: BindTransceiverID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
 // NewBindTransmitter creates a new Bind PDU.
-func NewBindTransmitter(manager interfaces.IManager) Body {
+func NewBindTransmitter(seq SequenceGenerator) Body {
 	b := newBind(&Header{ID: BindTransmitterID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
@@ -157,26 +152,23 @@ func newBindResp(hdr *Header) *codec {
 }
 
 // NewBindReceiverResp creates and initializes a new BindResp PDU.
-func NewBindReceiverResp(manager interfaces.IManager) Body {
+func NewBindReceiverResp(seq SequenceGenerator) Body {
 	b := newBindResp(&Header{ID: BindReceiverRespID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
 // NewBindTransceiverResp creates and initializes a new BindResp PDU.
-func NewBindTransceiverResp(manager interfaces.IManager) Body {
+func NewBindTransceiverResp(seq SequenceGenerator) Body {
 	b := newBindResp(&Header{ID: BindTransceiverRespID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
 // NewBindTransmitterResp creates and initializes a new BindResp PDU.
-func NewBindTransmitterResp(manager interfaces.IManager) Body {
+func NewBindTransmitterResp(seq SequenceGenerator) Body {
 	b := newBindResp(&Header{ID: BindTransmitterRespID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
@@ -213,10 +205,9 @@ func newCancelSM(hdr *Header) *codec {
 }
 
 // NewCancelSM creates and initializes a new CancelSM PDU.
-func NewCancelSM(manager interfaces.IManager) Body {
+func NewCancelSM(seq SequenceGenerator) Body {
 	b := newCancelSM(&Header{ID: CancelSMID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
@@ -227,10 +218,9 @@ func newCancelSMResp(hdr *Header) *codec {
 	return &codec{h: hdr}
 }
 
-func NewCancelSMResp(manager interfaces.IManager) Body {
+func NewCancelSMResp(seq SequenceGenerator) Body {
 	b := newCancelSMResp(&Header{ID: CancelSMRespID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
@@ -255,10 +245,9 @@ func newReplaceSM(hdr *Header) *codec {
 	}
 }
 
-func NewReplaceSM(manager interfaces.IManager) Body {
+func NewReplaceSM(seq SequenceGenerator) Body {
 	b := newReplaceSM(&Header{ID: ReplaceSMID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
@@ -302,10 +291,9 @@ func newQuerySM(hdr *Header) *codec {
 }
 
 // NewQuerySM creates and initializes a new QuerySM PDU.
-func NewQuerySM(manager interfaces.IManager) Body {
+func NewQuerySM(seq SequenceGenerator) Body {
 	b := newQuerySM(&Header{ID: QuerySMID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
@@ -325,10 +313,9 @@ func newQuerySMResp(hdr *Header) *codec {
 }
 
 // NewQuerySMResp creates and initializes a new QuerySMResp PDU.
-func NewQuerySMResp(manager interfaces.IManager) Body {
+func NewQuerySMResp(seq SequenceGenerator) Body {
 	b := newQuerySMResp(&Header{ID: QuerySMRespID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
@@ -364,10 +351,9 @@ func newSubmitSM(hdr *Header) *codec {
 }
 
 // NewSubmitSM creates and initializes a new SubmitSM PDU.
-func NewSubmitSM(fields pdutlv.Fields, manager interfaces.IManager) Body {
+func NewSubmitSM(fields pdutlv.Fields, seq SequenceGenerator) Body {
 	b := newSubmitSM(&Header{ID: SubmitSMID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	for tag, value := range fields {
 		b.t.Set(tag, value)
 	}
@@ -387,10 +373,9 @@ func newSubmitSMResp(hdr *Header) *codec {
 }
 
 // NewSubmitSMResp creates and initializes a new SubmitSMResp PDU.
-func NewSubmitSMResp(manager interfaces.IManager) Body {
+func NewSubmitSMResp(seq SequenceGenerator) Body {
 	b := newSubmitSMResp(&Header{ID: SubmitSMRespID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
@@ -416,10 +401,9 @@ func newDataSM(hdr *Header) *codec {
 }
 
 // NewDataSM creates and initializes a new DataSM PDU.
-func NewDataSM(fields pdutlv.Fields, manager interfaces.IManager) Body {
+func NewDataSM(fields pdutlv.Fields, seq SequenceGenerator) Body {
 	b := newDataSM(&Header{ID: DataSMID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	for tag, value := range fields {
 		_ = b.t.Set(tag, value)
 	}
@@ -440,10 +424,9 @@ func newDataSMResp(hdr *Header) *codec {
 }
 
 // NewDataSMResp creates and initializes a new NewDataSMResp PDU.
-func NewDataSMResp(manager interfaces.IManager) Body {
+func NewDataSMResp(seq SequenceGenerator) Body {
 	b := newDataSMResp(&Header{ID: DataSMRespID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
@@ -476,10 +459,9 @@ func newSubmitMulti(hdr *Header) *codec {
 }
 
 // NewSubmitMulti creates and initializes a new SubmitMulti PDU.
-func NewSubmitMulti(fields pdutlv.Fields, manager interfaces.IManager) Body {
+func NewSubmitMulti(fields pdutlv.Fields, seq SequenceGenerator) Body {
 	b := newSubmitMulti(&Header{ID: SubmitMultiID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	for tag, value := range fields {
 		b.t.Set(tag, value)
 	}
@@ -501,10 +483,9 @@ func newSubmitMultiResp(hdr *Header) *codec {
 }
 
 // NewSubmitMultiResp creates and initializes a new SubmitMultiResp PDU.
-func NewSubmitMultiResp(manager interfaces.IManager) Body {
+func NewSubmitMultiResp(seq SequenceGenerator) Body {
 	b := newSubmitMultiResp(&Header{ID: SubmitMultiRespID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
@@ -538,10 +519,9 @@ func newDeliverSM(hdr *Header) *codec {
 }
 
 // NewDeliverSM creates and initializes a new DeliverSM PDU.
-func NewDeliverSM(manager interfaces.IManager) Body {
+func NewDeliverSM(seq SequenceGenerator) Body {
 	b := newDeliverSM(&Header{ID: DeliverSMID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
@@ -558,18 +538,16 @@ func newDeliverSMResp(hdr *Header) *codec {
 }
 
 // NewDeliverSMResp creates and initializes a new DeliverSMResp PDU.
-func NewDeliverSMResp(manager interfaces.IManager) Body {
+func NewDeliverSMResp(seq SequenceGenerator) Body {
 	b := newDeliverSMResp(&Header{ID: DeliverSMRespID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
 // NewDeliverSMRespSeq creates and initializes a new DeliverSMResp PDU for a specific seq.
-func NewDeliverSMRespSeq(seq uint32, manager interfaces.IManager) Body {
+func NewDeliverSMRespSeq(seq uint32, gen SequenceGenerator) Body {
 	b := newDeliverSMResp(&Header{ID: DeliverSMRespID, Seq: seq})
-	b.manager = manager
-	b.init()
+	b.init(gen)
 	return b
 }
 
@@ -581,10 +559,9 @@ func newUnbind(hdr *Header) *codec {
 }
 
 // NewUnbind creates and initializes a Unbind PDU.
-func NewUnbind(manager interfaces.IManager) Body {
+func NewUnbind(seq SequenceGenerator) Body {
 	b := newUnbind(&Header{ID: UnbindID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
@@ -596,10 +573,9 @@ func newUnbindResp(hdr *Header) *codec {
 }
 
 // NewUnbindResp creates and initializes a UnbindResp PDU.
-func NewUnbindResp(manager interfaces.IManager) Body {
+func NewUnbindResp(seq SequenceGenerator) Body {
 	b := newUnbindResp(&Header{ID: UnbindRespID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
@@ -611,10 +587,9 @@ func newEnquireLink(hdr *Header) *codec {
 }
 
 // NewEnquireLink creates and initializes a EnquireLink PDU.
-func NewEnquireLink(manager interfaces.IManager) Body {
+func NewEnquireLink(seq SequenceGenerator) Body {
 	b := newEnquireLink(&Header{ID: EnquireLinkID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
@@ -626,17 +601,15 @@ func newEnquireLinkResp(hdr *Header) *codec {
 }
 
 // NewEnquireLinkResp creates and initializes a EnquireLinkResp PDU.
-func NewEnquireLinkResp(manager interfaces.IManager) Body {
+func NewEnquireLinkResp(seq SequenceGenerator) Body {
 	b := newEnquireLinkResp(&Header{ID: EnquireLinkRespID})
-	b.manager = manager
-	b.init()
+	b.init(seq)
 	return b
 }
 
 // NewEnquireLinkRespSeq creates and initializes a EnquireLinkResp PDU for a specific seq.
-func NewEnquireLinkRespSeq(seq uint32, manager interfaces.IManager) Body {
+func NewEnquireLinkRespSeq(seq uint32, gen SequenceGenerator) Body {
 	b := newEnquireLinkResp(&Header{ID: EnquireLinkRespID, Seq: seq})
-	b.manager = manager
-	b.init()
+	b.init(gen)
 	return b
 }
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/oarkflow/protocol/interfaces"
+	"github.com/oarkflow/protocol/smpp/capture"
 	"github.com/oarkflow/protocol/smpp/pdu"
 	"github.com/oarkflow/protocol/smpp/pdu/pdufield"
 )
@@ -32,7 +33,9 @@ type Receiver struct {
 	Handler              HandlerFunc
 	SkipAutoRespondIDs   []pdu.ID
 	ObserveEnquireLink   func(float64)
+	Capture              capture.Capturer // PDU capture hook, optional.
 	manager              interfaces.IManager
+	seq                  pdu.Sequence
 	chanClose            chan struct{}
 
 	// struct which holds the map of MergeHolders for the merging of the long incoming messages.
@@ -90,6 +93,10 @@ func (r *Receiver) Bind() <-chan ConnStatus {
 		BindFunc:           r.bindFunc,
 		BindInterval:       r.BindInterval,
 		ObserveEnquireLink: r.ObserveEnquireLink,
+		ID:                 r.ID,
+		Capture:            r.Capture,
+		manager:            r.manager,
+		seq:                &r.seq,
 	}
 	r.cl.client = c
 
@@ -110,7 +117,7 @@ func (r *Receiver) Bind() <-chan ConnStatus {
 }
 
 func (r *Receiver) bindFunc(c Conn) error {
-	p := pdu.NewBindReceiver(r.manager)
+	p := pdu.NewBindReceiver(&r.seq)
 	f := p.Fields()
 	f.Set(pdufield.SystemID, r.User)
 	f.Set(pdufield.Password, r.Passwd)
@@ -167,7 +174,7 @@ loop:
 		}
 
 		if p.Header().ID == pdu.DeliverSMID && autoRespondDeliver { // Send DeliverSMResp
-			pResp := pdu.NewDeliverSMRespSeq(p.Header().Seq, r.manager)
+			pResp := pdu.NewDeliverSMRespSeq(p.Header().Seq, &r.seq)
 			r.cl.Write(pResp)
 		}
 
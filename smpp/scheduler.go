@@ -0,0 +1,61 @@
+package smpp
+
+import (
+	"sync"
+	"time"
+)
+
+// scheduler holds messages in memory until they are due. It is used
+// instead of schedule_delivery_time for SMSCs that do not support it.
+type scheduler struct {
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{timers: make(map[string]*time.Timer)}
+}
+
+// schedule calls f at the given time, replacing any pending call
+// scheduled with the same id.
+func (s *scheduler) schedule(id string, at time.Time, f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.timers[id]; ok {
+		t.Stop()
+	}
+	s.timers[id] = time.AfterFunc(time.Until(at), func() {
+		s.mu.Lock()
+		delete(s.timers, id)
+		s.mu.Unlock()
+		f()
+	})
+}
+
+// cancel removes the pending call with the given id. It reports
+// whether the call was pending.
+func (s *scheduler) cancel(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.timers[id]
+	if ok {
+		delete(s.timers, id)
+	}
+	return ok && t.Stop()
+}
+
+func (s *scheduler) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.timers)
+}
+
+// stop cancels all pending calls.
+func (s *scheduler) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, t := range s.timers {
+		t.Stop()
+		delete(s.timers, id)
+	}
+}
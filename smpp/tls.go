@@ -0,0 +1,147 @@
+package smpp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// TLSSetting is the declarative, JSON and YAML serializable TLS
+// configuration of a Setting. A nil TLSSetting disables TLS.
+type TLSSetting struct {
+	CAFile             string `json:"ca_file,omitempty" yaml:"ca_file,omitempty"`                           // PEM bundle of trusted CAs, system pool if empty.
+	CertFile           string `json:"cert_file,omitempty" yaml:"cert_file,omitempty"`                       // PEM client certificate for mutual TLS, optional.
+	KeyFile            string `json:"key_file,omitempty" yaml:"key_file,omitempty"`                         // PEM private key of CertFile.
+	ServerName         string `json:"server_name,omitempty" yaml:"server_name,omitempty"`                   // Expected server name, host of the URL if empty.
+	MinVersion         string `json:"min_version,omitempty" yaml:"min_version,omitempty"`                   // Minimum version "1.0" to "1.3", default "1.2".
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty" yaml:"insecure_skip_verify,omitempty"` // Skip server certificate verification.
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func (s TLSSetting) minVersion() (uint16, error) {
+	v := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s.MinVersion)), "tls")
+	if v == "" {
+		return tls.VersionTLS12, nil
+	}
+	if n, ok := tlsVersions[strings.TrimSpace(v)]; ok {
+		return n, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS min_version %q", s.MinVersion)
+}
+
+// TLSConfig is a tls.Config built from a TLSSetting whose CA bundle
+// and client certificate can be reloaded at runtime. The tls.Config
+// is shared by all connections: Reload only affects handshakes made
+// afterwards, established connections keep their session.
+type TLSConfig struct {
+	setting    TLSSetting
+	serverName string
+	cert       atomic.Pointer[tls.Certificate]
+	roots      atomic.Pointer[x509.CertPool]
+	config     *tls.Config
+}
+
+// NewTLSConfig loads the certificates of s for connections to addr,
+// the host of which is the expected server name unless s.ServerName
+// is set.
+func NewTLSConfig(s TLSSetting, addr string) (*TLSConfig, error) {
+	minVersion, err := s.minVersion()
+	if err != nil {
+		return nil, err
+	}
+	if (s.CertFile == "") != (s.KeyFile == "") {
+		return nil, errors.New("TLS cert_file and key_file must be set together")
+	}
+	c := &TLSConfig{setting: s, serverName: s.ServerName}
+	if c.serverName == "" {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			c.serverName = host
+		} else {
+			c.serverName = addr
+		}
+	}
+	if err = c.Reload(); err != nil {
+		return nil, err
+	}
+	c.config = &tls.Config{
+		ServerName: c.serverName,
+		MinVersion: minVersion,
+		// Verification is done by verify against the current roots,
+		// the default one would pin RootCAs for the config lifetime.
+		InsecureSkipVerify: true,
+		VerifyConnection:   c.verify,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if cert := c.cert.Load(); cert != nil {
+				return cert, nil
+			}
+			return &tls.Certificate{}, nil
+		},
+	}
+	return c, nil
+}
+
+// Config returns the tls.Config to set on Transmitter, Receiver and
+// Transceiver. It returns nil if c is nil, which disables TLS.
+func (c *TLSConfig) Config() *tls.Config {
+	if c == nil {
+		return nil
+	}
+	return c.config
+}
+
+// Reload reads the CA bundle and client certificate again. On error
+// the previously loaded certificates are kept.
+func (c *TLSConfig) Reload() error {
+	var roots *x509.CertPool
+	if c.setting.CAFile != "" {
+		b, err := os.ReadFile(c.setting.CAFile)
+		if err != nil {
+			return err
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(b) {
+			return fmt.Errorf("no certificates found in %s", c.setting.CAFile)
+		}
+	}
+	var cert *tls.Certificate
+	if c.setting.CertFile != "" {
+		pair, err := tls.LoadX509KeyPair(c.setting.CertFile, c.setting.KeyFile)
+		if err != nil {
+			return err
+		}
+		cert = &pair
+	}
+	c.roots.Store(roots)
+	c.cert.Store(cert)
+	return nil
+}
+
+func (c *TLSConfig) verify(cs tls.ConnectionState) error {
+	if c.setting.InsecureSkipVerify {
+		return nil
+	}
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server presented no certificate")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         c.roots.Load(), // nil uses the system pool
+		DNSName:       c.serverName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
@@ -0,0 +1,146 @@
+package smpp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, cn string, parent *testCert, usage x509.ExtKeyUsage) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{cn},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) write(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	kb, _ := x509.MarshalECPrivateKey(c.key)
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kb}), 0o600)
+	return certFile, keyFile
+}
+
+func TestTLSConfigReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil, x509.ExtKeyUsageAny)
+	caFile, _ := ca.write(t, dir, "ca")
+	server := newTestCert(t, "localhost", ca, x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := newTestCert(t, "client-1", ca, x509.ExtKeyUsageClientAuth).write(t, dir, "client")
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{server.der}, PrivateKey: server.key}},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    pool,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	clients := make(chan string, 4)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			tc := c.(*tls.Conn)
+			if tc.Handshake() == nil {
+				if peers := tc.ConnectionState().PeerCertificates; len(peers) > 0 {
+					clients <- peers[0].Subject.CommonName
+				}
+			}
+			c.Close()
+		}
+	}()
+
+	handshake := func(tc *TLSConfig) error {
+		c, err := tls.Dial("tcp", l.Addr().String(), tc.Config())
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		return c.Handshake()
+	}
+
+	tc, err := NewTLSConfig(TLSSetting{
+		CAFile:     caFile,
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+		ServerName: "localhost",
+		MinVersion: "1.3",
+	}, l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = handshake(tc); err != nil {
+		t.Fatal(err)
+	}
+	if cn := <-clients; cn != "client-1" {
+		t.Fatalf("want client-1, have %s", cn)
+	}
+
+	newTestCert(t, "client-2", ca, x509.ExtKeyUsageClientAuth).write(t, dir, "client")
+	if err = tc.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if err = handshake(tc); err != nil {
+		t.Fatal(err)
+	}
+	if cn := <-clients; cn != "client-2" {
+		t.Fatalf("want client-2 after reload, have %s", cn)
+	}
+
+	wrong, err := NewTLSConfig(TLSSetting{CAFile: caFile, ServerName: "example.com"}, l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = handshake(wrong); err == nil {
+		t.Fatal("want verification error for wrong server name")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/oarkflow/protocol/interfaces"
+	"github.com/oarkflow/protocol/smpp/capture"
 	"github.com/oarkflow/protocol/smpp/pdu"
 	"github.com/oarkflow/protocol/smpp/pdu/pdufield"
 )
@@ -20,17 +21,18 @@ import (
 // The API is a combination of the Transmitter and Receiver.
 type Transceiver struct {
 	ID                 string
-	Addr               string        // Server address in form of host:port.
-	User               string        // Username.
-	Passwd             string        // Password.
-	SystemType         string        // System type, default empty.
-	EnquireLink        time.Duration // Enquire link interval, default 10s.
-	EnquireLinkTimeout time.Duration // Time after last EnquireLink response when connection considered down
-	RespTimeout        time.Duration // Response timeout, default 1s.
-	BindInterval       time.Duration // Binding retry interval
-	TLS                *tls.Config   // TLS client settings, optional.
-	Handler            HandlerFunc   // Receiver handler, optional.
-	RateLimiter        RateLimiter   // Rate limiter, optional.
+	Addr               string           // Server address in form of host:port.
+	User               string           // Username.
+	Passwd             string           // Password.
+	SystemType         string           // System type, default empty.
+	EnquireLink        time.Duration    // Enquire link interval, default 10s.
+	EnquireLinkTimeout time.Duration    // Time after last EnquireLink response when connection considered down
+	RespTimeout        time.Duration    // Response timeout, default 1s.
+	BindInterval       time.Duration    // Binding retry interval
+	TLS                *tls.Config      // TLS client settings, optional.
+	Handler            HandlerFunc      // Receiver handler, optional.
+	RateLimiter        RateLimiter      // Rate limiter, optional.
+	Capture            capture.Capturer // PDU capture hook, optional.
 	WindowSize         uint
 	manager            interfaces.IManager
 
@@ -60,7 +62,10 @@ func (t *Transceiver) Bind() <-chan ConnStatus {
 		RateLimiter:        t.RateLimiter,
 		BindInterval:       t.BindInterval,
 		ObserveEnquireLink: t.ObserveEnquireLink,
+		ID:                 t.ID,
+		Capture:            t.Capture,
 		manager:            t.manager,
+		seq:                &t.seq,
 	}
 	t.cl.client = c
 	c.init()
@@ -69,7 +74,7 @@ func (t *Transceiver) Bind() <-chan ConnStatus {
 }
 
 func (t *Transceiver) bindFunc(c Conn) error {
-	p := pdu.NewBindTransceiver(t.manager)
+	p := pdu.NewBindTransceiver(&t.seq)
 	t.Transmitter.manager = t.manager
 	f := p.Fields()
 	f.Set(pdufield.SystemID, t.User)
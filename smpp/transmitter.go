@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/oarkflow/protocol/interfaces"
+	"github.com/oarkflow/protocol/smpp/capture"
 	"github.com/oarkflow/protocol/smpp/pdu"
 	"github.com/oarkflow/protocol/smpp/pdu/pdufield"
 	"github.com/oarkflow/protocol/smpp/pdu/pdutext"
@@ -44,10 +45,12 @@ type Transmitter struct {
 	TLS                *tls.Config   // TLS client settings, optional.
 	RateLimiter        RateLimiter   // Rate limiter, optional.
 	WindowSize         uint
-	ObserveEnquireLink func(float64) // Include metrics
+	ObserveEnquireLink func(float64)    // Include metrics
+	Capture            capture.Capturer // PDU capture hook, optional.
 	rMutex             sync.Mutex
 	r                  *rand.Rand
 	manager            interfaces.IManager
+	seq                pdu.Sequence
 	cl                 struct {
 		sync.Mutex
 		*client
@@ -91,7 +94,10 @@ func (t *Transmitter) Bind() <-chan ConnStatus {
 		RateLimiter:        t.RateLimiter,
 		BindInterval:       t.BindInterval,
 		ObserveEnquireLink: t.ObserveEnquireLink,
+		ID:                 t.ID,
+		Capture:            t.Capture,
 		manager:            t.manager,
+		seq:                &t.seq,
 	}
 	t.cl.client = c
 	c.init()
@@ -100,7 +106,7 @@ func (t *Transmitter) Bind() <-chan ConnStatus {
 }
 
 func (t *Transmitter) bindFunc(c Conn) error {
-	p := pdu.NewBindTransmitter(t.manager)
+	p := pdu.NewBindTransmitter(&t.seq)
 	f := p.Fields()
 	f.Set(pdufield.SystemID, t.User)
 	f.Set(pdufield.Password, t.Passwd)
@@ -134,7 +140,7 @@ func (t *Transmitter) handlePDU(f HandlerFunc) {
 			f(p)
 		}
 		if p.Header().ID == pdu.DeliverSMID { // Send DeliverSMResp
-			pResp := pdu.NewDeliverSMRespSeq(p.Header().Seq, t.manager)
+			pResp := pdu.NewDeliverSMRespSeq(p.Header().Seq, &t.seq)
 			t.cl.Write(pResp)
 		}
 	}
@@ -194,9 +200,14 @@ type ShortMessage struct {
 	DstList  []string // List of destination addreses for submit multi
 	DLs      []string // List if destribution list for submit multi
 	Text     pdutext.Codec
-	Validity time.Duration
+	Validity time.Duration // Relative to now, sent in absolute format.
 	Register pdufield.DeliverySetting
 
+	// ValidityPeriod is the formatted validity_period, absolute or
+	// relative, see pdufield.AbsoluteDate and pdufield.RelativeDate.
+	// It takes precedence over Validity.
+	ValidityPeriod string
+
 	// Other fields, normally optional.
 	TLVFields            pdutlv.Fields
 	ServiceType          string
@@ -204,6 +215,8 @@ type ShortMessage struct {
 	SourceAddrNPI        uint8
 	DestAddrTON          uint8
 	DestAddrNPI          uint8
+	DstListTON           []uint8 // TON of each address of DstList, DestAddrTON if missing.
+	DstListNPI           []uint8 // NPI of each address of DstList, DestAddrNPI if missing.
 	ESMClass             uint8
 	ProtocolID           uint8
 	PriorityFlag         uint8
@@ -335,22 +348,25 @@ func (dm *DataMessage) RespID() string {
 // Submit sends a short message and returns and updates the given
 // sm with the response status. It returns the same sm object.
 func (t *Transmitter) Submit(sm *ShortMessage) (*ShortMessage, error) {
+	if err := sm.TLVFields.Validate(); err != nil {
+		return nil, err
+	}
 	if len(sm.DstList) > 0 || len(sm.DLs) > 0 {
 		// if we have a single destination address add it to the list
 		if sm.Dst != "" {
 			sm.DstList = append(sm.DstList, sm.Dst)
 		}
-		p := pdu.NewSubmitMulti(sm.TLVFields, t.manager)
+		p := pdu.NewSubmitMulti(sm.TLVFields, &t.seq)
 		return t.submitMsgMulti(sm, p, uint8(sm.Text.Type()))
 	}
-	p := pdu.NewSubmitSM(sm.TLVFields, t.manager)
+	p := pdu.NewSubmitSM(sm.TLVFields, &t.seq)
 	return t.submitMsg(sm, p, uint8(sm.Text.Type()))
 }
 
 // DataMsg sends a short message and returns and updates the given
 // sm with the response status. It returns the same sm object.
 func (t *Transmitter) DataMsg(dm *DataMessage) (*DataMessage, error) {
-	p := pdu.NewDataSM(dm.TLVFields, t.manager)
+	p := pdu.NewDataSM(dm.TLVFields, &t.seq)
 	return t.dataMsg(dm, p)
 }
 
@@ -404,10 +420,13 @@ func (t *Transmitter) SubmitLongMsg8bit(sm *ShortMessage) ([]ShortMessage, error
 	UDHHeader[2] = 3
 	UDHHeader[3] = ri
 	UDHHeader[4] = uint8(countParts)
+	if err := sm.TLVFields.Validate(); err != nil {
+		return nil, err
+	}
 	var responses []ShortMessage
 	for i := 0; i < countParts; i++ {
 		UDHHeader[5] = uint8(i + 1) // current message part
-		p := pdu.NewSubmitSM(sm.TLVFields, t.manager)
+		p := pdu.NewSubmitSM(sm.TLVFields, &t.seq)
 		f := p.Fields()
 		f.Set(pdufield.SourceAddr, sm.Src)
 		f.Set(pdufield.DestinationAddr, sm.Dst)
@@ -417,8 +436,8 @@ func (t *Transmitter) SubmitLongMsg8bit(sm *ShortMessage) ([]ShortMessage, error
 			f.Set(pdufield.ShortMessage, pdutext.Raw(append(UDHHeader, rawMsg[i*maxLen:]...)))
 		}
 		f.Set(pdufield.RegisteredDelivery, uint8(sm.Register))
-		if sm.Validity != time.Duration(0) {
-			f.Set(pdufield.ValidityPeriod, convertValidity(sm.Validity))
+		if v := sm.validityPeriod(); v != "" {
+			f.Set(pdufield.ValidityPeriod, v)
 		}
 		f.Set(pdufield.ServiceType, sm.ServiceType)
 		f.Set(pdufield.SourceAddrTON, sm.SourceAddrTON)
@@ -481,6 +500,9 @@ func (t *Transmitter) SubmitLongMsg(sm *ShortMessage) ([]ShortMessage, error) {
 	} else if sm.Text.Type() == pdutext.Latin1Type {
 		maxLen = 152 // to avoid a character being split between payloads
 	}
+	if err := sm.TLVFields.Validate(); err != nil {
+		return nil, err
+	}
 	rawMsg := sm.Text.Encode()
 	countParts := int((len(rawMsg)-1)/maxLen) + 1
 	parts := make([]ShortMessage, 0, countParts)
@@ -506,7 +528,7 @@ func (t *Transmitter) SubmitLongMsg(sm *ShortMessage) ([]ShortMessage, error) {
 	}
 	for i := 0; i < countParts; i++ {
 		UDHHeader[len(UDHHeader)-1] = uint8(i + 1) // current message part
-		p := pdu.NewSubmitSM(sm.TLVFields, t.manager)
+		p := pdu.NewSubmitSM(sm.TLVFields, &t.seq)
 		f := p.Fields()
 		f.Set(pdufield.SourceAddr, sm.Src)
 		f.Set(pdufield.DestinationAddr, sm.Dst)
@@ -516,8 +538,8 @@ func (t *Transmitter) SubmitLongMsg(sm *ShortMessage) ([]ShortMessage, error) {
 			f.Set(pdufield.ShortMessage, pdutext.Raw(append(UDHHeader, rawMsg[i*maxLen:]...)))
 		}
 		f.Set(pdufield.RegisteredDelivery, uint8(sm.Register))
-		if sm.Validity != time.Duration(0) {
-			f.Set(pdufield.ValidityPeriod, convertValidity(sm.Validity))
+		if v := sm.validityPeriod(); v != "" {
+			f.Set(pdufield.ValidityPeriod, v)
 		}
 		f.Set(pdufield.ServiceType, sm.ServiceType)
 		f.Set(pdufield.SourceAddrTON, sm.SourceAddrTON)
@@ -610,7 +632,7 @@ func (t *Transmitter) DataLongMsg(dm *DataMessage) ([]DataMessage, error) {
 			delete(fields, pdutlv.TagMoreMessagesToSend)
 		}
 
-		p := pdu.NewDataSM(fields, t.manager)
+		p := pdu.NewDataSM(fields, &t.seq)
 		f := p.Fields()
 		f.Set(pdufield.SourceAddr, dm.Src)
 		f.Set(pdufield.DestinationAddr, dm.Dst)
@@ -660,8 +682,8 @@ func (t *Transmitter) submitMsg(sm *ShortMessage, p pdu.Body, dataCoding uint8)
 	}
 	f.Set(pdufield.RegisteredDelivery, uint8(sm.Register))
 	// Check if the message has validity set.
-	if sm.Validity != time.Duration(0) {
-		f.Set(pdufield.ValidityPeriod, convertValidity(sm.Validity))
+	if v := sm.validityPeriod(); v != "" {
+		f.Set(pdufield.ValidityPeriod, v)
 	}
 	f.Set(pdufield.ServiceType, sm.ServiceType)
 	f.Set(pdufield.SourceAddrTON, sm.SourceAddrTON)
@@ -703,11 +725,18 @@ func (t *Transmitter) submitMsgMulti(sm *ShortMessage, p pdu.Body, dataCoding ui
 	// Put destination addresses and lists inside an byte array
 	var bArray []byte
 	// destination addresses
-	for _, destAddr := range sm.DstList {
+	for i, destAddr := range sm.DstList {
+		ton, npi := sm.DestAddrTON, sm.DestAddrNPI
+		if i < len(sm.DstListTON) {
+			ton = sm.DstListTON[i]
+		}
+		if i < len(sm.DstListNPI) {
+			npi = sm.DstListNPI[i]
+		}
 		// 1 - SME Address
 		bArray = append(bArray, byte(0x01))
-		bArray = append(bArray, byte(sm.DestAddrTON))
-		bArray = append(bArray, byte(sm.DestAddrNPI))
+		bArray = append(bArray, byte(ton))
+		bArray = append(bArray, byte(npi))
 		bArray = append(bArray, []byte(destAddr)...)
 		// null terminator
 		bArray = append(bArray, byte(0x00))
@@ -729,8 +758,8 @@ func (t *Transmitter) submitMsgMulti(sm *ShortMessage, p pdu.Body, dataCoding ui
 	f.Set(pdufield.NumberDests, uint8(numberOfDest))
 	f.Set(pdufield.RegisteredDelivery, uint8(sm.Register))
 	// Check if the message has validity set.
-	if sm.Validity != time.Duration(0) {
-		f.Set(pdufield.ValidityPeriod, convertValidity(sm.Validity))
+	if v := sm.validityPeriod(); v != "" {
+		f.Set(pdufield.ValidityPeriod, v)
 	}
 	f.Set(pdufield.ServiceType, sm.ServiceType)
 	f.Set(pdufield.SourceAddrTON, sm.SourceAddrTON)
@@ -772,7 +801,7 @@ type QueryResp struct {
 // QuerySM queries the delivery status of a message. It requires the
 // source address (sender) with TON and NPI and message ID.
 func (t *Transmitter) QuerySM(src, msgid string, srcTON, srcNPI uint8) (*QueryResp, error) {
-	p := pdu.NewQuerySM(t.manager)
+	p := pdu.NewQuerySM(&t.seq)
 	f := p.Fields()
 	f.Set(pdufield.SourceAddr, src)
 	f.Set(pdufield.SourceAddrTON, srcTON)
@@ -795,30 +824,7 @@ func (t *Transmitter) QuerySM(src, msgid string, srcTON, srcNPI uint8) (*QueryRe
 		return nil, fmt.Errorf("no state available")
 	}
 	qr := &QueryResp{MsgID: msgid}
-	switch ms.Bytes()[0] {
-	case 0:
-		qr.MsgState = "SCHEDULED"
-	case 1:
-		qr.MsgState = "ENROUTE"
-	case 2:
-		qr.MsgState = "DELIVERED"
-	case 3:
-		qr.MsgState = "EXPIRED"
-	case 4:
-		qr.MsgState = "DELETED"
-	case 5:
-		qr.MsgState = "UNDELIVERABLE"
-	case 6:
-		qr.MsgState = "ACCEPTED"
-	case 7:
-		qr.MsgState = "UNKNOWN"
-	case 8:
-		qr.MsgState = "REJECTED"
-	case 9:
-		qr.MsgState = "SKIPPED"
-	default:
-		qr.MsgState = fmt.Sprintf("UNKNOWN (%d)", ms.Bytes()[0])
-	}
+	qr.MsgState = pdutlv.MessageState(ms.Bytes()[0]).String()
 	if fd := f[pdufield.FinalDate]; fd != nil {
 		qr.FinalDate = fd.String()
 	}
@@ -828,8 +834,42 @@ func (t *Transmitter) QuerySM(src, msgid string, srcTON, srcNPI uint8) (*QueryRe
 	return qr, nil
 }
 
+// CancelSM cancels a previously submitted message that is pending
+// delivery. It requires the source address (sender) with TON and NPI
+// and message ID. The destination address is optional.
+func (t *Transmitter) CancelSM(src, dst, msgid string, srcTON, srcNPI uint8) error {
+	p := pdu.NewCancelSM(&t.seq)
+	f := p.Fields()
+	f.Set(pdufield.MessageID, msgid)
+	f.Set(pdufield.SourceAddr, src)
+	f.Set(pdufield.SourceAddrTON, srcTON)
+	f.Set(pdufield.SourceAddrNPI, srcNPI)
+	f.Set(pdufield.DestinationAddr, dst)
+
+	resp, err := t.do(p)
+	if err != nil {
+		return err
+	}
+	if id := resp.PDU.Header().ID; id != pdu.CancelSMRespID {
+		return fmt.Errorf("unexpected PDU ID: %s", id)
+	}
+	if s := resp.PDU.Header().Status; s != 0 {
+		return s
+	}
+	return nil
+}
+
+func (sm *ShortMessage) validityPeriod() string {
+	if sm.ValidityPeriod != "" {
+		return sm.ValidityPeriod
+	}
+	if sm.Validity != time.Duration(0) {
+		return convertValidity(sm.Validity)
+	}
+	return ""
+}
+
 func convertValidity(d time.Duration) string {
-	validity := time.Now().UTC().Add(d)
 	// Absolute time format YYMMDDhhmmsstnnp, see SMPP3.4 spec 7.1.1.
-	return validity.Format("060102150405") + "000+"
+	return pdufield.AbsoluteDate(time.Now().UTC().Add(d))
 }
@@ -1,23 +1,59 @@
 package protocol
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/oarkflow/errors"
+
+	"github.com/oarkflow/protocol/address"
+	"github.com/oarkflow/protocol/bounce"
+	"github.com/oarkflow/protocol/i18n"
 	"github.com/oarkflow/protocol/smtp"
+	"github.com/oarkflow/protocol/suppression"
 )
 
 type SMTP struct {
-	mailer  *smtp.Mailer
-	Config  smtp.Config
-	Service string
+	mailer       *smtp.Mailer // Of the first provider, rendering views.
+	providers    *smtp.Providers
+	Config       smtp.Config
+	Service      string
+	Suppression  *suppression.List   // Recipients not to email, updated from bounces and complaints.
+	QueueOptions QueueOptions        // Worker pool used by Queue.
+	Callbacks    *CallbackDispatcher // Posts the lifecycle events of payloads to their CallbackURL.
+	Catalog      *i18n.Catalog       // Templates of the payloads naming one, see Payload.Localize.
+	Addresses    *address.Validator  // Checks the domains of the recipients before sending, if set.
+	next         Service
+	queue        serviceQueue
+	mu           sync.Mutex
+	sent         map[string]Payload // Message-ID to the payload of the emails awaiting a delivery report.
 }
 
+// reportWindow is how long a sent email is tracked for its delivery
+// report.
+const reportWindow = 7 * 24 * time.Hour
+
 func (s *SMTP) Setup() error {
 	return nil
 }
 
-func (s *SMTP) SetService(service Service) {
+// Close stops the worker pool of the service, if started, and closes
+// the connections of its providers.
+func (s *SMTP) Close() error {
+	err := s.queue.close()
+	if e := s.providers.Close(); e != nil {
+		return e
+	}
+	return err
+}
 
+func (s *SMTP) SetService(service Service) {
+	s.next = service
 }
 
 func (s *SMTP) GetType() Type {
@@ -29,24 +65,278 @@ func (s *SMTP) GetServiceType() string {
 }
 
 func (s *SMTP) Handle(payload Payload) (Response, error) {
-	from := payload.From
-	if payload.FromName != "" {
-		from = fmt.Sprintf("%s<%s>", payload.FromName, from)
-	}
-	err := s.mailer.Send(smtp.Mail{
-		To:          []string{payload.To},
-		From:        from,
-		Subject:     payload.Subject,
-		Body:        payload.Message,
-		Cc:          []string{payload.Cc},
-		Attachments: payload.Attachments,
+	return fallback(s.next, payload, s.handle, func(res Response, err error) {
+		s.Callbacks.notify(s, payload, EventSent, res, err)
+	})
+}
+
+// SendEmail sends email, falling back to the next service on failure.
+func (s *SMTP) SendEmail(email EmailPayload) (Response, error) {
+	return s.Handle(email.Payload())
+}
+
+func (s *SMTP) handle(payload Payload) (Response, error) {
+	if payload.Template != "" {
+		t, err := payload.Localize(s.Catalog)
+		if err != nil {
+			return nil, err
+		}
+		if t.View != "" {
+			body, err := s.mailer.Localized(t.View, payload.Locale, payload.Data)
+			if err != nil {
+				return nil, err
+			}
+			payload.Message = body.Content
+		}
+	}
+	email := payload.Email()
+	if err := email.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.normalize(&email); err != nil {
+		return nil, err
+	}
+	if s.Suppression != nil {
+		var err error
+		// The email is sent to the recipients not suppressed, if any.
+		if email.To, err = s.unsuppressed(email.To); len(email.To) == 0 {
+			return nil, err
+		}
+		email.Cc, _ = s.unsuppressed(email.Cc)
+		email.Bcc, _ = s.unsuppressed(email.Bcc)
+	}
+	messageID, err := smtp.GenerateMessageID()
+	if err != nil {
+		return nil, err
+	}
+	provider, err := s.providers.Send(smtp.Mail{
+		To:          email.To,
+		From:        email.From,
+		Subject:     email.Subject,
+		Body:        email.Message,
+		Text:        email.Text,
+		Cc:          email.Cc,
+		Bcc:         email.Bcc,
+		ReplyTo:     email.ReplyTo,
+		MessageID:   messageID,
+		Attachments: email.Attachments,
+	})
+	if err != nil {
+		// The rejected mailbox is only known with a single recipient.
+		if len(email.To)+len(email.Cc)+len(email.Bcc) == 1 && isBadMailbox(err) {
+			s.Bounce(email.To[0], err.Error())
+		}
+		return nil, err
+	}
+	res := Response("email dispatched")
+	if s.providers.Len() > 1 {
+		res = Response("email dispatched via " + provider)
+	}
+	s.Callbacks.notify(s, payload, EventSent, res, nil)
+	s.track(messageID, payload)
+	return res, nil
+}
+
+// normalize validates the addresses of email, the domains of the
+// recipients with the Addresses validator, and rewrites them with ASCII
+// domains. The sender gets FromName as display name.
+func (s *SMTP) normalize(email *EmailPayload) error {
+	ctx := context.Background()
+	for i, list := range []*[]string{&email.To, &email.Cc, &email.Bcc} {
+		var addrs []string
+		for _, addr := range *list {
+			if strings.TrimSpace(addr) == "" {
+				continue
+			}
+			a, err := s.Addresses.Validate(ctx, addr)
+			if err != nil {
+				return addressField([]string{"to", "cc", "bcc"}[i], err)
+			}
+			addrs = append(addrs, a.String())
+		}
+		*list = addrs
+	}
+	if len(email.To) == 0 {
+		return errors.New("Email has no recipient")
+	}
+	if email.From != "" {
+		a, err := address.Parse(email.From)
+		if err != nil {
+			return addressField("from", err)
+		}
+		if email.FromName != "" {
+			a.Name = email.FromName
+		}
+		email.From = a.String()
+	}
+	if email.ReplyTo != "" {
+		a, err := address.Parse(email.ReplyTo)
+		if err != nil {
+			return addressField("reply_to", err)
+		}
+		email.ReplyTo = a.String()
+	}
+	return nil
+}
+
+// track keeps the payload of the email with the given message ID for
+// its delivery report, if its callback or queued job is to be updated.
+func (s *SMTP) track(messageID string, payload Payload) {
+	if (s.Callbacks == nil || payload.CallbackURL == "") && (payload.ID == "" || s.queue.started() == nil) {
+		return
+	}
+	messageID = strings.Trim(messageID, "<>")
+	if payload.SentAt.IsZero() {
+		payload.SentAt = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sent == nil {
+		s.sent = make(map[string]Payload)
+	}
+	s.sent[messageID] = Payload{ID: payload.ID, To: payload.To, CallbackURL: payload.CallbackURL, SentAt: payload.SentAt}
+	time.AfterFunc(reportWindow, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.sent, messageID)
 	})
+}
+
+// ProcessReport processes the delivery status notification or
+// feedback report read from r, about an email sent by the service.
+// Recipients whose mailbox does not exist are suppressed as bounced,
+// and those reporting the email as spam as complaints. The first
+// report of an email sent within a week marks its payload delivered
+// or failed, updating its callback and queued job.
+func (s *SMTP) ProcessReport(r io.Reader) (*bounce.Report, error) {
+	report, err := bounce.Parse(r)
 	if err != nil {
 		return nil, err
 	}
-	return Response("email dispatched"), nil
+	for _, rcpt := range report.Recipients {
+		switch {
+		case report.Kind == bounce.Feedback:
+			err = s.Complaint(rcpt.Address, rcpt.Diagnostic)
+		case rcpt.BadMailbox():
+			err = s.Bounce(rcpt.Address, rcpt.Diagnostic)
+		}
+		if err != nil {
+			return report, err
+		}
+	}
+	if report.Kind == bounce.DSN {
+		s.delivered(report)
+	}
+	return report, nil
+}
+
+// PollReports processes the reports received in mailbox every
+// interval until ctx is done, see ProcessReport.
+func (s *SMTP) PollReports(ctx context.Context, mailbox bounce.Mailbox, interval time.Duration) error {
+	return bounce.Poll(ctx, mailbox, interval, func(r io.Reader) error {
+		_, err := s.ProcessReport(r)
+		return err
+	})
+}
+
+// delivered reports the outcome of the email of a DSN, failed if it
+// failed for any recipient. DSNs only delaying or relaying the email
+// are ignored.
+func (s *SMTP) delivered(report *bounce.Report) {
+	var err error
+	status, event := JobDelivered, EventDelivered
+	if failed := report.Failed(); len(failed) > 0 {
+		reason := failed[0].Diagnostic
+		if reason == "" {
+			reason = failed[0].Status
+		}
+		status, event, err = JobFailed, EventFailed, fmt.Errorf("%s: %s", failed[0].Address, reason)
+	} else if !delivery(report) {
+		return
+	}
+	s.mu.Lock()
+	payload, ok := s.sent[report.MessageID]
+	delete(s.sent, report.MessageID)
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	s.Callbacks.notify(s, payload, event, nil, err)
+	if jobs := s.queue.started(); jobs != nil && payload.ID != "" {
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		}
+		jobs.Report(payload.ID, status, reason)
+	}
+}
+
+// delivery reports whether a DSN reports the email delivered.
+func delivery(report *bounce.Report) bool {
+	for _, rcpt := range report.Recipients {
+		if rcpt.Action == bounce.Delivered {
+			return true
+		}
+	}
+	return false
 }
 
+// unsuppressed returns the addresses not suppressed and the error of
+// the first suppressed one.
+func (s *SMTP) unsuppressed(addrs []string) ([]string, error) {
+	var kept []string
+	var first error
+	for _, addr := range addrs {
+		if err := s.Suppression.Check(suppression.Email, addr); err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		kept = append(kept, addr)
+	}
+	return kept, first
+}
+
+// Bounce suppresses recipient after a permanent delivery failure.
+func (s *SMTP) Bounce(recipient, diagnostic string) error {
+	if s.Suppression == nil {
+		return nil
+	}
+	return s.Suppression.Suppress(suppression.Email, recipient, suppression.Bounce, diagnostic)
+}
+
+// Complaint suppresses recipient after a spam complaint.
+func (s *SMTP) Complaint(recipient, feedback string) error {
+	if s.Suppression == nil {
+		return nil
+	}
+	return s.Suppression.Suppress(suppression.Email, recipient, suppression.Complaint, feedback)
+}
+
+// isBadMailbox reports whether err is a permanent rejection of the
+// recipient mailbox, see bounce.BadMailbox.
+func isBadMailbox(err error) bool {
+	var te *textproto.Error
+	if !errors.As(err, &te) || te.Code < 500 || te.Code >= 600 {
+		return false
+	}
+	status, _, _ := strings.Cut(te.Msg, " ")
+	return bounce.BadMailbox(status)
+}
+
+// Queue queues payload on the worker pool of the service and returns
+// the job ID.
 func (s *SMTP) Queue(payload Payload) (Response, error) {
-	return s.Handle(payload)
+	return s.queue.enqueue(s, s.queued, s.QueueOptions, s.Callbacks, payload)
+}
+
+// Jobs returns the worker pool of the service, started with
+// QueueOptions on first use.
+func (s *SMTP) Jobs() (*JobQueue, error) {
+	return s.queue.get(s, s.queued, s.QueueOptions)
+}
+
+func (s *SMTP) queued(payload *Payload) (Response, error) {
+	return s.Handle(*payload)
 }
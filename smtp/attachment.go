@@ -0,0 +1,174 @@
+package smtp
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+
+	sMail "github.com/xhit/go-simple-mail/v2"
+)
+
+// Attachment is a file attached to a Mail, read from the first of
+// Data, Reader, Path and URL set.
+type Attachment struct {
+	Name   string    `json:"name,omitempty"` // File name, the base of Path or URL if empty.
+	Data   []byte    `json:"data,omitempty"`
+	Reader io.Reader `json:"-"` // Read once, Send replacing it by Data. Closed if an io.Closer.
+	// Path is a local file. It cannot be set from JSON, payloads
+	// being untrusted.
+	Path     string `json:"-"`
+	URL      string `json:"url,omitempty"`       // Fetched with the HTTPClient of the Mailer.
+	MimeType string `json:"mime_type,omitempty"` // Detected from the name or the content if empty.
+	// Inline attachments are images shown by the HTML body, which
+	// refers to them by name, e.g. <img src="cid:logo.png">.
+	Inline bool `json:"inline,omitempty"`
+	// Deprecated: use Name, or Path for AttachFiles.
+	File string `json:"file,omitempty"`
+	// Deprecated: use Name.
+	FileName string `json:"filename,omitempty"`
+}
+
+// SizeError is returned by Send for an attachment or a message larger
+// than the limits of the Config.
+type SizeError struct {
+	Attachment string // Name of the attachment too large, empty if the whole message is.
+	Limit      int64
+}
+
+func (e *SizeError) Error() string {
+	if e.Attachment != "" {
+		return fmt.Sprintf("Mail Error: attachment %q exceeds the size limit of %d bytes", e.Attachment, e.Limit)
+	}
+	return fmt.Sprintf("Mail Error: message exceeds the size limit of %d bytes", e.Limit)
+}
+
+// name returns the file name of a.
+func (a *Attachment) name() string {
+	for _, name := range []string{a.Name, a.FileName, a.File} {
+		if name != "" {
+			return name
+		}
+	}
+	if a.Path != "" {
+		return filepath.Base(a.Path)
+	}
+	if u, err := url.Parse(a.URL); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+		return path.Base(u.Path)
+	}
+	return ""
+}
+
+// attach adds the attachments of msg to email, within the size limits
+// given the size of the body.
+func (m *Mailer) attach(email *sMail.Email, msg Mail, size int64) error {
+	maxMessage := m.Config.MaxMessageSize
+	if maxMessage > 0 && size > maxMessage {
+		return &SizeError{Limit: maxMessage}
+	}
+	attachments := make([]*Attachment, 0, len(msg.Attachments)+len(msg.AttachFiles))
+	for i := range msg.Attachments {
+		attachments = append(attachments, &msg.Attachments[i])
+	}
+	for _, a := range msg.AttachFiles {
+		// The File of AttachFiles is a path.
+		a.Path, a.File = a.File, ""
+		attachments = append(attachments, &a)
+	}
+	for _, a := range attachments {
+		name := a.name()
+		limit, tooLarge := int64(-1), error(nil)
+		if maxAttachment := m.Config.MaxAttachmentSize; maxAttachment > 0 {
+			limit, tooLarge = maxAttachment, &SizeError{Attachment: name, Limit: maxAttachment}
+		}
+		if maxMessage > 0 && (limit < 0 || maxMessage-size < limit) {
+			limit, tooLarge = maxMessage-size, &SizeError{Limit: maxMessage}
+		}
+		data, mimeType, err := m.read(a, limit)
+		if err != nil {
+			return err
+		}
+		if limit >= 0 && int64(len(data)) > limit {
+			return tooLarge
+		}
+		size += int64(len(data))
+		if mimeType == "" {
+			mimeType = detectType(name, data)
+		}
+		if name == "" {
+			name = "attachment"
+			if exts, _ := mime.ExtensionsByType(mimeType); len(exts) > 0 {
+				name += exts[0]
+			}
+		}
+		email.Attach(&sMail.File{Data: data, Name: name, MimeType: mimeType, Inline: a.Inline})
+	}
+	return nil
+}
+
+// read returns the content of a, reading at most limit+1 bytes unless
+// limit is negative, and its MIME type if known.
+func (m *Mailer) read(a *Attachment, limit int64) ([]byte, string, error) {
+	switch {
+	case a.Data != nil:
+		return a.Data, a.MimeType, nil
+	case a.Reader != nil:
+		if c, ok := a.Reader.(io.Closer); ok {
+			defer c.Close()
+		}
+		data, err := readLimited(a.Reader, limit)
+		if err == nil {
+			// A retried Mail is sent with the content read.
+			a.Data, a.Reader = data, nil
+		}
+		return data, a.MimeType, err
+	case a.Path != "":
+		f, err := os.Open(a.Path)
+		if err != nil {
+			return nil, "", fmt.Errorf("Mail Error: attachment: %w", err)
+		}
+		defer f.Close()
+		data, err := readLimited(f, limit)
+		return data, a.MimeType, err
+	case a.URL != "":
+		resp, err := m.HTTPClient.Get(a.URL, nil)
+		if err != nil {
+			return nil, "", fmt.Errorf("Mail Error: attachment %s: %w", a.URL, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return nil, "", fmt.Errorf("Mail Error: attachment %s: status %d", a.URL, resp.StatusCode)
+		}
+		mimeType := a.MimeType
+		if mimeType == "" {
+			if t, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && t != "application/octet-stream" {
+				mimeType = t
+			}
+		}
+		data, err := readLimited(resp.Body, limit)
+		return data, mimeType, err
+	}
+	return nil, "", fmt.Errorf("Mail Error: attachment %q has no content", a.name())
+}
+
+// readLimited reads r up to limit+1 bytes, to tell if it is larger
+// than limit, or fully if limit is negative.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	if limit >= 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+	return io.ReadAll(r)
+}
+
+// detectType returns the MIME type of a file from its name, or its
+// content if the extension is unknown.
+func detectType(name string, data []byte) string {
+	if t := mime.TypeByExtension(filepath.Ext(name)); t != "" {
+		return t
+	}
+	return http.DetectContentType(data)
+}
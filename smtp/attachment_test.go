@@ -0,0 +1,66 @@
+package smtp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/oarkflow/protocol/smtp/smtptest"
+)
+
+func TestMailerAttachments(t *testing.T) {
+	srv := smtptest.NewServer()
+	defer srv.Close()
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		io.WriteString(w, "%PDF-1.4")
+	}))
+	defer files.Close()
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("notes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(Config{Host: srv.Host, Port: srv.Port, Encryption: "none", FromAddress: "noreply@example.com", MaxAttachmentSize: 16, MaxMessageSize: 64}, nil)
+	defer m.Close()
+	msg := Mail{To: []string{"jane@example.com"}, Subject: "Files", Body: "<p>Hi</p>", Attachments: []Attachment{
+		{Reader: strings.NewReader("\x89PNG\r\n\x1a\n"), Name: "logo", Inline: true},
+		{Path: path},
+		{URL: files.URL + "/invoice.pdf"},
+		{Data: []byte("a,b"), File: "old.csv"},
+	}}
+	if err := m.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Attachments[0].Reader != nil || string(msg.Attachments[0].Data) != "\x89PNG\r\n\x1a\n" {
+		t.Fatal("want read content kept for retries")
+	}
+	want := map[string]string{"logo": "image/png", "notes.txt": "text/plain", "invoice.pdf": "application/pdf", "old.csv": "text/csv"}
+	attachments := srv.Messages()[0].Attachments()
+	if len(attachments) != len(want) {
+		t.Fatalf("want %d attachments, have %+v", len(want), attachments)
+	}
+	for _, a := range attachments {
+		if !strings.HasPrefix(a.ContentType, want[a.FileName]) {
+			t.Errorf("%s: want %s, have %s", a.FileName, want[a.FileName], a.ContentType)
+		}
+	}
+
+	var se *SizeError
+	err := m.Send(Mail{To: []string{"jane@example.com"}, Attachments: []Attachment{{Name: "big.bin", Reader: strings.NewReader(strings.Repeat("x", 17))}}})
+	if !errors.As(err, &se) || se.Attachment != "big.bin" || se.Limit != 16 {
+		t.Fatalf("want attachment size error, have %v", err)
+	}
+	err = m.Send(Mail{To: []string{"jane@example.com"}, Body: strings.Repeat("x", 50), Attachments: []Attachment{{Name: "a.bin", Data: make([]byte, 16)}}})
+	if !errors.As(err, &se) || se.Attachment != "" || se.Limit != 64 {
+		t.Fatalf("want message size error, have %v", err)
+	}
+	if err = m.Send(Mail{To: []string{"jane@example.com"}, Attachments: []Attachment{{Name: "none"}}}); err == nil {
+		t.Fatal("want error for attachment without content")
+	}
+}
@@ -0,0 +1,114 @@
+package smtp
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/oarkflow/errors"
+	"github.com/toorop/go-dkim"
+)
+
+// DefaultDKIMHeaders are the headers signed when DKIMConfig.Headers is
+// empty.
+var DefaultDKIMHeaders = []string{"From", "To", "Cc", "Reply-To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type"}
+
+// DKIMConfig configures the DKIM signature of the mail of a Mailer,
+// verified by recipients against the public key published in the DNS
+// TXT record of <selector>._domainkey.<domain>.
+type DKIMConfig struct {
+	Domain         string `json:"domain" yaml:"domain" env:"MAIL_DKIM_DOMAIN"`
+	Selector       string `json:"selector" yaml:"selector" env:"MAIL_DKIM_SELECTOR"`
+	PrivateKeyFile string `json:"private_key" yaml:"private_key" env:"MAIL_DKIM_PRIVATE_KEY"` // PEM RSA key, PKCS #1 or #8.
+	// HeaderCanonicalization and BodyCanonicalization are "relaxed",
+	// the default, or "simple".
+	HeaderCanonicalization string   `json:"header_canonicalization,omitempty" yaml:"header_canonicalization,omitempty" env:"MAIL_DKIM_HEADER_CANONICALIZATION"`
+	BodyCanonicalization   string   `json:"body_canonicalization,omitempty" yaml:"body_canonicalization,omitempty" env:"MAIL_DKIM_BODY_CANONICALIZATION"`
+	Headers                []string `json:"headers,omitempty" yaml:"headers,omitempty"` // Signed headers, DefaultDKIMHeaders if empty. From is always signed.
+}
+
+// dkimSigner signs messages with the options of a DKIMConfig.
+type dkimSigner struct {
+	options dkim.SigOptions
+}
+
+// newDKIMSigner loads the key of c and checks its settings, so that
+// a bad configuration fails when the Mailer is created rather than
+// when sending.
+func newDKIMSigner(c DKIMConfig) (*dkimSigner, error) {
+	if c.Domain == "" || c.Selector == "" || c.PrivateKeyFile == "" {
+		return nil, errors.New("DKIM domain, selector and private_key are required")
+	}
+	key, err := os.ReadFile(c.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("DKIM private key: %w", err)
+	}
+	if err = checkDKIMKey(key); err != nil {
+		return nil, fmt.Errorf("DKIM private key %s: %w", c.PrivateKeyFile, err)
+	}
+	header, err := canonicalization(c.HeaderCanonicalization)
+	if err != nil {
+		return nil, fmt.Errorf("DKIM header_canonicalization: %w", err)
+	}
+	body, err := canonicalization(c.BodyCanonicalization)
+	if err != nil {
+		return nil, fmt.Errorf("DKIM body_canonicalization: %w", err)
+	}
+	headers := c.Headers
+	if len(headers) == 0 {
+		headers = DefaultDKIMHeaders
+	}
+	options := dkim.NewSigOptions()
+	options.PrivateKey = key
+	options.Domain = c.Domain
+	options.Selector = c.Selector
+	options.Canonicalization = header + "/" + body
+	options.Headers = []string{"from"}
+	for _, h := range headers {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" && h != "from" {
+			options.Headers = append(options.Headers, h)
+		}
+	}
+	return &dkimSigner{options: options}, nil
+}
+
+// sigOptions returns the options to sign a message. dkim.Sign writes
+// to the headers, which are copied for concurrent sends.
+func (s *dkimSigner) sigOptions() dkim.SigOptions {
+	options := s.options
+	options.Headers = append([]string(nil), s.options.Headers...)
+	return options
+}
+
+func canonicalization(s string) (string, error) {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case "":
+		return "relaxed", nil
+	case "relaxed", "simple":
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown canonicalization %q, want relaxed or simple", s)
+}
+
+// checkDKIMKey reports whether key is a PEM RSA private key, the only
+// keys dkim.Sign handles.
+func checkDKIMKey(key []byte) error {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return errors.New("no PEM data")
+	}
+	if _, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return err
+	}
+	if _, ok := parsed.(*rsa.PrivateKey); !ok {
+		return fmt.Errorf("%T is not an RSA key", parsed)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package smtp
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeKey(t *testing.T, key any) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "dkim.pem")
+	if err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMailerDKIM(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := writeKey(t, rsaKey)
+	for _, c := range []DKIMConfig{
+		{Domain: "example.com", PrivateKeyFile: keyFile},
+		{Domain: "example.com", Selector: "mail", PrivateKeyFile: filepath.Join(t.TempDir(), "missing.pem")},
+		{Domain: "example.com", Selector: "mail", PrivateKeyFile: writeKey(t, ecKey)},
+		{Domain: "example.com", Selector: "mail", PrivateKeyFile: keyFile, HeaderCanonicalization: "loose"},
+	} {
+		if _, err = NewMailer(Config{DKIM: &c}, nil); err == nil {
+			t.Errorf("%+v: want error", c)
+		}
+	}
+
+	server := newFakeServer(t)
+	addr := server.ln.Addr().(*net.TCPAddr)
+	m, err := NewMailer(Config{Host: "127.0.0.1", Port: addr.Port, Encryption: "none", FromAddress: "noreply@example.com",
+		DKIM: &DKIMConfig{Domain: "example.com", Selector: "mail", PrivateKeyFile: keyFile, Headers: []string{"Subject"}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if err = m.Send(Mail{To: []string{"user@example.com"}, Subject: "Hi", Body: "<p>Hi</p>"}); err != nil {
+		t.Fatal(err)
+	}
+	server.mu.Lock()
+	data := server.last
+	server.mu.Unlock()
+	header, _, _ := bytes.Cut(data, []byte("\n\n"))
+	unfolded := strings.Join(strings.Fields(string(header)), " ")
+	if !strings.HasPrefix(unfolded, "DKIM-Signature:") {
+		t.Fatalf("want DKIM-Signature first, have %q", header)
+	}
+	for _, tag := range []string{"d=example.com;", "s=mail;", "c=relaxed/relaxed;", "h=from:subject;", "b="} {
+		if !strings.Contains(unfolded, tag) {
+			t.Errorf("want %q in signature, have %q", tag, unfolded)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package smtp
+
+import (
+	"net/textproto"
+	"sync"
+	"time"
+
+	"github.com/oarkflow/errors"
+	sMail "github.com/xhit/go-simple-mail/v2"
+)
+
+// DefaultPoolSize is the number of connections kept alive by a Mailer
+// whose Config has no PoolSize.
+const DefaultPoolSize = 4
+
+var ErrPoolClosed = errors.New("Mail Error: connection pool closed")
+
+// Pool keeps up to size authenticated connections to a SMTP server
+// alive and sends messages over them, safe for concurrent use. The
+// client resets each connection with RSET after a message. A send
+// waits for a connection when all are busy, and is retried on another
+// connection when a reused one turns out to be closed.
+type Pool struct {
+	// IdleTimeout closes the connections idle for longer instead of
+	// reusing them, as servers drop idle clients. 30s by default.
+	IdleTimeout time.Duration
+	// MaxMessages, if positive, replaces the connections that sent as
+	// many messages, as servers may limit them per connection.
+	MaxMessages int
+	server      *sMail.SMTPServer
+	tokens      chan struct{} // Held by the connections in use.
+	mu          sync.Mutex
+	idle        []*conn
+	closed      bool
+}
+
+type conn struct {
+	client *sMail.SMTPClient
+	sent   int
+	used   time.Time
+}
+
+// NewPool returns a pool of size connections to server, dialed when
+// first needed. server is set to keep its connections alive.
+func NewPool(server *sMail.SMTPServer, size int) *Pool {
+	if size <= 0 {
+		size = DefaultPoolSize
+	}
+	server.KeepAlive = true
+	return &Pool{
+		IdleTimeout: 30 * time.Second,
+		server:      server,
+		tokens:      make(chan struct{}, size),
+	}
+}
+
+// ConnectError is returned by Send when connecting, starting TLS or
+// authenticating to the server fails.
+type ConnectError struct {
+	Err error
+}
+
+func (e *ConnectError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConnectError) Unwrap() error {
+	return e.Err
+}
+
+// Send sends email over a connection of the pool.
+func (p *Pool) Send(email *sMail.Email) error {
+	if err := email.GetError(); err != nil {
+		return err
+	}
+	if len(email.GetRecipients()) == 0 {
+		return errors.New("Mail Error: No recipient specified")
+	}
+	for {
+		c, reused, err := p.get()
+		if err != nil {
+			return err
+		}
+		err = email.Send(c.client)
+		p.put(c, err)
+		// Retried until a new connection is dialed.
+		if err == nil || !reused || !closedConn(err) {
+			return err
+		}
+	}
+}
+
+// get returns an idle connection, reused, or a new one.
+func (p *Pool) get() (*conn, bool, error) {
+	p.tokens <- struct{}{}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		<-p.tokens
+		return nil, false, ErrPoolClosed
+	}
+	for len(p.idle) > 0 {
+		c := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if p.IdleTimeout <= 0 || time.Since(c.used) < p.IdleTimeout {
+			p.mu.Unlock()
+			return c, true, nil
+		}
+		go c.client.Close()
+	}
+	p.mu.Unlock()
+	client, err := p.server.Connect()
+	if err != nil {
+		if client != nil {
+			client.Close()
+		}
+		<-p.tokens
+		return nil, false, &ConnectError{Err: err}
+	}
+	return &conn{client: client}, false, nil
+}
+
+// put returns c to the idle connections unless it failed or is spent.
+func (p *Pool) put(c *conn, err error) {
+	defer func() { <-p.tokens }()
+	c.sent++
+	c.used = time.Now()
+	var te *textproto.Error
+	// The server rejecting the message leaves the connection usable.
+	usable := err == nil || errors.As(err, &te)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !usable || p.closed || p.MaxMessages > 0 && c.sent >= p.MaxMessages {
+		// Closing waits for a send timed out to end.
+		go func() {
+			if usable {
+				c.client.Quit()
+			}
+			c.client.Close()
+		}()
+		return
+	}
+	p.idle = append(p.idle, c)
+}
+
+// Close closes the idle connections and those in use once done.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for _, c := range p.idle {
+		c.client.Quit()
+		c.client.Close()
+	}
+	p.idle = nil
+	return nil
+}
+
+// closedConn reports whether err is the failure of a connection rather
+// than a reply of the server or a timeout. A reused connection closed
+// by the server fails on its first command, before the message is
+// sent.
+func closedConn(err error) bool {
+	var te *textproto.Error
+	if errors.As(err, &te) {
+		return false
+	}
+	return err.Error() != "Mail Error: SMTP Send timed out"
+}
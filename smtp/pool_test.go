@@ -0,0 +1,140 @@
+package smtp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeServer accepts mail for any recipient but those containing
+// "reject".
+type fakeServer struct {
+	ln       net.Listener
+	dialed   atomic.Int32
+	messages atomic.Int32
+	mu       sync.Mutex
+	conns    []net.Conn
+	last     []byte // Data of the last message.
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.dialed.Add(1)
+			s.mu.Lock()
+			s.conns = append(s.conns, c)
+			s.mu.Unlock()
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+// drop closes the connections, as a server timing out idle clients.
+func (s *fakeServer) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.conns = nil
+}
+
+func (s *fakeServer) serve(c net.Conn) {
+	defer c.Close()
+	r := textproto.NewReader(bufio.NewReader(c))
+	reply := func(format string, args ...any) { fmt.Fprintf(c, format+"\r\n", args...) }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "RCPT":
+			if strings.Contains(arg, "reject") {
+				reply("550 5.1.1 No such user")
+			} else {
+				reply("250 OK")
+			}
+		case "DATA":
+			reply("354 Go ahead")
+			data, err := r.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.last = data
+			s.mu.Unlock()
+			s.messages.Add(1)
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestMailerPool(t *testing.T) {
+	server := newFakeServer(t)
+	addr := server.ln.Addr().(*net.TCPAddr)
+	m := New(Config{Host: "127.0.0.1", Port: addr.Port, Encryption: "none", FromAddress: "noreply@example.com", PoolSize: 2}, nil)
+	defer m.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := m.Send(Mail{To: []string{fmt.Sprintf("user%d@example.com", i)}, Subject: "Hi", Body: "<p>Hi</p>"}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if n := server.messages.Load(); n != 10 {
+		t.Fatalf("want 10 messages, have %d", n)
+	}
+	if n := server.dialed.Load(); n > 2 {
+		t.Fatalf("want at most 2 connections, have %d", n)
+	}
+
+	dialed := server.dialed.Load()
+	err := m.Send(Mail{To: []string{"reject@example.com"}, Subject: "Hi"})
+	var te *textproto.Error
+	if !errors.As(err, &te) || te.Code != 550 {
+		t.Fatalf("want 550 error, have %v", err)
+	}
+	if err = m.Send(Mail{To: []string{"user@example.com"}, Subject: "Hi"}); err != nil || server.dialed.Load() != dialed {
+		t.Fatalf("want connection reused after rejection, have %v", err)
+	}
+
+	server.drop()
+	if err = m.Send(Mail{To: []string{"user@example.com"}, Subject: "Hi"}); err != nil {
+		t.Fatalf("want reconnection, have %v", err)
+	}
+	if n := server.messages.Load(); n != 12 {
+		t.Fatalf("want 12 messages, have %d", n)
+	}
+}
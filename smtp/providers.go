@@ -0,0 +1,213 @@
+package smtp
+
+import (
+	"net/textproto"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oarkflow/errors"
+	"github.com/oarkflow/log"
+	"github.com/oarkflow/render"
+)
+
+// ErrNoProvider is returned by Providers.Send when every provider is
+// ejected.
+var ErrNoProvider = errors.New("Mail Error: no SMTP provider available")
+
+const (
+	// DefaultFailureThreshold is the number of consecutive connection
+	// failures ejecting a provider.
+	DefaultFailureThreshold = 3
+	// DefaultEjectDuration is how long a provider is ejected.
+	DefaultEjectDuration = time.Minute
+)
+
+// Provider is a mail server of Providers.
+type Provider struct {
+	Name   string `json:"name" yaml:"name"`                         // Host of the Config if empty.
+	Weight int    `json:"weight,omitempty" yaml:"weight,omitempty"` // Share of the mail sent, 1 if 0.
+	Config Config `json:"config" yaml:"config"`
+}
+
+// Providers sends mail over several providers, safe for concurrent
+// use. Messages are distributed by the weight of the providers, and
+// sent by the next providers when a provider cannot be connected to
+// or defers them with a 4xx reply. A provider failing to connect or
+// authenticate FailureThreshold times in a row is ejected for
+// EjectDuration, then tried again: it is ejected anew on the next
+// failure, until it sends a message.
+type Providers struct {
+	FailureThreshold int           // DefaultFailureThreshold if 0.
+	EjectDuration    time.Duration // DefaultEjectDuration if 0.
+	mu               sync.Mutex
+	providers        []*provider
+}
+
+type provider struct {
+	Provider
+	mailer   *Mailer
+	current  int // Of the smooth weighted round robin.
+	failures int
+	ejected  time.Time // Until when.
+}
+
+// NewProviders returns the providers of the given configs, rendering
+// views with engine.
+func NewProviders(providers []Provider, engine *render.HtmlEngine) (*Providers, error) {
+	if len(providers) == 0 {
+		return nil, errors.New("No SMTP provider configured")
+	}
+	p := &Providers{}
+	for _, config := range providers {
+		mailer, err := NewMailer(config.Config, engine)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		if config.Name == "" {
+			config.Name = config.Config.Host
+		}
+		if config.Weight <= 0 {
+			config.Weight = 1
+		}
+		p.providers = append(p.providers, &provider{Provider: config, mailer: mailer})
+	}
+	return p, nil
+}
+
+// Len returns the number of providers.
+func (p *Providers) Len() int {
+	return len(p.providers)
+}
+
+// Mailer returns the mailer of the first provider, e.g. to render
+// views.
+func (p *Providers) Mailer() *Mailer {
+	return p.providers[0].mailer
+}
+
+// Ejected returns the names of the providers ejected.
+func (p *Providers) Ejected() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var names []string
+	now := time.Now()
+	for _, pr := range p.providers {
+		if now.Before(pr.ejected) {
+			names = append(names, pr.Name)
+		}
+	}
+	return names
+}
+
+// Send sends msg and returns the name of the provider that accepted
+// it.
+func (p *Providers) Send(msg Mail) (string, error) {
+	candidates := p.order()
+	if len(candidates) == 0 {
+		return "", ErrNoProvider
+	}
+	var err error
+	for _, pr := range candidates {
+		err = pr.mailer.Send(msg)
+		p.report(pr, err)
+		if err == nil {
+			return pr.Name, nil
+		}
+		if !failover(err) {
+			return pr.Name, err
+		}
+		log.Error().Err(err).Str("provider", pr.Name).Msg("Unable to send email, failing over")
+	}
+	return "", err
+}
+
+// order returns the providers not ejected, the first picked by weight
+// and the others by decreasing weight.
+func (p *Providers) order() []*provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var available []*provider
+	total := 0
+	for _, pr := range p.providers {
+		if now.Before(pr.ejected) {
+			continue
+		}
+		available = append(available, pr)
+		total += pr.Weight
+	}
+	if len(available) == 0 {
+		return nil
+	}
+	// Smooth weighted round robin: the provider with the largest
+	// current weight is picked, spreading picks evenly.
+	var picked *provider
+	for _, pr := range available {
+		pr.current += pr.Weight
+		if picked == nil || pr.current > picked.current {
+			picked = pr
+		}
+	}
+	picked.current -= total
+	ordered := []*provider{picked}
+	for _, pr := range available {
+		if pr != picked {
+			ordered = append(ordered, pr)
+		}
+	}
+	sort.SliceStable(ordered[1:], func(i, j int) bool {
+		return ordered[1+i].Weight > ordered[1+j].Weight
+	})
+	return ordered
+}
+
+// report counts the connection failures of pr, ejecting it after too
+// many.
+func (p *Providers) report(pr *provider, err error) {
+	var ce *ConnectError
+	var te *textproto.Error
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case err == nil || errors.As(err, &te) && !errors.As(err, &ce):
+		// The server replied, the connection works.
+		pr.failures = 0
+		return
+	case !errors.As(err, &ce):
+		return
+	}
+	pr.failures++
+	threshold, duration := p.FailureThreshold, p.EjectDuration
+	if threshold <= 0 {
+		threshold = DefaultFailureThreshold
+	}
+	if duration <= 0 {
+		duration = DefaultEjectDuration
+	}
+	if pr.failures >= threshold {
+		pr.ejected = time.Now().Add(duration)
+		log.Error().Err(err).Str("provider", pr.Name).Int("failures", pr.failures).Msg("SMTP provider ejected")
+	}
+}
+
+// failover reports whether a message failing with err may be sent by
+// another provider: it could not be sent or was deferred. Messages
+// timing out may have been received, and are not sent again.
+func failover(err error) bool {
+	var ce *ConnectError
+	if errors.As(err, &ce) {
+		return true
+	}
+	var te *textproto.Error
+	return errors.As(err, &te) && te.Code >= 400 && te.Code < 500
+}
+
+// Close closes the connections of the providers.
+func (p *Providers) Close() error {
+	for _, pr := range p.providers {
+		pr.mailer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package smtp
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/oarkflow/protocol/smtp/smtptest"
+)
+
+func TestProviders(t *testing.T) {
+	primary, secondary := smtptest.NewServer(), smtptest.NewServer()
+	defer primary.Close()
+	defer secondary.Close()
+	// A port nothing listens on.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	downPort := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	config := func(srv *smtptest.Server) Config {
+		return Config{Host: srv.Host, Port: srv.Port, Encryption: "none", FromAddress: "noreply@example.com"}
+	}
+	p, err := NewProviders([]Provider{
+		{Name: "primary", Weight: 2, Config: config(primary)},
+		{Name: "secondary", Config: config(secondary)},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	sent := map[string]int{}
+	for i := 0; i < 6; i++ {
+		name, err := p.Send(Mail{To: []string{"jane@example.com"}, Subject: "Hi"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sent[name]++
+	}
+	if sent["primary"] != 4 || sent["secondary"] != 2 {
+		t.Fatalf("want 4 messages by primary and 2 by secondary, have %v", sent)
+	}
+
+	// Deferred messages fail over, rejected ones do not.
+	primary.Recipient = func(addr string) *smtptest.Reply {
+		switch addr {
+		case "later@example.com":
+			return &smtptest.Reply{Code: 451, Message: "4.7.1 Greylisted"}
+		case "gone@example.com":
+			return &smtptest.Reply{Code: 550, Message: "5.1.1 No such user"}
+		}
+		return nil
+	}
+	secondary.Recipient = primary.Recipient
+	if name, err := p.Send(Mail{To: []string{"later@example.com"}}); err == nil || name != "" {
+		t.Fatalf("want deferral by both providers, have %q %v", name, err)
+	}
+	if name, err := p.Send(Mail{To: []string{"gone@example.com"}}); err == nil || name == "" {
+		t.Fatalf("want rejection by one provider, have %q %v", name, err)
+	}
+	if n := len(primary.Messages()) + len(secondary.Messages()); n != 6 {
+		t.Fatalf("want 6 messages, have %d", n)
+	}
+
+	down, err := NewProviders([]Provider{
+		{Name: "down", Weight: 5, Config: Config{Host: "127.0.0.1", Port: downPort, Encryption: "none"}},
+		{Name: "up", Config: config(secondary)},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer down.Close()
+	down.FailureThreshold, down.EjectDuration = 2, 50*time.Millisecond
+	for i := 0; i < 3; i++ {
+		if name, err := down.Send(Mail{To: []string{"jane@example.com"}, From: "a@example.com"}); err != nil || name != "up" {
+			t.Fatalf("want failover to up, have %q %v", name, err)
+		}
+	}
+	if ejected := down.Ejected(); len(ejected) != 1 || ejected[0] != "down" {
+		t.Fatalf("want down ejected, have %v", ejected)
+	}
+	secondary.Close()
+	for i := 0; i < 2; i++ {
+		if _, err = down.Send(Mail{To: []string{"jane@example.com"}, From: "a@example.com"}); err == nil {
+			t.Fatal("want error with the only provider left down")
+		}
+	}
+	if _, err = down.Send(Mail{To: []string{"jane@example.com"}, From: "a@example.com"}); !errors.Is(err, ErrNoProvider) {
+		t.Fatalf("want ErrNoProvider, have %v", err)
+	}
+	// Back after EjectDuration, and ejected anew on the next failure.
+	time.Sleep(60 * time.Millisecond)
+	if ejected := down.Ejected(); len(ejected) != 0 {
+		t.Fatalf("want no provider ejected, have %v", ejected)
+	}
+	var ce *ConnectError
+	if _, err = down.Send(Mail{To: []string{"jane@example.com"}, From: "a@example.com"}); !errors.As(err, &ce) {
+		t.Fatalf("want connection error, have %v", err)
+	}
+	if ejected := down.Ejected(); len(ejected) != 2 {
+		t.Fatalf("want both providers ejected, have %v", ejected)
+	}
+}
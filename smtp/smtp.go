@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"net/mail"
 	"os"
 	"strings"
 	"time"
@@ -17,6 +18,7 @@ import (
 	sMail "github.com/xhit/go-simple-mail/v2"
 
 	"github.com/oarkflow/protocol/bytebufferpool"
+	"github.com/oarkflow/protocol/http"
 )
 
 var maxBigInt = big.NewInt(math.MaxInt64)
@@ -30,30 +32,43 @@ type Config struct {
 	FromName    string `json:"from_name" yaml:"from_name" env:"MAIL_FROM_NAME"`
 	EmailLayout string `json:"layout" yaml:"layout" env:"MAIL_LAYOUT"`
 	Port        int    `json:"port" yaml:"port" env:"MAIL_PORT"`
+	// PoolSize is the number of connections kept alive to send
+	// concurrently, DefaultPoolSize if 0.
+	PoolSize int `json:"pool_size,omitempty" yaml:"pool_size,omitempty" env:"MAIL_POOL_SIZE"`
+	// MaxAttachmentSize and MaxMessageSize limit the size in bytes of
+	// each attachment and of the bodies and attachments of a message,
+	// before encoding. There is no limit if 0.
+	MaxAttachmentSize int64 `json:"max_attachment_size,omitempty" yaml:"max_attachment_size,omitempty" env:"MAIL_MAX_ATTACHMENT_SIZE"`
+	MaxMessageSize    int64 `json:"max_message_size,omitempty" yaml:"max_message_size,omitempty" env:"MAIL_MAX_MESSAGE_SIZE"`
+	// DKIM signs the mail sent when set.
+	DKIM *DKIMConfig `json:"dkim,omitempty" yaml:"dkim,omitempty"`
 }
 
 type Mailer struct {
 	*sMail.SMTPServer
+	// Deprecated: Send uses the connections of its Pool and no longer
+	// sets SMTPClient.
 	*sMail.SMTPClient
 	*render.HtmlEngine
 	Config Config
-}
-
-type Attachment struct {
-	Data     []byte
-	File     string
-	FileName string
-	MimeType string
+	// HTTPClient fetches the attachments given by URL.
+	HTTPClient *http.Client
+	pool       *Pool
+	dkim       *dkimSigner
 }
 
 type Mail struct {
 	To          []string     `json:"to,omitempty"`
 	From        string       `json:"from,omitempty"`
 	Subject     string       `json:"subject,omitempty"`
-	Body        string       `json:"body,omitempty"`
+	Body        string       `json:"body,omitempty"` // HTML body, none for a text only email.
+	Text        string       `json:"text,omitempty"` // Plain text alternative of Body, generated with HTMLToText if empty.
 	Bcc         []string     `json:"bcc,omitempty"`
 	Cc          []string     `json:"cc,omitempty"`
+	ReplyTo     string       `json:"reply_to,omitempty"`
+	MessageID   string       `json:"message_id,omitempty"` // Generated with GenerateMessageID if empty.
 	Attachments []Attachment `json:"attachments,omitempty"`
+	// Deprecated: use Attachments with Path set.
 	AttachFiles []Attachment `json:"attach_files"`
 	engine      *render.HtmlEngine
 }
@@ -64,40 +79,71 @@ func Default(cfg Config, templateEngine *render.HtmlEngine) {
 	DefaultMailer = New(cfg, templateEngine)
 }
 
+// New returns a mailer like NewMailer, panicking if the DKIM settings
+// of cfg are invalid.
 func New(cfg Config, templateEngine *render.HtmlEngine) *Mailer {
+	m, err := NewMailer(cfg, templateEngine)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// NewMailer returns a mailer sending with cfg, loading its DKIM key,
+// if any.
+func NewMailer(cfg Config, templateEngine *render.HtmlEngine) (*Mailer, error) {
 	m := &Mailer{Config: cfg}
+	if cfg.DKIM != nil {
+		var err error
+		if m.dkim, err = newDKIMSigner(*cfg.DKIM); err != nil {
+			return nil, err
+		}
+	}
 	m.HtmlEngine = templateEngine
 	m.SMTPServer = sMail.NewSMTPClient()
 	m.SMTPServer.Host = cfg.Host
 	m.SMTPServer.Port = cfg.Port
 	m.SMTPServer.Username = cfg.Username
 	m.SMTPServer.Password = cfg.Password
-	if cfg.Encryption == "tls" {
+	switch cfg.Encryption {
+	case "tls":
 		m.SMTPServer.Encryption = sMail.EncryptionSTARTTLS
-	} else {
+	case "none":
+		m.SMTPServer.Encryption = sMail.EncryptionNone
+	default:
 		m.SMTPServer.Encryption = sMail.EncryptionSSL
 	}
-	// Variable to keep alive connection
-	m.SMTPServer.KeepAlive = false
 	// Timeout for connect to SMTP Server
 	m.SMTPServer.ConnectTimeout = 10 * time.Second
 	// Timeout for send the data and wait respond
 	m.SMTPServer.SendTimeout = 10 * time.Second
-	return m
+	// Connections are kept alive by the pool.
+	m.pool = NewPool(m.SMTPServer, cfg.PoolSize)
+	m.HTTPClient = http.NewClient(&http.Options{
+		RetryWaitMin: 500 * time.Millisecond,
+		RetryWaitMax: 5 * time.Second,
+		Timeout:      30 * time.Second,
+		RetryMax:     2,
+	})
+	return m, nil
+}
+
+// Pool returns the connections used by Send, e.g. to tune them.
+func (m *Mailer) Pool() *Pool {
+	return m.pool
+}
+
+// Close closes the connections of the mailer.
+func (m *Mailer) Close() error {
+	return m.pool.Close()
 }
 
+// Send sends msg over a pooled connection, safe for concurrent use.
 func (m *Mailer) Send(msg Mail) error {
-	var err error
-	m.SMTPClient, err = m.SMTPServer.Connect()
-	if err != nil {
-		fmt.Println("Error on connection: " + err.Error())
-		return err
-	}
-	defer m.SMTPClient.Close()
 	// New email simple html with inline and CC
 	email := sMail.NewMSG()
 	if msg.From == "" {
-		msg.From = fmt.Sprintf("%s<%s>", m.Config.FromName, m.Config.FromAddress)
+		msg.From = (&mail.Address{Name: m.Config.FromName, Address: m.Config.FromAddress}).String()
 	}
 	email.SetFrom(msg.From).AddTo(msg.To...).SetSubject(msg.Subject)
 	if len(msg.Cc) > 0 { //nolint:wsl
@@ -106,23 +152,38 @@ func (m *Mailer) Send(msg Mail) error {
 	if len(msg.Bcc) > 0 { //nolint:wsl
 		email.AddBcc(msg.Bcc...)
 	}
-	// txt, _ := html2text.FromString(body, html2text.Options{PrettyTables: false})
-	// email.AddAlternative(sMail.TextPlain, txt)
-	email.SetBody(sMail.TextHTML, msg.Body) //nolint:wsl
-	for _, attachment := range msg.Attachments {
-		email.AddAttachmentData(attachment.Data, attachment.File, attachment.MimeType)
+	if msg.ReplyTo != "" {
+		email.SetReplyTo(msg.ReplyTo)
 	}
-	for _, attachment := range msg.AttachFiles {
-		email.AddAttachment(attachment.File, attachment.FileName)
+	// Delivery reports refer to the message by its ID.
+	if msg.MessageID == "" {
+		var err error
+		if msg.MessageID, err = GenerateMessageID(); err != nil {
+			return err
+		}
+	}
+	email.AddHeader("Message-ID", "<"+strings.Trim(msg.MessageID, "<>")+">")
+	// Clients show the last alternative they support, HTML first.
+	text := msg.Text
+	if text == "" {
+		text = HTMLToText(msg.Body)
+	}
+	email.SetBody(sMail.TextPlain, text)
+	if msg.Body != "" {
+		email.AddAlternative(sMail.TextHTML, msg.Body)
+	}
+	if err := m.attach(email, msg, int64(len(msg.Body)+len(text))); err != nil {
+		return err
+	}
+	// The signature covers the whole message, so it is set last.
+	if m.dkim != nil {
+		email.SetDkim(m.dkim.sigOptions())
 	}
 
-	// Call Send and pass the client
-	err = email.Send(m.SMTPClient)
-	if err != nil {
+	if err := m.pool.Send(email); err != nil {
 		return err
-	} else {
-		log.Info().Msg("Email Sent to " + strings.Join(msg.To, ", "))
 	}
+	log.Info().Msg("Email Sent to " + strings.Join(msg.To, ", "))
 	return nil
 }
 
@@ -136,6 +197,26 @@ func (m *Mailer) View(view string, body map[string]any) *Body {
 	return bodyContent
 }
 
+// Localized renders view like View with body and its "locale" entry
+// set to locale, for the functions formatting in a locale, e.g. those
+// of i18n.Funcs. It returns the rendering error instead of panicking.
+func (m *Mailer) Localized(view, locale string, body map[string]any) (*Body, error) {
+	if m.HtmlEngine == nil {
+		return nil, errors.New("No template engine configured")
+	}
+	data := make(map[string]any, len(body)+1)
+	for k, v := range body {
+		data[k] = v
+	}
+	data["locale"] = locale
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	if err := m.Render(buf, view, data, m.Config.EmailLayout); err != nil {
+		return nil, err
+	}
+	return &Body{Content: buf.String(), mailer: m}, nil
+}
+
 func (m *Mailer) Html(view string, body map[string]any) string {
 	buf := bytebufferpool.Get()
 	defer bytebufferpool.Put(buf)
@@ -169,7 +250,11 @@ type Body struct {
 }
 
 func SendMail(config Config, msg Mail) error {
-	mailer := New(config, nil)
+	mailer, err := NewMailer(config, nil)
+	if err != nil {
+		return err
+	}
+	defer mailer.Close()
 	return mailer.Send(msg)
 }
 
@@ -184,7 +269,8 @@ func (t *Body) Send(msg Mail) error {
 	return DefaultMailer.Send(msg)
 }
 
-func generateMessageID() (string, error) {
+// GenerateMessageID returns a unique message ID, in angle brackets.
+func GenerateMessageID() (string, error) {
 	t := time.Now().UnixNano()
 	pid := os.Getpid()
 	rint, err := rand.Int(rand.Reader, maxBigInt)
@@ -0,0 +1,133 @@
+package smtptest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// Message is a message received by a Server.
+type Message struct {
+	From     string   // Envelope sender.
+	To       []string // Envelope recipients, including Bcc.
+	Data     []byte   // Raw message, with LF line endings.
+	TLS      bool     // Received after STARTTLS.
+	Username string   // Authenticated user, if any.
+
+	Header mail.Header
+	// Parts are the leaf parts of the body, a single part if it is
+	// not multipart.
+	Parts []Part
+	// Err is the error parsing Data, if any.
+	Err error
+}
+
+// Part is a leaf MIME part of a message.
+type Part struct {
+	Header      textproto.MIMEHeader
+	ContentType string // Media type, e.g. "text/plain".
+	Disposition string // "attachment", "inline" or empty.
+	FileName    string
+	ContentID   string // Without angle brackets, of inline images.
+	Body        []byte // Decoded from its transfer encoding.
+}
+
+// Subject returns the decoded subject of m.
+func (m *Message) Subject() string {
+	subject := m.Header.Get("Subject")
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(subject); err == nil {
+		return decoded
+	}
+	return subject
+}
+
+// Text returns the body of the first text/plain part, not attached.
+func (m *Message) Text() string {
+	return m.body("text/plain")
+}
+
+// HTML returns the body of the first text/html part, not attached.
+func (m *Message) HTML() string {
+	return m.body("text/html")
+}
+
+func (m *Message) body(mediaType string) string {
+	for _, p := range m.Parts {
+		if p.ContentType == mediaType && p.Disposition != "attachment" && p.FileName == "" {
+			return string(p.Body)
+		}
+	}
+	return ""
+}
+
+// Attachments returns the parts having a file name, attached or
+// inline.
+func (m *Message) Attachments() []Part {
+	var attachments []Part
+	for _, p := range m.Parts {
+		if p.FileName != "" {
+			attachments = append(attachments, p)
+		}
+	}
+	return attachments
+}
+
+func newMessage(from string, to []string, data []byte) *Message {
+	m := &Message{From: from, To: to, Data: data}
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		m.Err = err
+		return m
+	}
+	m.Header = msg.Header
+	m.Err = m.parse(textproto.MIMEHeader(msg.Header), msg.Body)
+	return m
+}
+
+// parse adds the leaf parts of the entity of header and body.
+func (m *Message) parse(header textproto.MIMEHeader, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", nil
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err = m.parse(part.Header, part); err != nil {
+				return err
+			}
+		}
+	}
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	p := Part{Header: header, ContentType: mediaType, Body: b, ContentID: strings.Trim(header.Get("Content-Id"), "<>")}
+	if disposition, dparams, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		p.Disposition, p.FileName = disposition, dparams["filename"]
+	}
+	if p.FileName == "" {
+		p.FileName = params["name"]
+	}
+	m.Parts = append(m.Parts, p)
+	return nil
+}
@@ -0,0 +1,407 @@
+// Package smtptest provides an in-process SMTP server capturing the
+// messages it receives, to test mail sending without a mail server.
+//
+//	srv := smtptest.NewServer()
+//	defer srv.Close()
+//	srv.Recipient = func(addr string) *smtptest.Reply {
+//		if addr == "gone@example.com" {
+//			return &smtptest.Reply{Code: 550, Message: "5.1.1 No such user"}
+//		}
+//		return nil
+//	}
+//	mailer := smtp.New(smtp.Config{Host: srv.Host, Port: srv.Port, Encryption: "none"}, nil)
+//	...
+//	msg := srv.Messages()[0]
+//
+// The server supports EHLO, AUTH PLAIN and LOGIN and STARTTLS with a
+// self-signed certificate trusted by ClientTLSConfig.
+package smtptest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reply is an SMTP reply, e.g. {Code: 451, Message: "4.3.0 Try again
+// later"}.
+type Reply struct {
+	Code    int
+	Message string
+}
+
+func (r Reply) String() string {
+	return fmt.Sprintf("%d %s", r.Code, r.Message)
+}
+
+// Server is an SMTP server listening on a loopback address.
+type Server struct {
+	Addr string // host:port of the server.
+	Host string
+	Port int
+
+	// Username and Password are the credentials required to send
+	// mail. Any credentials are accepted if Username is empty, and
+	// AUTH is optional.
+	Username string
+	Password string
+	// RequireTLS rejects mail sent before STARTTLS.
+	RequireTLS bool
+	// Recipient returns the reply to RCPT TO of addr, nil to accept
+	// it, e.g. to reject it with a 5xx or defer it with a 4xx code.
+	Recipient func(addr string) *Reply
+	// Data returns the reply to a received message, nil to accept and
+	// capture it.
+	Data func(msg *Message) *Reply
+
+	listener net.Listener
+	tls      *tls.Config
+	cert     *x509.Certificate
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	conns    map[net.Conn]bool
+	messages []*Message
+	closed   bool
+}
+
+// NewServer starts and returns a new Server. The caller should call
+// Close when finished, to shut it down.
+func NewServer() *Server {
+	s := NewUnstartedServer()
+	s.Start()
+	return s
+}
+
+// NewUnstartedServer returns a new Server listening but not serving,
+// to be configured before calling Start.
+func NewUnstartedServer() *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("smtptest: failed to listen on a port: %v", err))
+	}
+	cert, err := generateCertificate()
+	if err != nil {
+		panic(fmt.Sprintf("smtptest: failed to generate certificate: %v", err))
+	}
+	s := &Server{
+		listener: ln,
+		Addr:     ln.Addr().String(),
+		Host:     "127.0.0.1",
+		Port:     ln.Addr().(*net.TCPAddr).Port,
+		tls:      &tls.Config{Certificates: []tls.Certificate{cert}},
+		cert:     cert.Leaf,
+		conns:    make(map[net.Conn]bool),
+	}
+	return s
+}
+
+// Start starts serving connections.
+func (s *Server) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			c, err := s.listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			if s.closed {
+				s.mu.Unlock()
+				c.Close()
+				return
+			}
+			s.conns[c] = true
+			s.wg.Add(1)
+			s.mu.Unlock()
+			go func() {
+				defer s.wg.Done()
+				s.serve(c)
+				s.mu.Lock()
+				delete(s.conns, c)
+				s.mu.Unlock()
+			}()
+		}
+	}()
+}
+
+// Close shuts down the server, closing its connections.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.listener.Close()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// CloseConnections closes the open connections, as a server timing
+// out idle clients.
+func (s *Server) CloseConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.Close()
+	}
+}
+
+// Certificate returns the self-signed certificate of STARTTLS.
+func (s *Server) Certificate() *x509.Certificate {
+	return s.cert
+}
+
+// ClientTLSConfig returns a TLS configuration trusting the server
+// certificate, e.g. for the TLSConfig of a smtp.Mailer.
+func (s *Server) ClientTLSConfig() *tls.Config {
+	roots := x509.NewCertPool()
+	roots.AddCert(s.cert)
+	return &tls.Config{RootCAs: roots, ServerName: s.Host}
+}
+
+// Messages returns the messages received, in order.
+func (s *Server) Messages() []*Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Message(nil), s.messages...)
+}
+
+// Reset forgets the messages received.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+}
+
+// session is the state of a connection.
+type session struct {
+	text     *textproto.Conn
+	tls      bool
+	username string
+	mail     bool // MAIL FROM received, the sender may be null.
+	from     string
+	to       []string
+}
+
+func (ss *session) reply(code int, format string, args ...any) error {
+	return ss.text.PrintfLine("%d %s", code, fmt.Sprintf(format, args...))
+}
+
+func (s *Server) serve(c net.Conn) {
+	defer c.Close()
+	ss := &session{text: textproto.NewConn(c)}
+	ss.reply(220, "%s ESMTP smtptest", s.Host)
+	for {
+		line, err := ss.text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			ss.reply(250, "%s", s.Host)
+		case "EHLO":
+			ext := []string{s.Host, "8BITMIME", "SIZE 0"}
+			if !ss.tls {
+				ext = append(ext, "STARTTLS")
+			}
+			ext = append(ext, "AUTH PLAIN LOGIN")
+			for i, e := range ext {
+				sep := "-"
+				if i == len(ext)-1 {
+					sep = " "
+				}
+				ss.text.PrintfLine("250%s%s", sep, e)
+			}
+		case "STARTTLS":
+			if ss.tls {
+				ss.reply(503, "5.5.1 TLS already active")
+				continue
+			}
+			ss.reply(220, "2.0.0 Ready to start TLS")
+			conn := tls.Server(c, s.tls)
+			if err = conn.Handshake(); err != nil {
+				return
+			}
+			// The client starts over after the handshake.
+			*ss = session{text: textproto.NewConn(conn), tls: true}
+		case "AUTH":
+			s.auth(ss, arg)
+		case "MAIL":
+			switch {
+			case s.RequireTLS && !ss.tls:
+				ss.reply(530, "5.7.0 Must issue a STARTTLS command first")
+			case s.Username != "" && ss.username == "":
+				ss.reply(530, "5.7.0 Authentication required")
+			default:
+				ss.mail, ss.from, ss.to = true, path(arg, "FROM:"), nil
+				ss.reply(250, "2.1.0 OK")
+			}
+		case "RCPT":
+			if !ss.mail {
+				ss.reply(503, "5.5.1 MAIL first")
+				continue
+			}
+			addr := path(arg, "TO:")
+			if s.Recipient != nil {
+				if r := s.Recipient(addr); r != nil {
+					ss.text.PrintfLine("%s", r)
+					continue
+				}
+			}
+			ss.to = append(ss.to, addr)
+			ss.reply(250, "2.1.5 OK")
+		case "DATA":
+			if len(ss.to) == 0 {
+				ss.reply(503, "5.5.1 RCPT first")
+				continue
+			}
+			ss.reply(354, "Start mail input; end with <CRLF>.<CRLF>")
+			data, err := ss.text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg := newMessage(ss.from, ss.to, data)
+			msg.TLS, msg.Username = ss.tls, ss.username
+			ss.mail, ss.from, ss.to = false, "", nil
+			if s.Data != nil {
+				if r := s.Data(msg); r != nil {
+					ss.text.PrintfLine("%s", r)
+					continue
+				}
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			ss.reply(250, "2.0.0 OK queued")
+		case "RSET":
+			ss.mail, ss.from, ss.to = false, "", nil
+			ss.reply(250, "2.0.0 OK")
+		case "NOOP":
+			ss.reply(250, "2.0.0 OK")
+		case "VRFY":
+			ss.reply(252, "2.5.0 Cannot VRFY user")
+		case "QUIT":
+			ss.reply(221, "2.0.0 Bye")
+			return
+		default:
+			ss.reply(502, "5.5.2 Command not recognized")
+		}
+	}
+}
+
+// auth authenticates the session with AUTH PLAIN or LOGIN, the
+// initial response being given in arg or after a 334 challenge.
+func (s *Server) auth(ss *session, arg string) {
+	if ss.username != "" {
+		ss.reply(503, "5.5.1 Already authenticated")
+		return
+	}
+	mechanism, initial, _ := strings.Cut(arg, " ")
+	challenge := func(prompt string) (string, bool) {
+		ss.reply(334, "%s", base64.StdEncoding.EncodeToString([]byte(prompt)))
+		line, err := ss.text.ReadLine()
+		if err != nil || line == "*" {
+			return "", false
+		}
+		b, err := base64.StdEncoding.DecodeString(line)
+		return string(b), err == nil
+	}
+	decode := func(s string) (string, bool) {
+		b, err := base64.StdEncoding.DecodeString(s)
+		return string(b), err == nil
+	}
+	var username, password string
+	ok := false
+	switch strings.ToUpper(mechanism) {
+	case "PLAIN":
+		var resp string
+		if initial != "" {
+			resp, ok = decode(initial)
+		} else {
+			resp, ok = challenge("")
+		}
+		// authzid NUL authcid NUL passwd
+		fields := strings.Split(resp, "\x00")
+		if ok = ok && len(fields) == 3; ok {
+			username, password = fields[1], fields[2]
+		}
+	case "LOGIN":
+		if initial != "" {
+			username, ok = decode(initial)
+		} else {
+			username, ok = challenge("Username:")
+		}
+		if ok {
+			password, ok = challenge("Password:")
+		}
+	default:
+		ss.reply(504, "5.5.4 Unrecognized authentication type")
+		return
+	}
+	if !ok {
+		ss.reply(501, "5.5.2 Cannot decode response")
+		return
+	}
+	if s.Username != "" && (username != s.Username || password != s.Password) {
+		ss.reply(535, "5.7.8 Authentication credentials invalid")
+		return
+	}
+	ss.username = username
+	ss.reply(235, "2.7.0 Authentication successful")
+}
+
+// path returns the address of a MAIL FROM or RCPT TO argument,
+// dropping its parameters.
+func path(arg, prefix string) string {
+	arg = strings.TrimSpace(arg)
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = strings.TrimSpace(arg[len(prefix):])
+	}
+	if i := strings.IndexByte(arg, '>'); strings.HasPrefix(arg, "<") && i > 0 {
+		return arg[1:i]
+	}
+	addr, _, _ := strings.Cut(arg, " ")
+	return addr
+}
+
+func generateCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "smtptest"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
@@ -0,0 +1,125 @@
+package smtptest_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/textproto"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/oarkflow/protocol/smtp"
+	"github.com/oarkflow/protocol/smtp/smtptest"
+)
+
+func TestServer(t *testing.T) {
+	srv := smtptest.NewUnstartedServer()
+	srv.Username, srv.Password, srv.RequireTLS = "user", "secret", true
+	srv.Recipient = func(addr string) *smtptest.Reply {
+		if strings.HasPrefix(addr, "gone@") {
+			return &smtptest.Reply{Code: 550, Message: "5.1.1 No such user"}
+		}
+		return nil
+	}
+	var deferred atomic.Int32
+	srv.Data = func(msg *smtptest.Message) *smtptest.Reply {
+		if msg.Subject() == "Later" && deferred.Add(1) == 1 {
+			return &smtptest.Reply{Code: 451, Message: "4.3.0 Try again later"}
+		}
+		return nil
+	}
+	srv.Start()
+	defer srv.Close()
+
+	m := smtp.New(smtp.Config{Host: srv.Host, Port: srv.Port, Encryption: "tls", Username: "user", Password: "secret", FromAddress: "noreply@example.com"}, nil)
+	m.SMTPServer.TLSConfig = srv.ClientTLSConfig()
+	defer m.Close()
+	err := m.Send(smtp.Mail{
+		To:      []string{"jane@example.com"},
+		Bcc:     []string{"audit@example.com"},
+		Subject: "Héllo",
+		Body:    `<p>Hi <img src="cid:logo.png"></p>`,
+		Attachments: []smtp.Attachment{
+			{Data: []byte("PNG"), File: "logo.png", MimeType: "image/png", Inline: true},
+			{Data: []byte("a,b\n"), File: "report.csv", MimeType: "text/csv"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := srv.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("want 1 message, have %d", len(msgs))
+	}
+	msg := msgs[0]
+	if msg.Err != nil || !msg.TLS || msg.Username != "user" || msg.From != "noreply@example.com" || len(msg.To) != 2 {
+		t.Fatalf("want message over TLS to 2 recipients, have %+v", msg)
+	}
+	if msg.Subject() != "Héllo" || msg.Text() != "Hi" || !strings.Contains(msg.HTML(), "<p>Hi") {
+		t.Fatalf("want subject, text and html, have %q %q %q", msg.Subject(), msg.Text(), msg.HTML())
+	}
+	attachments := msg.Attachments()
+	if len(attachments) != 2 || attachments[0].ContentID == "" || string(attachments[1].Body) != "a,b\n" {
+		t.Fatalf("want inline image and attachment, have %+v", attachments)
+	}
+
+	var te *textproto.Error
+	if err = m.Send(smtp.Mail{To: []string{"gone@example.com"}, Subject: "Hi"}); !errors.As(err, &te) || te.Code != 550 {
+		t.Fatalf("want 550 error, have %v", err)
+	}
+	if err = m.Send(smtp.Mail{To: []string{"jane@example.com"}, Subject: "Later"}); !errors.As(err, &te) || te.Code != 451 {
+		t.Fatalf("want 451 error, have %v", err)
+	}
+	if err = m.Send(smtp.Mail{To: []string{"jane@example.com"}, Subject: "Later"}); err != nil {
+		t.Fatalf("want retry accepted, have %v", err)
+	}
+	if n := len(srv.Messages()); n != 2 {
+		t.Fatalf("want 2 messages, have %d", n)
+	}
+
+	bad := smtp.New(smtp.Config{Host: srv.Host, Port: srv.Port, Encryption: "tls", Username: "user", Password: "wrong"}, nil)
+	bad.SMTPServer.TLSConfig = srv.ClientTLSConfig()
+	defer bad.Close()
+	if err = bad.Send(smtp.Mail{To: []string{"jane@example.com"}, From: "a@example.com"}); err == nil {
+		t.Fatal("want authentication error")
+	}
+}
+
+func TestServerAuthLogin(t *testing.T) {
+	srv := smtptest.NewServer()
+	srv.Username, srv.Password = "user", "secret"
+	defer srv.Close()
+
+	c, err := textproto.Dial("tcp", srv.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	b64 := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	for _, step := range []struct {
+		cmd  string
+		code int
+	}{
+		{"", 220},
+		{"EHLO client", 250},
+		{"MAIL FROM:<>", 530},
+		{"AUTH LOGIN", 334},
+		{b64("user"), 334},
+		{b64("secret"), 235},
+		{"MAIL FROM:<>", 250},
+		{"RCPT TO:<jane@example.com> NOTIFY=NEVER", 250},
+		{"DATA", 354},
+		{"Subject: Bounce\r\n\r\nGone\r\n.", 250},
+	} {
+		if step.cmd != "" {
+			c.PrintfLine("%s", step.cmd)
+		}
+		if _, _, err = c.ReadResponse(step.code); err != nil {
+			t.Fatalf("%s: %v", step.cmd, err)
+		}
+	}
+	msgs := srv.Messages()
+	if len(msgs) != 1 || msgs[0].From != "" || msgs[0].To[0] != "jane@example.com" || msgs[0].Text() != "Gone\n" {
+		t.Fatalf("want null sender message, have %+v", msgs)
+	}
+}
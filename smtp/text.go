@@ -0,0 +1,307 @@
+package smtp
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTMLToText returns the plain text of an HTML body, used as its
+// alternative for clients not displaying HTML. Paragraphs and headings
+// are separated by blank lines, links are followed by their URL, list
+// items are marked with "*" or their number, quotes with ">" and the
+// cells of data tables are separated with "|". Scripts and styles are
+// dropped.
+func HTMLToText(body string) string {
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return body
+	}
+	w := &textWriter{}
+	w.walk(doc)
+	lines := strings.Split(w.b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// textWriter writes the text of HTML nodes, breaking lines lazily so
+// that blocks are separated by at most one blank line.
+type textWriter struct {
+	b         strings.Builder
+	prefixes  []string // Of the lines, e.g. "> " in quotes.
+	lists     []int    // Number of the next item of each list, 0 if unordered.
+	cells     []int    // Cells written in each table row, -1 in layout tables.
+	newlines  int      // Line breaks due before the next text.
+	space     bool     // Space due before the next text.
+	lineStart bool
+	pre       int // Depth of pre elements, keeping whitespace.
+}
+
+// breakLines ends the current line, leaving n-1 blank lines before
+// the next text.
+func (w *textWriter) breakLines(n int) {
+	if w.b.Len() == 0 {
+		return
+	}
+	if n > w.newlines {
+		w.newlines = n
+	}
+	w.space = false
+}
+
+// write writes s as is after the due breaks and space.
+func (w *textWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	prefix := strings.Join(w.prefixes, "")
+	if w.newlines > 0 {
+		for i := 0; i < w.newlines; i++ {
+			if i > 0 {
+				w.b.WriteString(strings.TrimRight(prefix, " "))
+			}
+			w.b.WriteByte('\n')
+		}
+		w.newlines = 0
+		w.lineStart = true
+	}
+	if w.b.Len() == 0 {
+		w.lineStart = true
+	}
+	if w.lineStart {
+		w.b.WriteString(prefix)
+		w.lineStart = false
+	} else if w.space {
+		w.b.WriteByte(' ')
+	}
+	w.space = false
+	w.b.WriteString(s)
+}
+
+// text writes s with its whitespace collapsed, unless in pre.
+func (w *textWriter) text(s string) {
+	if w.pre > 0 {
+		for i, line := range strings.Split(s, "\n") {
+			if i > 0 {
+				w.b.WriteByte('\n')
+				w.lineStart = true
+			}
+			w.write(line)
+		}
+		return
+	}
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		if s != "" {
+			w.space = true
+		}
+		return
+	}
+	if first, _ := utf8.DecodeRuneInString(s); unicode.IsSpace(first) {
+		w.space = true
+	}
+	w.write(strings.Join(words, " "))
+	last, _ := utf8.DecodeLastRuneInString(s)
+	w.space = unicode.IsSpace(last)
+}
+
+func (w *textWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.walk(c)
+	}
+}
+
+// block writes the children of n between line breaks.
+func (w *textWriter) block(n *html.Node, lines int) {
+	w.breakLines(lines)
+	w.children(n)
+	w.breakLines(lines)
+}
+
+func (w *textWriter) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		w.children(n)
+		return
+	}
+	switch n.DataAtom {
+	case atom.Head, atom.Script, atom.Style, atom.Title, atom.Template, atom.Noscript:
+	case atom.Br:
+		if w.b.Len() > 0 && w.newlines < 2 {
+			w.newlines++
+		}
+	case atom.Hr:
+		w.breakLines(2)
+		w.write("----------")
+		w.breakLines(2)
+	case atom.Img:
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			w.text(alt)
+		}
+	case atom.A:
+		w.link(n)
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		w.breakLines(2)
+		title := strings.Join(strings.Fields(nodeText(n)), " ")
+		w.write(title)
+		switch n.DataAtom {
+		case atom.H1:
+			w.breakLines(1)
+			w.write(strings.Repeat("=", utf8.RuneCountInString(title)))
+		case atom.H2:
+			w.breakLines(1)
+			w.write(strings.Repeat("-", utf8.RuneCountInString(title)))
+		}
+		w.breakLines(2)
+	case atom.P:
+		w.block(n, 2)
+	case atom.Ul, atom.Ol:
+		next := 0
+		if n.DataAtom == atom.Ol {
+			next = 1
+		}
+		lines := 1
+		if len(w.lists) == 0 {
+			lines = 2
+		}
+		w.lists = append(w.lists, next)
+		w.block(n, lines)
+		w.lists = w.lists[:len(w.lists)-1]
+	case atom.Li:
+		marker := "* "
+		if len(w.lists) > 0 && w.lists[len(w.lists)-1] > 0 {
+			marker = fmt.Sprintf("%d. ", w.lists[len(w.lists)-1])
+			w.lists[len(w.lists)-1]++
+		}
+		w.breakLines(1)
+		w.write(marker)
+		w.prefixes = append(w.prefixes, strings.Repeat(" ", len(marker)))
+		w.children(n)
+		w.prefixes = w.prefixes[:len(w.prefixes)-1]
+		w.breakLines(1)
+	case atom.Blockquote:
+		w.breakLines(2)
+		w.prefixes = append(w.prefixes, "> ")
+		w.children(n)
+		w.prefixes = w.prefixes[:len(w.prefixes)-1]
+		w.breakLines(2)
+	case atom.Pre:
+		w.breakLines(2)
+		w.pre++
+		w.children(n)
+		w.pre--
+		w.breakLines(2)
+	case atom.Table:
+		w.block(n, 2)
+	case atom.Tr:
+		cells := 0
+		if layoutTable(n) {
+			cells = -1
+		}
+		w.cells = append(w.cells, cells)
+		w.block(n, 1)
+		w.cells = w.cells[:len(w.cells)-1]
+	case atom.Td, atom.Th:
+		if len(w.cells) == 0 || w.cells[len(w.cells)-1] < 0 {
+			w.block(n, 1)
+			break
+		}
+		if w.cells[len(w.cells)-1] > 0 {
+			w.space = true
+			w.write("|")
+			w.space = true
+		}
+		w.cells[len(w.cells)-1]++
+		w.children(n)
+	case atom.Dd:
+		w.breakLines(1)
+		w.prefixes = append(w.prefixes, "  ")
+		w.children(n)
+		w.prefixes = w.prefixes[:len(w.prefixes)-1]
+		w.breakLines(1)
+	case atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Main, atom.Nav,
+		atom.Aside, atom.Address, atom.Figure, atom.Figcaption, atom.Form, atom.Center, atom.Dl, atom.Dt,
+		atom.Caption, atom.Tbody, atom.Thead, atom.Tfoot:
+		w.block(n, 1)
+	default:
+		w.children(n)
+	}
+}
+
+// link writes the text of a link followed by its URL, unless they are
+// the same or the URL is internal to the message.
+func (w *textWriter) link(n *html.Node) {
+	w.children(n)
+	href := strings.TrimSpace(attr(n, "href"))
+	for _, internal := range []string{"#", "cid:", "javascript:"} {
+		if href == "" || strings.HasPrefix(href, internal) {
+			return
+		}
+	}
+	url := strings.TrimPrefix(href, "mailto:")
+	text := strings.Join(strings.Fields(nodeText(n)), " ")
+	switch text {
+	case url, href:
+		return
+	case "":
+		w.text(url)
+	default:
+		w.space = true
+		w.write("(" + url + ")")
+	}
+}
+
+// layoutTable reports whether the cells of the row tr hold blocks, as
+// in tables laying out an email rather than holding data.
+func layoutTable(tr *html.Node) bool {
+	var blocks func(n *html.Node) bool
+	blocks = func(n *html.Node) bool {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch c.DataAtom {
+			case atom.Table, atom.P, atom.Div, atom.Ul, atom.Ol, atom.Blockquote, atom.Pre,
+				atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+				return true
+			}
+			if blocks(c) {
+				return true
+			}
+		}
+		return false
+	}
+	return blocks(tr)
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// nodeText returns the text of n and its descendants.
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.DataAtom == atom.Img {
+			b.WriteString(attr(c, "alt"))
+			continue
+		}
+		b.WriteString(nodeText(c))
+	}
+	return b.String()
+}
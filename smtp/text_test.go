@@ -0,0 +1,55 @@
+package smtp
+
+import "testing"
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name, html, want string
+	}{
+		{
+			name: "paragraphs",
+			html: "<p>Hello  <b>world</b>,</p>\n<p>second<br>line</p>",
+			want: "Hello world,\n\nsecond\nline",
+		},
+		{
+			name: "heading",
+			html: "<h1>Welcome</h1><p>text</p>",
+			want: "Welcome\n=======\n\ntext",
+		},
+		{
+			name: "links",
+			html: `<p><a href="https://example.com/a">Read more</a> or <a href="https://example.com/b">https://example.com/b</a> <a href="#top">top</a></p>`,
+			want: "Read more (https://example.com/a) or https://example.com/b top",
+		},
+		{
+			name: "lists",
+			html: "<ul><li>one</li><li>two<ol><li>a</li><li>b</li></ol></li></ul>",
+			want: "* one\n* two\n  1. a\n  2. b",
+		},
+		{
+			name: "data table",
+			html: "<table><tr><th>Item</th><th>Qty</th></tr><tr><td>Pen</td><td>2</td></tr></table>",
+			want: "Item | Qty\nPen | 2",
+		},
+		{
+			name: "layout table",
+			html: "<table><tr><td><p>first</p></td><td><p>second</p></td></tr></table>",
+			want: "first\n\nsecond",
+		},
+		{
+			name: "quote",
+			html: "<blockquote><p>a</p><p>b</p></blockquote>",
+			want: "> a\n>\n> b",
+		},
+		{
+			name: "dropped",
+			html: "<html><head><title>T</title><style>p{}</style></head><body><script>x()</script><img src=\"cid:logo.png\" alt=\"Logo\"> hi</body></html>",
+			want: "Logo hi",
+		},
+	}
+	for _, test := range tests {
+		if got := HTMLToText(test.html); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
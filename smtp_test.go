@@ -0,0 +1,134 @@
+package protocol
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oarkflow/errors"
+
+	"github.com/oarkflow/protocol/address"
+	"github.com/oarkflow/protocol/smtp"
+	"github.com/oarkflow/protocol/smtp/smtptest"
+	"github.com/oarkflow/protocol/suppression"
+)
+
+const bounceReport = `From: MAILER-DAEMON@mx.example.net
+Content-Type: multipart/report; report-type=delivery-status; boundary="b"
+
+--b
+Content-Type: message/delivery-status
+
+Reporting-MTA: dns; mx.example.net
+
+Final-Recipient: rfc822; jane@example.net
+Action: failed
+Status: 5.1.1
+Diagnostic-Code: smtp; 550 5.1.1 User unknown
+
+--b
+Content-Type: text/rfc822-headers
+
+Message-ID: <1.2.3@example.com>
+
+--b--
+`
+
+func TestSMTPProcessReport(t *testing.T) {
+	received := make(chan Callback, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cb Callback
+		json.NewDecoder(r.Body).Decode(&cb)
+		received <- cb
+	}))
+	defer srv.Close()
+	callbacks := NewCallbackDispatcher(CallbackOptions{DeadLetterPath: t.TempDir() + "/dead"})
+	defer callbacks.Close()
+
+	s := &SMTP{Suppression: suppression.New(nil), Callbacks: callbacks}
+	s.track("<1.2.3@example.com>", Payload{ID: "p1", To: "jane@example.net", CallbackURL: srv.URL})
+	report, err := s.ProcessReport(strings.NewReader(bounceReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Recipients) != 1 {
+		t.Fatalf("want 1 recipient, have %+v", report)
+	}
+	if err = s.Suppression.Check(suppression.Email, "jane@example.net"); err == nil {
+		t.Fatal("want bounced recipient suppressed")
+	}
+	select {
+	case cb := <-received:
+		if cb.Event != EventFailed || cb.PayloadID != "p1" || !strings.Contains(cb.Error, "5.1.1") {
+			t.Fatalf("want failed callback, have %+v", cb)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no callback")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.sent) != 0 {
+		t.Fatal("want reported email untracked")
+	}
+}
+
+func TestSMTPHandle(t *testing.T) {
+	srv := smtptest.NewServer()
+	defer srv.Close()
+	srv.Recipient = func(addr string) *smtptest.Reply {
+		if addr == "gone@example.com" {
+			return &smtptest.Reply{Code: 550, Message: "5.1.1 No such user"}
+		}
+		return nil
+	}
+	s, err := NewSMTP(smtp.Config{Host: srv.Host, Port: srv.Port, Encryption: "none", FromAddress: "noreply@example.com"}, nil, "mail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Suppression = suppression.New(nil)
+	if _, err = s.Handle(Payload{To: "jane@example.com", Subject: "Hi", Message: "<p>Hello</p>", Text: "Hello!"}); err != nil {
+		t.Fatal(err)
+	}
+	msgs := srv.Messages()
+	if len(msgs) != 1 || msgs[0].Text() != "Hello!" || msgs[0].Header.Get("Message-Id") == "" {
+		t.Fatalf("want message with text and ID, have %+v", msgs)
+	}
+	if _, err = s.Handle(Payload{To: "gone@example.com", Subject: "Hi", Message: "Hello"}); err == nil {
+		t.Fatal("want rejection")
+	}
+	if err = s.Suppression.Check(suppression.Email, "gone@example.com"); err == nil {
+		t.Fatal("want rejected mailbox suppressed")
+	}
+}
+
+func TestSMTPAddresses(t *testing.T) {
+	srv := smtptest.NewServer()
+	defer srv.Close()
+	s, err := NewSMTP(smtp.Config{Host: srv.Host, Port: srv.Port, Encryption: "none"}, nil, "mail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Handle(Payload{From: "news@example.com", FromName: "Doe, Jane", To: "jane@bücher.example", Cc: " ", Subject: "Hi", Message: "Hello"}); err != nil {
+		t.Fatal(err)
+	}
+	msgs := srv.Messages()
+	if len(msgs) != 1 || msgs[0].Header.Get("From") != `"Doe, Jane" <news@example.com>` {
+		t.Fatalf("want sender with display name, have %+v", msgs)
+	}
+	if to := msgs[0].To; len(to) != 1 || to[0] != "jane@xn--bcher-kva.example" {
+		t.Fatalf("want punycode recipient only, have %q", to)
+	}
+
+	s.Addresses = &address.Validator{Disposable: address.DisposableDomains}
+	_, err = s.Handle(Payload{To: "jane@example.com", Bcc: "temp@mailinator.com", Subject: "Hi", Message: "Hello"})
+	var e *address.Error
+	if !errors.As(err, &e) || e.Code != address.Disposable || e.Field != "bcc" {
+		t.Fatalf("want disposable bcc error, have %v", err)
+	}
+	if len(srv.Messages()) != 1 {
+		t.Fatal("want no message sent to an invalid address")
+	}
+}
@@ -0,0 +1,85 @@
+package suppression
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var csvHeader = []string{"channel", "address", "reason", "source", "created_at"}
+
+// Export writes all entries to w as CSV with a header line.
+func (l *List) Export(w io.Writer) error {
+	entries, err := l.store.All()
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err = cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		err = cw.Write([]string{
+			string(e.Channel),
+			e.Address,
+			string(e.Reason),
+			e.Source,
+			e.CreatedAt.UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Import reads entries written by Export and returns the number of
+// entries added. The header line is optional, only the channel and
+// address columns are required: the reason defaults to Manual and
+// the creation time to now. Addresses are normalized.
+func (l *List) Import(r io.Reader) (int, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	n := 0
+	for i := 1; ; i++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if i == 1 && strings.EqualFold(rec[0], csvHeader[0]) {
+			continue
+		}
+		if len(rec) < 2 || rec[0] == "" || rec[1] == "" {
+			return n, fmt.Errorf("record %d: channel and address are required", i)
+		}
+		e := Entry{
+			Channel:   Channel(strings.ToLower(rec[0])),
+			Reason:    Manual,
+			CreatedAt: time.Now(),
+		}
+		e.Address = l.Normalize(e.Channel, rec[1])
+		if len(rec) > 2 && rec[2] != "" {
+			e.Reason = Reason(rec[2])
+		}
+		if len(rec) > 3 {
+			e.Source = rec[3]
+		}
+		if len(rec) > 4 && rec[4] != "" {
+			if e.CreatedAt, err = time.Parse(time.RFC3339, rec[4]); err != nil {
+				return n, fmt.Errorf("record %d: %w", i, err)
+			}
+		}
+		if err = l.store.Add(e); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
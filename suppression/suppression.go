@@ -0,0 +1,212 @@
+// Package suppression keeps the recipients that must not be messaged
+// anymore, keyed by channel and normalized address.
+//
+// Entries are added when a recipient opts out by replying with a stop
+// keyword, when an email hard bounces or is reported as spam, or
+// manually. Services consult the list before sending and fail with
+// ErrSuppressed for suppressed recipients.
+package suppression
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/protocol/smpp/number"
+	"github.com/oarkflow/protocol/utils/maps"
+)
+
+// Channel is the channel a recipient is suppressed for.
+type Channel string
+
+const (
+	SMS   Channel = "sms"
+	Email Channel = "email"
+)
+
+// Reason is why a recipient is suppressed.
+type Reason string
+
+const (
+	OptOut    Reason = "opt_out"   // Stop keyword received.
+	Bounce    Reason = "bounce"    // Permanent delivery failure.
+	Complaint Reason = "complaint" // Reported as spam.
+	Manual    Reason = "manual"
+)
+
+// Entry is a suppressed recipient.
+type Entry struct {
+	Channel   Channel   `json:"channel"`
+	Address   string    `json:"address"`
+	Reason    Reason    `json:"reason"`
+	Source    string    `json:"source,omitempty"` // Keyword, bounce diagnostic, etc.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ErrSuppressed is returned when sending to a suppressed recipient.
+type ErrSuppressed struct {
+	Entry Entry
+}
+
+func (e *ErrSuppressed) Error() string {
+	return fmt.Sprintf("%s recipient %s is suppressed: %s", e.Entry.Channel, e.Entry.Address, e.Entry.Reason)
+}
+
+// Store persists entries. Addresses given to a Store are normalized.
+type Store interface {
+	Get(channel Channel, address string) (Entry, bool, error)
+	Add(entry Entry) error
+	Remove(channel Channel, address string) error
+	All() ([]Entry, error)
+}
+
+// Memory is an in-memory Store.
+type Memory struct {
+	entries *maps.Map[string, Entry]
+}
+
+func NewMemory() *Memory {
+	return &Memory{entries: maps.New[string, Entry]()}
+}
+
+func key(channel Channel, address string) string {
+	return string(channel) + ":" + address
+}
+
+func (s *Memory) Get(channel Channel, address string) (Entry, bool, error) {
+	e, ok := s.entries.Get(key(channel, address))
+	return e, ok, nil
+}
+
+func (s *Memory) Add(entry Entry) error {
+	s.entries.Set(key(entry.Channel, entry.Address), entry)
+	return nil
+}
+
+func (s *Memory) Remove(channel Channel, address string) error {
+	s.entries.Del(key(channel, address))
+	return nil
+}
+
+func (s *Memory) All() ([]Entry, error) {
+	var entries []Entry
+	s.entries.ForEach(func(_ string, e Entry) bool {
+		entries = append(entries, e)
+		return true
+	})
+	return entries, nil
+}
+
+var (
+	// DefaultStopKeywords opt a recipient out when received as the
+	// whole text of a message.
+	DefaultStopKeywords = []string{"STOP", "STOPALL", "UNSUBSCRIBE", "CANCEL", "END", "QUIT", "OPTOUT"}
+	// DefaultStartKeywords opt a recipient back in.
+	DefaultStartKeywords = []string{"START", "UNSTOP", "SUBSCRIBE"}
+)
+
+// List is a suppression list backed by a Store.
+type List struct {
+	store Store
+	// Country is the ISO 3166-1 alpha-2 code used to normalize
+	// national SMS numbers, see number.Parse.
+	Country       string
+	StopKeywords  []string
+	StartKeywords []string
+}
+
+// New returns a List backed by store, in memory if nil.
+func New(store Store) *List {
+	if store == nil {
+		store = NewMemory()
+	}
+	return &List{
+		store:         store,
+		StopKeywords:  DefaultStopKeywords,
+		StartKeywords: DefaultStartKeywords,
+	}
+}
+
+// Normalize returns the key of address in channel: E.164 numbers for
+// SMS and lower cased addresses for email.
+func (l *List) Normalize(channel Channel, address string) string {
+	address = strings.TrimSpace(address)
+	switch channel {
+	case SMS:
+		if n, err := number.Parse(address, l.Country); err == nil {
+			return n.String()
+		}
+	case Email:
+		if a, err := mail.ParseAddress(address); err == nil {
+			address = a.Address
+		}
+		return strings.ToLower(address)
+	}
+	return address
+}
+
+// Check returns an *ErrSuppressed if address is suppressed in channel.
+func (l *List) Check(channel Channel, address string) error {
+	e, ok, err := l.store.Get(channel, l.Normalize(channel, address))
+	if err != nil {
+		return err
+	}
+	if ok {
+		return &ErrSuppressed{Entry: e}
+	}
+	return nil
+}
+
+// Suppress adds address to the list. An existing entry is replaced.
+func (l *List) Suppress(channel Channel, address string, reason Reason, source string) error {
+	return l.store.Add(Entry{
+		Channel:   channel,
+		Address:   l.Normalize(channel, address),
+		Reason:    reason,
+		Source:    source,
+		CreatedAt: time.Now(),
+	})
+}
+
+// Unsuppress removes address from the list.
+func (l *List) Unsuppress(channel Channel, address string) error {
+	return l.store.Remove(channel, l.Normalize(channel, address))
+}
+
+// Entries returns all suppressed recipients.
+func (l *List) Entries() ([]Entry, error) {
+	return l.store.All()
+}
+
+// Inbound handles a message received from address. A stop keyword
+// suppresses it, a start keyword removes a previous opt out. Bounces
+// and complaints are not removed by a start keyword.
+func (l *List) Inbound(channel Channel, address, text string) error {
+	if kw, ok := match(text, l.StopKeywords); ok {
+		return l.Suppress(channel, address, OptOut, kw)
+	}
+	if _, ok := match(text, l.StartKeywords); ok {
+		addr := l.Normalize(channel, address)
+		e, ok, err := l.store.Get(channel, addr)
+		if err != nil || !ok || e.Reason != OptOut {
+			return err
+		}
+		return l.store.Remove(channel, addr)
+	}
+	return nil
+}
+
+// match reports whether text, ignoring case, surrounding spaces and
+// punctuation, is one of keywords.
+func match(text string, keywords []string) (string, bool) {
+	text = strings.TrimFunc(text, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\r' || r == '\n' || r == '.' || r == '!'
+	})
+	for _, kw := range keywords {
+		if strings.EqualFold(text, kw) {
+			return kw, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,68 @@
+package suppression
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	l := New(nil)
+	l.Country = "NP"
+	if err := l.Inbound(SMS, "+9779812345678", " stop "); err != nil {
+		t.Fatal(err)
+	}
+	var es *ErrSuppressed
+	if err := l.Check(SMS, "9812345678"); !errors.As(err, &es) || es.Entry.Reason != OptOut {
+		t.Fatalf("want opt out of national number, have %v", err)
+	}
+	if err := l.Inbound(SMS, "+9779812345678", "Start"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Check(SMS, "+9779812345678"); err != nil {
+		t.Fatalf("want opt in after start keyword, have %v", err)
+	}
+
+	l.Suppress(Email, "Jane <Jane@Example.com>", Bounce, "5.1.1 user unknown")
+	if err := l.Check(Email, "jane@example.COM"); !errors.As(err, &es) || es.Entry.Address != "jane@example.com" {
+		t.Fatalf("want bounced address suppressed, have %v", err)
+	}
+	if err := l.Check(SMS, "jane@example.com"); err != nil {
+		t.Fatalf("want channels kept apart, have %v", err)
+	}
+	l.Inbound(Email, "jane@example.com", "START")
+	if err := l.Check(Email, "jane@example.com"); err == nil {
+		t.Fatal("want bounce kept after start keyword")
+	}
+}
+
+func TestCSV(t *testing.T) {
+	l := New(nil)
+	l.Suppress(Email, "jane@example.com", Complaint, "abuse report")
+	l.Suppress(SMS, "+9779812345678", OptOut, "STOP")
+	var buf bytes.Buffer
+	if err := l.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(nil)
+	n, err := c.Import(&buf)
+	if err != nil || n != 2 {
+		t.Fatalf("want 2 entries, have %d: %v", n, err)
+	}
+	var es *ErrSuppressed
+	if err = c.Check(Email, "JANE@example.com"); !errors.As(err, &es) || es.Entry.Reason != Complaint || es.Entry.Source != "abuse report" {
+		t.Fatalf("want imported complaint, have %v", err)
+	}
+
+	n, err = c.Import(bytes.NewBufferString("sms, 00 977 9801234567\n"))
+	if err != nil || n != 1 {
+		t.Fatalf("want 1 entry without header, have %d: %v", n, err)
+	}
+	if err = c.Check(SMS, "+977 980-123-4567"); !errors.As(err, &es) || es.Entry.Reason != Manual {
+		t.Fatalf("want manual entry, have %v", err)
+	}
+	if _, err = c.Import(bytes.NewBufferString("sms\n")); err == nil {
+		t.Fatal("want error for missing address")
+	}
+}
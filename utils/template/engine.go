@@ -0,0 +1,482 @@
+package template
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Escape selects how the values output by a template are escaped.
+type Escape int
+
+const (
+	Text Escape = iota // Values are output as is.
+	HTML               // Values are HTML escaped.
+	// JSON outputs values inside JSON strings as string content and
+	// other values as JSON, nil being null.
+	JSON
+	// URL path escapes values before the "?" of the URL and query
+	// escapes them after.
+	URL
+)
+
+type context int
+
+const (
+	ctxText context = iota
+	ctxHTML
+	ctxJSONString
+	ctxJSONValue
+	ctxURLPath
+	ctxURLQuery
+)
+
+// Options configures the compilation of a template.
+type Options struct {
+	Escape      Escape
+	Left, Right string // Action delimiters, "{{" and "}}" by default.
+	Locale      string // Locale of the locale filters, see RegisterLocaleFilter.
+}
+
+// Compiled is a compiled template, safe for concurrent use.
+//
+// Actions output a value, {{ user.name }}, filtered with {{ name |
+// upper }} or {{ created | date "2006-01-02" }}, or control the
+// output:
+//
+//	{{if count == 1}}...{{else if not count}}...{{else}}...{{end}}
+//	{{range item in items}}...{{else}}empty{{end}}
+//	{{range key, value in map}}...{{end}}
+//	{{/* comment */}}
+//
+// Paths are dotted map keys, struct fields or json names and slice
+// indexes, a key containing dots being matched first. Missing values
+// are nil and output as nothing, or null in JSON.
+type Compiled struct {
+	nodes []node
+}
+
+type cacheKey struct {
+	src  string
+	opts Options
+}
+
+// CacheSize is the number of compiled templates Compile keeps, the
+// least recently used being evicted: payload messages are compiled
+// too, and are seldom the same.
+const CacheSize = 512
+
+var cache = struct {
+	sync.Mutex
+	entries map[cacheKey]*list.Element
+	order   *list.List // Of *cacheEntry, most recently used first.
+}{entries: make(map[cacheKey]*list.Element), order: list.New()}
+
+type cacheEntry struct {
+	key      cacheKey
+	compiled *Compiled
+}
+
+// cached returns the template compiled for key, if kept.
+func cached(key cacheKey) (*Compiled, bool) {
+	cache.Lock()
+	defer cache.Unlock()
+	e, ok := cache.entries[key]
+	if !ok {
+		return nil, false
+	}
+	cache.order.MoveToFront(e)
+	return e.Value.(*cacheEntry).compiled, true
+}
+
+// store keeps c compiled for key, evicting the least recently used
+// template beyond CacheSize.
+func store(key cacheKey, c *Compiled) {
+	cache.Lock()
+	defer cache.Unlock()
+	if _, ok := cache.entries[key]; ok {
+		return
+	}
+	cache.entries[key] = cache.order.PushFront(&cacheEntry{key: key, compiled: c})
+	if cache.order.Len() > CacheSize {
+		last := cache.order.Back()
+		cache.order.Remove(last)
+		delete(cache.entries, last.Value.(*cacheEntry).key)
+	}
+}
+
+// Compile compiles src, returning the template compiled before for the
+// same source and options if still cached, see CacheSize.
+func Compile(src string, opts Options) (*Compiled, error) {
+	if opts.Left == "" {
+		opts.Left = "{{"
+	}
+	if opts.Right == "" {
+		opts.Right = "}}"
+	}
+	key := cacheKey{src: src, opts: opts}
+	if c, ok := cached(key); ok {
+		return c, nil
+	}
+	nodes, err := parse(src, opts)
+	if err != nil {
+		return nil, err
+	}
+	c := &Compiled{nodes: nodes}
+	store(key, c)
+	return c, nil
+}
+
+// Render compiles src and executes it with data.
+func Render(src string, opts Options, data any) (string, error) {
+	c, err := Compile(src, opts)
+	if err != nil {
+		return "", err
+	}
+	return c.Execute(data)
+}
+
+// Execute returns the output of the template for data.
+func (c *Compiled) Execute(data any) (string, error) {
+	var b strings.Builder
+	if err := c.ExecuteTo(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// ExecuteTo writes the output of the template for data to w.
+func (c *Compiled) ExecuteTo(w io.Writer, data any) error {
+	s := &state{w: w, data: data}
+	return s.walk(c.nodes)
+}
+
+// state is an execution of a template.
+type state struct {
+	w    io.Writer
+	data any
+	vars []variable
+}
+
+type variable struct {
+	name  string
+	value any
+}
+
+func (s *state) walk(nodes []node) error {
+	for _, n := range nodes {
+		var err error
+		switch n := n.(type) {
+		case textNode:
+			_, err = io.WriteString(s.w, string(n))
+		case *outputNode:
+			var v any
+			if v, err = s.eval(n.expr); err == nil {
+				_, err = io.WriteString(s.w, escape(v, n.ctx))
+			}
+		case *ifNode:
+			err = s.walkIf(n)
+		case *rangeNode:
+			err = s.walkRange(n)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *state) walkIf(n *ifNode) error {
+	for _, b := range n.branches {
+		v, err := s.eval(b.cond)
+		if err != nil {
+			return err
+		}
+		if truthy(v) {
+			return s.walk(b.body)
+		}
+	}
+	return s.walk(n.orElse)
+}
+
+func (s *state) walkRange(n *rangeNode) error {
+	v, err := s.eval(n.expr)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+	mark := len(s.vars)
+	defer func() { s.vars = s.vars[:mark] }()
+	iterate := func(key, value any) error {
+		s.vars = s.vars[:mark]
+		if n.key != "" {
+			s.vars = append(s.vars, variable{n.key, key})
+		}
+		s.vars = append(s.vars, variable{n.value, value})
+		return s.walk(n.body)
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Len() == 0 {
+			break
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err = iterate(i, rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if rv.Len() == 0 {
+			break
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			if err = iterate(k.Interface(), rv.MapIndex(k).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Invalid:
+	default:
+		return fmt.Errorf("template: cannot range over %T", v)
+	}
+	return s.walk(n.orElse)
+}
+
+func (s *state) eval(e *expr) (any, error) {
+	left, err := s.pipeline(e.left)
+	if err != nil {
+		return nil, err
+	}
+	if e.op != "" {
+		right, err := s.pipeline(e.right)
+		if err != nil {
+			return nil, err
+		}
+		left = equal(left, right) == (e.op == "==")
+	}
+	if e.not {
+		return !truthy(left), nil
+	}
+	return left, nil
+}
+
+func (s *state) pipeline(p *pipeline) (any, error) {
+	v := s.operand(p.operand)
+	for _, f := range p.filters {
+		args := make([]any, len(f.args))
+		for i, a := range f.args {
+			args[i] = s.operand(a)
+		}
+		var err error
+		if v, err = f.fn(v, args...); err != nil {
+			return nil, fmt.Errorf("template: %s: %w", f.name, err)
+		}
+	}
+	return v, nil
+}
+
+func (s *state) operand(o operand) any {
+	if o.path == "" {
+		return o.literal
+	}
+	head, rest, _ := strings.Cut(o.path, ".")
+	for i := len(s.vars) - 1; i >= 0; i-- {
+		if s.vars[i].name == head {
+			if rest == "" {
+				return s.vars[i].value
+			}
+			return lookup(s.vars[i].value, rest)
+		}
+	}
+	return lookup(s.data, o.path)
+}
+
+// lookup returns the value at the dotted path of v, nil if missing.
+func lookup(v any, path string) any {
+	for path != "" {
+		if m, ok := v.(map[string]any); ok {
+			// Keys containing dots, e.g. from flat data.
+			if x, ok := m[path]; ok {
+				return x
+			}
+		}
+		var key string
+		key, path, _ = strings.Cut(path, ".")
+		v = field(v, key)
+		if v == nil {
+			return nil
+		}
+	}
+	return v
+}
+
+func field(v any, key string) any {
+	if m, ok := v.(map[string]any); ok {
+		return m[key]
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		x := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+		if !x.IsValid() {
+			return nil
+		}
+		return x.Interface()
+	case reflect.Slice, reflect.Array:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= rv.Len() {
+			return nil
+		}
+		return rv.Index(i).Interface()
+	case reflect.Struct:
+		t := rv.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if f.Name == key || name == key {
+				return rv.Field(i).Interface()
+			}
+		}
+	}
+	return nil
+}
+
+func truthy(v any) bool {
+	if v == nil {
+		return false
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return rv.Len() > 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() != 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint() != 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() != 0
+	case reflect.Pointer, reflect.Interface:
+		return !rv.IsNil()
+	}
+	return true
+}
+
+// equal compares numbers by value and other values by their text.
+func equal(a, b any) bool {
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			return x == y
+		}
+	}
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func number(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// Safe is text output without escaping, see the raw filter.
+type Safe string
+
+// JSONText is JSON output as is where a JSON value is expected, see
+// the json filter.
+type JSONText string
+
+func text(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case Safe:
+		return string(v)
+	case JSONText:
+		return string(v)
+	case []byte:
+		return string(v)
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(v)
+}
+
+func escape(v any, ctx context) string {
+	if s, ok := v.(Safe); ok {
+		return string(s)
+	}
+	switch ctx {
+	case ctxHTML:
+		return html.EscapeString(text(v))
+	case ctxURLPath:
+		return url.PathEscape(text(v))
+	case ctxURLQuery:
+		return url.QueryEscape(text(v))
+	case ctxJSONString:
+		b, _ := marshal(text(v))
+		return string(b[1 : len(b)-1])
+	case ctxJSONValue:
+		if j, ok := v.(JSONText); ok {
+			return string(j)
+		}
+		b, err := marshal(v)
+		if err != nil {
+			b, _ = marshal(text(v))
+		}
+		return string(b)
+	}
+	return text(v)
+}
+
+// marshal encodes v as JSON without escaping HTML characters.
+func marshal(v any) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,150 @@
+package template
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Filter transforms the value of a pipeline, e.g. {{ name | upper }}.
+// args are the values following the filter name.
+type Filter func(value any, args ...any) (any, error)
+
+var (
+	filtersMu sync.RWMutex
+	filters   = map[string]Filter{
+		"upper":    func(v any, _ ...any) (any, error) { return strings.ToUpper(text(v)), nil },
+		"lower":    func(v any, _ ...any) (any, error) { return strings.ToLower(text(v)), nil },
+		"trim":     func(v any, _ ...any) (any, error) { return strings.TrimSpace(text(v)), nil },
+		"default":  defaultFilter,
+		"truncate": truncate,
+		"date":     date,
+		"json":     jsonFilter,
+		"html":     func(v any, _ ...any) (any, error) { return Safe(html.EscapeString(text(v))), nil },
+		"url":      func(v any, _ ...any) (any, error) { return Safe(url.QueryEscape(text(v))), nil },
+		"raw":      func(v any, _ ...any) (any, error) { return Safe(text(v)), nil },
+	}
+)
+
+// LocaleFilter is a filter depending on the Locale the template is
+// compiled for, e.g. to format numbers.
+type LocaleFilter func(locale string, value any, args ...any) (any, error)
+
+var localeFilters = map[string]LocaleFilter{}
+
+// RegisterFilter makes a filter available to the templates compiled
+// afterwards, replacing any filter of the same name.
+func RegisterFilter(name string, f Filter) {
+	filtersMu.Lock()
+	defer filtersMu.Unlock()
+	filters[name] = f
+}
+
+// RegisterLocaleFilter makes a locale filter available to the
+// templates compiled afterwards. It takes precedence over the filter
+// registered with the same name, which remains available with
+// LookupFilter.
+func RegisterLocaleFilter(name string, f LocaleFilter) {
+	filtersMu.Lock()
+	defer filtersMu.Unlock()
+	localeFilters[name] = f
+}
+
+// LookupFilter returns the filter registered as name.
+func LookupFilter(name string) (Filter, bool) {
+	filtersMu.RLock()
+	defer filtersMu.RUnlock()
+	f, ok := filters[name]
+	return f, ok
+}
+
+func lookupFilter(name, locale string) (Filter, bool) {
+	filtersMu.RLock()
+	lf, ok := localeFilters[name]
+	filtersMu.RUnlock()
+	if ok {
+		return func(v any, args ...any) (any, error) { return lf(locale, v, args...) }, true
+	}
+	return LookupFilter(name)
+}
+
+// defaultFilter returns its argument for empty values.
+func defaultFilter(v any, args ...any) (any, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("want 1 argument, have %d", len(args))
+	}
+	if truthy(v) {
+		return v, nil
+	}
+	return args[0], nil
+}
+
+// truncate cuts text to n characters, adding the optional second
+// argument when cut, e.g. {{ text | truncate 20 "…" }}.
+func truncate(v any, args ...any) (any, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return nil, fmt.Errorf("want 1 or 2 arguments, have %d", len(args))
+	}
+	n, ok := args[0].(int)
+	if !ok || n < 0 {
+		return nil, fmt.Errorf("invalid length %v", args[0])
+	}
+	r := []rune(text(v))
+	if len(r) <= n {
+		return text(v), nil
+	}
+	s := string(r[:n])
+	if len(args) == 2 {
+		s += text(args[1])
+	}
+	return s, nil
+}
+
+// date formats a time.Time, an RFC 3339 string or Unix seconds with
+// the Go layout argument, RFC 3339 by default.
+func date(v any, args ...any) (any, error) {
+	layout := time.RFC3339
+	if len(args) > 0 {
+		layout = text(args[0])
+	}
+	var t time.Time
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case time.Time:
+		t = v
+	case *time.Time:
+		if v == nil {
+			return "", nil
+		}
+		t = *v
+	case string:
+		var err error
+		if t, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, fmt.Errorf("invalid time %q", v)
+		}
+	default:
+		secs, ok := number(v)
+		if !ok {
+			s, err := strconv.ParseFloat(text(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid time %v", v)
+			}
+			secs = s
+		}
+		t = time.Unix(int64(secs), 0)
+	}
+	return t.Format(layout), nil
+}
+
+func jsonFilter(v any, _ ...any) (any, error) {
+	b, err := marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return JSONText(b), nil
+}
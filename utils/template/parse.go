@@ -0,0 +1,404 @@
+package template
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// node is a part of a compiled template.
+type node interface{}
+
+type textNode string
+
+type outputNode struct {
+	expr *expr
+	ctx  context
+}
+
+type ifNode struct {
+	branches []ifBranch
+	orElse   []node
+}
+
+type ifBranch struct {
+	cond *expr
+	body []node
+}
+
+type rangeNode struct {
+	key, value string // Variable names, key may be empty.
+	expr       *expr
+	body       []node
+	orElse     []node
+}
+
+// expr is a pipeline, optionally negated or compared to another one.
+type expr struct {
+	not   bool
+	left  *pipeline
+	op    string // "==", "!=" or empty.
+	right *pipeline
+}
+
+type pipeline struct {
+	operand operand
+	filters []filterCall
+}
+
+type filterCall struct {
+	name string
+	fn   Filter
+	args []operand
+}
+
+// operand is a literal value or a dotted path.
+type operand struct {
+	path    string
+	literal any
+}
+
+// parser builds the nodes of a template source.
+type parser struct {
+	src         string
+	left, right string
+	escape      Escape
+	locale      string
+	pos         int
+	// Escaping context of the text scanned so far.
+	inString bool // Inside a JSON string.
+	escaped  bool // After a backslash inside a JSON string.
+	query    bool // After the "?" of a URL.
+}
+
+type stackFrame struct {
+	kind   string // "if" or "range".
+	node   any
+	target *[]node // Where the nodes of the current branch go.
+}
+
+func parse(src string, opts Options) ([]node, error) {
+	p := &parser{src: src, left: opts.Left, right: opts.Right, escape: opts.Escape, locale: opts.Locale}
+	var root []node
+	target := &root
+	var stack []stackFrame
+	for p.pos < len(src) {
+		start := strings.Index(src[p.pos:], p.left)
+		if start < 0 {
+			p.text(src[p.pos:])
+			*target = append(*target, textNode(src[p.pos:]))
+			break
+		}
+		if start > 0 {
+			text := src[p.pos : p.pos+start]
+			p.text(text)
+			*target = append(*target, textNode(text))
+		}
+		at := p.pos + start
+		end := strings.Index(src[at+len(p.left):], p.right)
+		if end < 0 {
+			return nil, p.errorf(at, "unclosed action")
+		}
+		action := strings.TrimSpace(src[at+len(p.left) : at+len(p.left)+end])
+		p.pos = at + len(p.left) + end + len(p.right)
+		word, rest, _ := strings.Cut(action, " ")
+		rest = strings.TrimSpace(rest)
+		switch {
+		case strings.HasPrefix(action, "/*") && strings.HasSuffix(action, "*/"):
+		case word == "if":
+			cond, err := p.expr(at, rest)
+			if err != nil {
+				return nil, err
+			}
+			n := &ifNode{branches: []ifBranch{{cond: cond}}}
+			*target = append(*target, n)
+			target = &n.branches[0].body
+			stack = append(stack, stackFrame{kind: "if", node: n, target: target})
+		case word == "range":
+			n, err := p.rangeNode(at, rest)
+			if err != nil {
+				return nil, err
+			}
+			*target = append(*target, n)
+			target = &n.body
+			stack = append(stack, stackFrame{kind: "range", node: n, target: target})
+		case word == "else":
+			if len(stack) == 0 {
+				return nil, p.errorf(at, "else outside if or range")
+			}
+			top := &stack[len(stack)-1]
+			switch n := top.node.(type) {
+			case *ifNode:
+				if n.orElse != nil {
+					return nil, p.errorf(at, "else after else")
+				}
+				if next, cond, _ := strings.Cut(rest, " "); next == "if" {
+					c, err := p.expr(at, cond)
+					if err != nil {
+						return nil, err
+					}
+					n.branches = append(n.branches, ifBranch{cond: c})
+					target = &n.branches[len(n.branches)-1].body
+				} else if rest != "" {
+					return nil, p.errorf(at, "unexpected %q after else", rest)
+				} else {
+					n.orElse = []node{}
+					target = &n.orElse
+				}
+			case *rangeNode:
+				if n.orElse != nil || rest != "" {
+					return nil, p.errorf(at, "invalid else in range")
+				}
+				n.orElse = []node{}
+				target = &n.orElse
+			}
+			top.target = target
+		case word == "end":
+			if len(stack) == 0 {
+				return nil, p.errorf(at, "end without if or range")
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				target = &root
+			} else {
+				target = stack[len(stack)-1].target
+			}
+		default:
+			e, err := p.expr(at, action)
+			if err != nil {
+				return nil, err
+			}
+			*target = append(*target, &outputNode{expr: e, ctx: p.context()})
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("template: unclosed %s", stack[len(stack)-1].kind)
+	}
+	return root, nil
+}
+
+// text updates the escaping context with literal text.
+func (p *parser) text(s string) {
+	switch p.escape {
+	case JSON:
+		for i := 0; i < len(s); i++ {
+			switch {
+			case p.escaped:
+				p.escaped = false
+			case p.inString && s[i] == '\\':
+				p.escaped = true
+			case s[i] == '"':
+				p.inString = !p.inString
+			}
+		}
+	case URL:
+		if strings.ContainsAny(s, "?") {
+			p.query = true
+		}
+	}
+}
+
+func (p *parser) context() context {
+	switch p.escape {
+	case HTML:
+		return ctxHTML
+	case JSON:
+		if p.inString {
+			return ctxJSONString
+		}
+		return ctxJSONValue
+	case URL:
+		if p.query {
+			return ctxURLQuery
+		}
+		return ctxURLPath
+	}
+	return ctxText
+}
+
+func (p *parser) errorf(at int, format string, args ...any) error {
+	line := 1 + strings.Count(p.src[:at], "\n")
+	return fmt.Errorf("template: line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// rangeNode parses "v in expr" or "k, v in expr".
+func (p *parser) rangeNode(at int, s string) (*rangeNode, error) {
+	vars, source, ok := strings.Cut(s, " in ")
+	if !ok {
+		return nil, p.errorf(at, "range wants \"item in list\", have %q", s)
+	}
+	n := &rangeNode{}
+	names := strings.Split(vars, ",")
+	if len(names) > 2 {
+		return nil, p.errorf(at, "range wants at most two variables")
+	}
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+		if !isIdent(names[i]) {
+			return nil, p.errorf(at, "invalid range variable %q", names[i])
+		}
+	}
+	if len(names) == 2 {
+		n.key = names[0]
+	}
+	n.value = names[len(names)-1]
+	e, err := p.expr(at, source)
+	if err != nil {
+		return nil, err
+	}
+	n.expr = e
+	return n, nil
+}
+
+func (p *parser) expr(at int, s string) (*expr, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, p.errorf(at, "%v", err)
+	}
+	if len(tokens) == 0 {
+		return nil, p.errorf(at, "empty expression")
+	}
+	e := &expr{}
+	if tokens[0] == "not" {
+		e.not = true
+		tokens = tokens[1:]
+	}
+	for i, t := range tokens {
+		if t == "==" || t == "!=" {
+			e.op = t
+			if e.right, err = p.pipeline(at, tokens[i+1:]); err != nil {
+				return nil, err
+			}
+			tokens = tokens[:i]
+			break
+		}
+	}
+	if e.left, err = p.pipeline(at, tokens); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (p *parser) pipeline(at int, tokens []string) (*pipeline, error) {
+	var stages [][]string
+	stage := []string{}
+	for _, t := range tokens {
+		if t == "|" {
+			stages = append(stages, stage)
+			stage = []string{}
+			continue
+		}
+		stage = append(stage, t)
+	}
+	stages = append(stages, stage)
+	if len(stages[0]) != 1 {
+		return nil, p.errorf(at, "want a single value before filters, have %q", strings.Join(stages[0], " "))
+	}
+	op, err := parseOperand(stages[0][0])
+	if err != nil {
+		return nil, p.errorf(at, "%v", err)
+	}
+	pl := &pipeline{operand: op}
+	for _, stage := range stages[1:] {
+		if len(stage) == 0 {
+			return nil, p.errorf(at, "missing filter name")
+		}
+		fn, ok := lookupFilter(stage[0], p.locale)
+		if !ok {
+			return nil, p.errorf(at, "unknown filter %q", stage[0])
+		}
+		call := filterCall{name: stage[0], fn: fn}
+		for _, a := range stage[1:] {
+			if a == "," {
+				continue
+			}
+			arg, err := parseOperand(a)
+			if err != nil {
+				return nil, p.errorf(at, "%v", err)
+			}
+			call.args = append(call.args, arg)
+		}
+		pl.filters = append(pl.filters, call)
+	}
+	return pl, nil
+}
+
+// tokenize splits an action into quoted strings, operators and words.
+func tokenize(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'' || c == '`':
+			j := i + 1
+			for ; j < len(s) && s[j] != c; j++ {
+				if s[j] == '\\' && c == '"' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string %s", s[i:])
+			}
+			tokens = append(tokens, s[i:j+1])
+			i = j + 1
+		case c == '|' || c == ',':
+			tokens = append(tokens, string(c))
+			i++
+		case (c == '=' || c == '!') && i+1 < len(s) && s[i+1] == '=':
+			tokens = append(tokens, s[i:i+2])
+			i += 2
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\r\n|,\"'`", rune(s[j])) && !(j+1 < len(s) && (s[j] == '=' || s[j] == '!') && s[j+1] == '=') {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+func parseOperand(t string) (operand, error) {
+	switch t {
+	case "true":
+		return operand{literal: true}, nil
+	case "false":
+		return operand{literal: false}, nil
+	case "nil", "null":
+		return operand{}, nil
+	}
+	switch t[0] {
+	case '"':
+		s, err := strconv.Unquote(t)
+		if err != nil {
+			return operand{}, fmt.Errorf("invalid string %s", t)
+		}
+		return operand{literal: s}, nil
+	case '\'', '`':
+		return operand{literal: t[1 : len(t)-1]}, nil
+	}
+	if c := t[0]; c >= '0' && c <= '9' || c == '-' && len(t) > 1 && t[1] >= '0' && t[1] <= '9' {
+		if n, err := strconv.ParseInt(t, 10, 64); err == nil {
+			return operand{literal: int(n)}, nil
+		}
+		if f, err := strconv.ParseFloat(t, 64); err == nil {
+			return operand{literal: f}, nil
+		}
+	}
+	return operand{path: t}, nil
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -1,7 +1,7 @@
 package template
 
 import (
-	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -10,14 +10,66 @@ type Template struct {
 	ByteContent []byte `json:"byte_content"`
 	Prefix      string `json:"prefix"`
 	Suffix      string `json:"suffix"`
+	Escape      Escape `json:"escape"`
 }
 
+// Parse renders the template with data, see Fill, the output being
+// empty if the template fails.
 func (t *Template) Parse(data map[string]any) string {
+	s, _ := t.Fill(data)
+	return s
+}
+
+// Fill renders the template with data, see Compiled. Content which
+// does not compile, e.g. with keys containing spaces, has its {{key}}
+// placeholders replaced instead, values being escaped as selected by
+// t.Escape.
+func (t *Template) Fill(data map[string]any) (string, error) {
+	c, err := Compile(t.Content, Options{Escape: t.Escape, Left: t.Prefix, Right: t.Suffix})
+	if err != nil {
+		return t.replace(data), nil
+	}
+	return c.Execute(data)
+}
+
+// replace replaces the placeholders of the keys of data, tracking the
+// escaping context as the parser does.
+func (t *Template) replace(data map[string]any) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	// Longest keys first so overlapping keys replace deterministically.
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	p := &parser{escape: t.Escape}
+	var b strings.Builder
 	content := t.Content
-	for k, v := range data {
-		content = strings.ReplaceAll(content, t.Prefix+k+t.Suffix, fmt.Sprintf("%v", v))
+outer:
+	for i := 0; i < len(content); {
+		if strings.HasPrefix(content[i:], t.Prefix) {
+			for _, k := range keys {
+				if placeholder := t.Prefix + k + t.Suffix; strings.HasPrefix(content[i:], placeholder) {
+					b.WriteString(escape(data[k], p.context()))
+					i += len(placeholder)
+					continue outer
+				}
+			}
+		}
+		p.text(content[i : i+1])
+		b.WriteByte(content[i])
+		i++
 	}
-	return content
+	return b.String()
+}
+
+// Render renders the template with data.
+func (t *Template) Render(data any) (string, error) {
+	return Render(t.Content, Options{Escape: t.Escape, Left: t.Prefix, Right: t.Suffix}, data)
 }
 
 func New(content string, prefix, suffix string) *Template {
@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 )
 
 func BenchmarkLoopReplace(b *testing.B) {
@@ -32,3 +33,102 @@ func BenchmarkTemplate_Parse(b *testing.B) {
 		tmpl.Parse(data)
 	}
 }
+
+func TestRender(t *testing.T) {
+	type user struct {
+		Name  string `json:"name"`
+		Roles []string
+	}
+	data := map[string]any{
+		"user":     user{Name: "jane", Roles: []string{"admin", "ops"}},
+		"flat.key": "flat",
+		"count":    2,
+		"scores":   map[string]int{"b": 2, "a": 1},
+		"created":  time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		"empty":    []string{},
+	}
+	for _, tt := range []struct {
+		src, want string
+	}{
+		{"{{ user.name | upper }} {{user.Roles.1}} {{flat.key}}", "JANE ops flat"},
+		{"{{if count == 1}}one{{else if count == 2}}two{{else}}many{{end}}", "two"},
+		{"{{if not missing}}none{{end}}{{missing}}", "none"},
+		{"{{range i, r in user.Roles}}{{i}}:{{r}} {{end}}", "0:admin 1:ops "},
+		{"{{range k, v in scores}}{{k}}={{v}};{{end}}", "a=1;b=2;"},
+		{"{{range x in empty}}{{x}}{{else}}empty{{end}}", "empty"},
+		{`{{ missing | default "guest" }} {{ "abcdef" | truncate 3 "…" }}`, "guest abc…"},
+		{`{{ created | date "2006-01-02" }}{{/* comment */}}`, "2024-05-01"},
+	} {
+		have, err := Render(tt.src, Options{}, data)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.src, err)
+		}
+		if have != tt.want {
+			t.Errorf("%s: want %q, have %q", tt.src, tt.want, have)
+		}
+	}
+
+	for _, src := range []string{"{{if x}}", "{{end}}", "{{ x | nope }}", "{{range x}}{{end}}", "{{x"} {
+		if _, err := Render(src, Options{}, data); err == nil {
+			t.Errorf("%s: want error", src)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	data := map[string]any{"name": `Jane "JD" <Doe>`, "n": 3, "tags": []string{"a", "b"}, "q": "a b&c"}
+	for _, tt := range []struct {
+		escape    Escape
+		src, want string
+	}{
+		{JSON, `{"name": "{{name}}", "n": {{n}}, "tags": {{tags}}, "none": {{missing}}}`, `{"name": "Jane \"JD\" <Doe>", "n": 3, "tags": ["a","b"], "none": null}`},
+		{JSON, `{"tags": {{tags | json}}}`, `{"tags": ["a","b"]}`},
+		{HTML, "<b>{{name}}</b>{{ name | raw }}", "<b>Jane &#34;JD&#34; &lt;Doe&gt;</b>Jane \"JD\" <Doe>"},
+		{URL, "https://example.com/{{q}}?q={{q}}", "https://example.com/a%20b&c?q=a+b%26c"},
+	} {
+		have, err := Render(tt.src, Options{Escape: tt.escape}, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have != tt.want {
+			t.Errorf("%s: want %s, have %s", tt.src, tt.want, have)
+		}
+	}
+}
+
+func TestCompileCache(t *testing.T) {
+	a, err := Compile("[[ x ]]", Options{Left: "[[", Right: "]]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := Compile("[[ x ]]", Options{Left: "[[", Right: "]]"}); a != b {
+		t.Fatal("want cached template")
+	}
+	if b, _ := Compile("[[ x ]]", Options{Left: "[[", Right: "]]", Escape: HTML}); a == b {
+		t.Fatal("want template compiled per options")
+	}
+	for i := 0; i < CacheSize; i++ {
+		Compile(fmt.Sprintf("message %d", i), Options{})
+	}
+	if b, _ := Compile("[[ x ]]", Options{Left: "[[", Right: "]]"}); a == b {
+		t.Fatal("want least recently used template evicted")
+	}
+	if n := len(cache.entries); n != CacheSize {
+		t.Fatalf("want %d cached templates, have %d", CacheSize, n)
+	}
+}
+
+func TestTemplateParseFallback(t *testing.T) {
+	tmpl := New("{{first name}} {{first name x}}", "", "")
+	if have := tmpl.Parse(map[string]any{"first name": "Jane", "first name x": "X"}); have != "Jane X" {
+		t.Fatalf("want replaced placeholders, have %q", have)
+	}
+	tmpl = New("<b>{{first name}}</b>", "", "")
+	tmpl.Escape = HTML
+	if have, err := tmpl.Fill(map[string]any{"first name": "<Jane>"}); err != nil || have != "<b>&lt;Jane&gt;</b>" {
+		t.Fatalf("want replaced value escaped, have %q, %v", have, err)
+	}
+	if _, err := New("{{ n | truncate }}", "", "").Fill(map[string]any{"n": "Jane"}); err == nil {
+		t.Fatal("want error of valid template")
+	}
+}